@@ -16,54 +16,182 @@ package main
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
+
+	"golang.org/x/time/rate"
+	"tailstream/client/pkg/tailstream"
 )
 
 // Stream represents a user's stream from the API
-type Stream struct {
-	ID          int    `json:"id"`
-	Name        string `json:"name"`
-	StreamID    string `json:"stream_id"`
-	Description string `json:"description"`
-}
-
-// logResponse represents the API response structure
-type logResponse struct {
-	Data []map[string]any `json:"data"`
-	Meta struct {
-		HasMore    bool    `json:"has_more"`
-		NextCursor *string `json:"next_cursor"`
-		Total      *int    `json:"total"` // null in tail mode (no time range)
-	} `json:"meta"`
-	Links struct {
-		Next *string `json:"next"`
-	} `json:"links"`
+type Stream = tailstream.Stream
+
+// newAPIClient returns a tailstream.Client using the CLI's TLS settings
+func newAPIClient(baseURL, token string, timeout time.Duration) *tailstream.Client {
+	return &tailstream.Client{BaseURL: baseURL, Token: token, HTTPClient: getHTTPClient(timeout)}
+}
+
+// insecureTLS disables TLS certificate verification at runtime (--insecure
+// or TAILSTREAM_INSECURE), in addition to the build-time insecureSkipTLSStr
+var insecureTLS bool
+
+// customRootCAs holds the trusted roots when --ca-cert is given (nil = system roots)
+var customRootCAs *x509.CertPool
+
+// clientCertificates are presented to servers that require mutual TLS
+// (--client-cert/--client-key)
+var clientCertificates []tls.Certificate
+
+// tlsVerifyDisabled reports whether HTTP clients skip certificate verification
+func tlsVerifyDisabled() bool {
+	return insecureTLS || insecureSkipTLSStr == "true"
+}
+
+// loadCACert returns the system roots plus the certificates in a PEM bundle,
+// so a private CA is trusted without losing the public ones
+func loadCACert(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA certificate: %v", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no PEM certificates found in %s", path)
+	}
+	return pool, nil
+}
+
+// loadClientCert loads the certificate and private key for mutual TLS. Both
+// files must be given together.
+func loadClientCert(certPath, keyPath string) (tls.Certificate, error) {
+	if (certPath == "") != (keyPath == "") {
+		return tls.Certificate{}, fmt.Errorf("--client-cert and --client-key must be used together")
+	}
+	cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to load client certificate: %v", err)
+	}
+	return cert, nil
 }
 
 // getHTTPClient returns an HTTP client with appropriate timeout and TLS settings
 func getHTTPClient(timeout time.Duration) *http.Client {
 	client := &http.Client{Timeout: timeout}
 
-	// Check if we should skip TLS verification (for local testing)
-	if insecureSkipTLSStr == "true" {
-		client.Transport = &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	// Custom TLS settings: skipped verification (for local testing), a private
+	// CA, or client certificates for mutual TLS
+	if tlsVerifyDisabled() || customRootCAs != nil || len(clientCertificates) > 0 {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = &tls.Config{
+			InsecureSkipVerify: tlsVerifyDisabled(),
+			RootCAs:            customRootCAs,
+			Certificates:       clientCertificates,
 		}
+		client.Transport = transport
 	}
 
 	return client
 }
 
+// uploadShare POSTs shared output to the configured paste service and returns
+// the link to it: the Location header when set, else a "url" or "link" field
+// of a JSON reply, else the trimmed response body
+func uploadShare(shareURL string, body []byte, contentType string) (string, error) {
+	client := getHTTPClient(defaultRequestTimeout)
+	resp, err := client.Post(shareURL, contentType, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("share upload failed: %v", diagnoseRequestError(shareURL, err))
+	}
+	defer resp.Body.Close()
+
+	reply, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("share upload failed: %s\n%s", resp.Status, strings.TrimSpace(string(reply)))
+	}
+	if loc := resp.Header.Get("Location"); loc != "" {
+		if u, err := resp.Request.URL.Parse(loc); err == nil {
+			return u.String(), nil
+		}
+		return loc, nil
+	}
+	var fields map[string]any
+	if json.Unmarshal(reply, &fields) == nil {
+		for _, key := range []string{"url", "link"} {
+			if link, _ := fields[key].(string); link != "" {
+				return link, nil
+			}
+		}
+	}
+	link := strings.TrimSpace(string(reply))
+	if link == "" {
+		return "", fmt.Errorf("share upload succeeded but the service returned no link")
+	}
+	return link, nil
+}
+
+// diagnoseRequestError classifies a connection-level request failure (DNS,
+// refused connection, TLS) and adds a remediation hint, so users don't have
+// to decode a raw dial error. Other errors are returned unchanged.
+func diagnoseRequestError(baseURL string, err error) error {
+	host := baseURL
+	if u, perr := url.Parse(baseURL); perr == nil && u.Host != "" {
+		host = u.Host
+	}
+
+	var dnsErr *net.DNSError
+	var unknownAuthority x509.UnknownAuthorityError
+	var certInvalid x509.CertificateInvalidError
+	var hostnameErr x509.HostnameError
+	switch {
+	case errors.As(err, &dnsErr):
+		return fmt.Errorf("cannot resolve host %s (%v)\nHint: check --base-url or base_url in the config file", host, dnsErr.Err)
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return fmt.Errorf("connection to %s refused\nHint: the server may be down, or --base-url points at the wrong host or port", host)
+	case errors.As(err, &unknownAuthority), errors.As(err, &certInvalid):
+		return fmt.Errorf("TLS certificate of %s is not trusted (%v)\nHint: for a private CA, pass its certificate with --ca-cert; for a self-signed test server, use --insecure", host, err)
+	case errors.As(err, &hostnameErr):
+		return fmt.Errorf("TLS certificate does not match %s (%v)\nHint: check --base-url", host, hostnameErr)
+	case strings.Contains(err.Error(), "server gave HTTP response to HTTPS client"):
+		// net/http replaces the TLS record error with this message
+		return fmt.Errorf("%s did not answer with TLS\nHint: if the server only speaks plain HTTP, use an http:// --base-url", host)
+	}
+	return err
+}
+
+// statusHint returns a remediation hint for an HTTP error status, starting
+// with a newline, or "" when there is nothing specific to suggest
+func statusHint(code int) string {
+	switch code {
+	case http.StatusUnauthorized:
+		return "\nHint: the token is missing or expired; run tailstream-client --login"
+	case http.StatusForbidden:
+		return "\nHint: the token has no access to this stream; check --stream-id or log in again"
+	case http.StatusNotFound:
+		return "\nHint: check --stream-id and --base-url"
+	}
+	return ""
+}
+
 // selectStreamInteractive fetches user streams and lets them choose
 func selectStreamInteractive(baseURL, accessToken string, config *ClientConfig) (string, error) {
 	fmt.Println("Fetching your streams...")
@@ -137,41 +265,137 @@ func selectStreamInteractive(baseURL, accessToken string, config *ClientConfig)
 	return selectedStream.StreamID, nil
 }
 
+// runListStreams prints the user's streams numbered as in the stream
+// selector (or as JSON), and saves the list so --stream-index can refer to
+// them by number
+func runListStreams(out io.Writer, config *ClientConfig, baseURL, token string, asJSON bool) error {
+	streams, err := fetchUserStreams(baseURL, token)
+	if err != nil {
+		return err
+	}
+	if dir, err := determineCacheDir(config); err == nil {
+		if err := saveStreamList(dir, baseURL, streams); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not save the stream list: %v\n", err)
+		}
+	}
+	if asJSON {
+		return json.NewEncoder(out).Encode(streams)
+	}
+	if len(streams) == 0 {
+		fmt.Fprintf(out, "No streams found. Create one at %s\n", baseURL)
+		return nil
+	}
+	for i, stream := range streams {
+		marker := ""
+		if config != nil && stream.StreamID == config.DefaultStream {
+			marker = " (default)"
+		}
+		fmt.Fprintf(out, "[%d] %s (%s)%s\n", i+1, stream.Name, stream.StreamID, marker)
+	}
+	return nil
+}
+
 // fetchUserStreams retrieves the user's streams
 func fetchUserStreams(baseURL, accessToken string) ([]Stream, error) {
-	client := getHTTPClient(10 * time.Second)
+	streams, err := newAPIClient(baseURL, accessToken, 10*time.Second).Streams(context.Background())
+	var statusErr *tailstream.StatusError
+	switch {
+	case errors.As(err, &statusErr):
+		return nil, fmt.Errorf("failed to fetch streams: %s - %s%s", statusErr.Status, statusErr.Body, statusHint(statusErr.StatusCode))
+	case err != nil:
+		return nil, diagnoseRequestError(baseURL, err)
+	}
+	return streams, nil
+}
 
-	req, err := http.NewRequest("GET", baseURL+"/api/user/streams", nil)
+// checkReachable reports whether the server at baseURL answers HTTP at all;
+// any status counts, since the root may redirect or require login
+func checkReachable(baseURL string) error {
+	resp, err := getHTTPClient(10 * time.Second).Get(baseURL)
 	if err != nil {
-		return nil, err
+		return diagnoseRequestError(baseURL, err)
 	}
-	req.Header.Set("Authorization", "Bearer "+accessToken)
+	resp.Body.Close()
+	return nil
+}
 
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
+// newRateLimiter returns a limiter allowing the given number of requests per
+// second, or nil when rate limiting is disabled (requestsPerSecond <= 0)
+func newRateLimiter(requestsPerSecond float64) *rate.Limiter {
+	if requestsPerSecond <= 0 {
+		return nil
 	}
-	defer resp.Body.Close()
+	return rate.NewLimiter(rate.Limit(requestsPerSecond), 1)
+}
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("failed to fetch streams: %s - %s", resp.Status, string(body))
+// pageFetcher fetches one page of entries starting at cursor, optionally with a
+// server-side search query. It returns the entries, whether more pages exist,
+// the total (nil in tail mode), and the token for the next page.
+type pageFetcher func(cursor string, searchQuery string) ([]map[string]any, bool, *int, string, error)
+
+// resolveStreamName finds the stream matching a human-readable name. An exact
+// (case-insensitive) name match wins; otherwise a unique partial match is used.
+// Ambiguous names return an error listing the candidates.
+func resolveStreamName(streams []Stream, name string) (Stream, error) {
+	name = strings.TrimSpace(name)
+	var partial []Stream
+	for _, stream := range streams {
+		if strings.EqualFold(stream.Name, name) {
+			return stream, nil
+		}
+		if strings.Contains(strings.ToLower(stream.Name), strings.ToLower(name)) {
+			partial = append(partial, stream)
+		}
 	}
 
-	var streamsResp struct {
-		Streams []Stream `json:"streams"`
+	switch len(partial) {
+	case 0:
+		return Stream{}, fmt.Errorf("no stream named %q", name)
+	case 1:
+		return partial[0], nil
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&streamsResp); err != nil {
-		return nil, err
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "stream name %q is ambiguous, matches:\n", name)
+	for _, stream := range partial {
+		fmt.Fprintf(&b, "  %s (%s)\n", stream.Name, stream.StreamID)
 	}
+	b.WriteString("Use a more specific --stream name or --stream-id")
+	return Stream{}, errors.New(b.String())
+}
 
-	return streamsResp.Streams, nil
+// defaultRequestTimeout bounds a single HTTP request when no --timeout is given
+const defaultRequestTimeout = 15 * time.Second
+
+// fetchOptions holds optional behavior for page fetches. The zero value
+// fetches every page directly with no throttling, no overall deadline, and
+// defaultRequestTimeout per request.
+type fetchOptions struct {
+	Limiter *rate.Limiter   // Throttles page requests when non-nil
+	Cache   *pageCache      // Serves repeated page requests from disk when non-nil
+	Context context.Context // Parent context bounding all fetches (e.g. --overall-timeout)
+	Timeout time.Duration   // Deadline for each individual page request
+	Filters []fieldFilter   // --filter expressions re-checked client-side
+	Match   matchMode       // How search terms match entries (zero = substring)
+	// PageSize returns the limit param for the next page request, so the
+	// last page can shrink to what --limit still needs; nil or <= 0 keeps
+	// the base query's limit
+	PageSize func() int
+	Post     bool // Always send the query as a POST body (--post)
 }
 
 // createFetcher creates a fetcher function for pagination
-func createFetcher(baseURL, token, streamID string, baseQuery url.Values, terms []string) func(string, string) ([]map[string]any, bool, *int, string, error) {
-	endpoint := strings.TrimRight(baseURL, "/") + "/api/streams/" + url.PathEscape(strings.TrimSpace(streamID)) + "/logs"
-	client := getHTTPClient(15 * time.Second)
+func createFetcher(baseURL, token, streamID string, baseQuery url.Values, terms []string, opts fetchOptions) pageFetcher {
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = defaultRequestTimeout
+	}
+	client := getHTTPClient(timeout)
+	api := &tailstream.Client{BaseURL: baseURL, Token: token, HTTPClient: client, PostQueries: opts.Post}
+	parent := opts.Context
+	if parent == nil {
+		parent = context.Background()
+	}
 
 	return func(cursor string, searchQuery string) ([]map[string]any, bool, *int, string, error) {
 		queryParams := url.Values{}
@@ -180,12 +404,16 @@ func createFetcher(baseURL, token, streamID string, baseQuery url.Values, terms
 			queryParams[k] = v
 		}
 
-		// Set cursor if provided
-		if cursor != "" {
-			queryParams.Set("cursor", cursor)
+		if opts.PageSize != nil {
+			if n := opts.PageSize(); n > 0 {
+				queryParams.Set("limit", strconv.Itoa(n))
+			}
 		}
 
-		// Add server-side search filter if provided
+		// Add server-side search filter if provided. The server doesn't know
+		// "-word" exclusions, so those are applied to the results here.
+		searchQuery, excluded := splitExclusions(searchQuery)
+		exclusions := normalizeQueries(excluded)
 		if searchQuery != "" {
 			filters := []map[string]any{}
 			// Parse existing filters if any
@@ -201,77 +429,619 @@ func createFetcher(baseURL, token, streamID string, baseQuery url.Values, terms
 			queryParams.Set("filters", string(filtersJSON))
 		}
 
-		fullURL := endpoint + "?" + queryParams.Encode()
+		// Each page gets its own deadline so long exports aren't cut short
+		// by a single timeout covering every request
+		ctx, cancel := context.WithTimeout(parent, timeout)
+		defer cancel()
 
-		req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, fullURL, nil)
+		// A server-provided next link is followed as-is, but only on the API
+		// host, since the token is sent along
+		req, err := api.NewLogsRequest(ctx, streamID, queryParams, cursor)
 		if err != nil {
 			return nil, false, nil, "", err
 		}
-		req.Header.Set("Accept", "application/json")
-		req.Header.Set("Authorization", "Bearer "+token)
+		fullURL := api.LogsURL(streamID, queryParams, cursor)
 
-		resp, err := client.Do(req)
-		if err != nil {
-			return nil, false, nil, "", err
-		}
-		defer resp.Body.Close()
+		pageBody, cached := opts.Cache.get(token, fullURL)
+		if !cached {
+			// Throttle paginated requests to avoid hammering the backend
+			if opts.Limiter != nil {
+				if err := opts.Limiter.Wait(parent); err != nil {
+					return nil, false, nil, "", err
+				}
+			}
 
-		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-			return nil, false, nil, "", fmt.Errorf("request failed: %s", resp.Status)
+			pageBody, err = fetchPageBody(client, req)
+			if err != nil {
+				return nil, false, nil, "", err
+			}
+			opts.Cache.put(token, fullURL, pageBody)
 		}
 
-		var pagePayload logResponse
-		if err := json.NewDecoder(resp.Body).Decode(&pagePayload); err != nil {
+		pagePayload, err := tailstream.DecodeLogResponse(pageBody)
+		if err != nil {
 			return nil, false, nil, "", err
 		}
+		page := pagePayload.Page(req.URL)
 
 		// Filter entries based on client-side search terms (from --search flag)
 		pageFiltered := make([]map[string]any, 0)
-		for _, entry := range pagePayload.Data {
-			if len(terms) > 0 && !entryMatches(entry, terms) {
+		for _, entry := range page.Entries {
+			if len(terms) > 0 && !opts.Match.matches(entry, terms) || !filtersMatch(entry, opts.Filters) {
+				continue
+			}
+			if len(exclusions) > 0 && !opts.Match.matches(entry, exclusions) {
 				continue
 			}
 			pageFiltered = append(pageFiltered, entry)
 		}
 
-		hasMore := pagePayload.Meta.HasMore
-		nextCursor := ""
-		if pagePayload.Meta.NextCursor != nil {
-			nextCursor = *pagePayload.Meta.NextCursor
+		return pageFiltered, page.HasMore, page.Total, page.NextCursor, nil
+	}
+}
+
+// setAuthorization adds the bearer token to req. An empty token (--public)
+// sends no Authorization header at all.
+func setAuthorization(req *http.Request, token string) {
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+}
+
+// errorBody returns the trimmed body of an error response, decompressed like
+// a successful one since Accept-Encoding is set by hand
+func errorBody(resp *http.Response) string {
+	body, err := tailstream.DecodeBody(resp)
+	if err != nil {
+		return ""
+	}
+	data, _ := io.ReadAll(body)
+	return strings.TrimSpace(string(data))
+}
+
+// fetchPageBody sends a page request and returns the decoded response body
+func fetchPageBody(client *http.Client, req *http.Request) ([]byte, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	warnAPIVersion(resp)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("request failed: %s", resp.Status)
+	}
+
+	body, err := tailstream.DecodeBody(resp)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(body)
+}
+
+// cursorHistory is how many recent pagination cursors are remembered when
+// checking for loops
+const cursorHistory = 8
+
+// errCursorLoop reports a backend that hands out a cursor it already returned
+var errCursorLoop = errors.New("server repeated a pagination cursor; stopping to avoid an endless loop")
+
+// cursorLoop remembers recent pagination cursors so a backend that keeps
+// returning the same next cursor can't make pagination spin forever. Empty
+// pages alone aren't treated as a loop, since client-side filtering can
+// legitimately empty many pages in a row.
+type cursorLoop struct {
+	recent []string
+}
+
+// advance records the cursor a page was fetched with and reports whether the
+// next cursor returned for it was already seen recently
+func (l *cursorLoop) advance(current, next string) bool {
+	l.recent = append(l.recent, current)
+	if len(l.recent) > cursorHistory {
+		l.recent = l.recent[1:]
+	}
+	return slices.Contains(l.recent, next)
+}
+
+// defaultSSERetry is how long to wait before reconnecting to the event
+// stream when the server sends no retry hint
+const defaultSSERetry = 2 * time.Second
+
+// errNoSSE reports a server without a live log stream endpoint
+var errNoSSE = errors.New("server has no live log stream endpoint")
+
+// sseEvent is one server-sent event
+type sseEvent struct {
+	ID    string
+	Event string // "" for the default "message" type
+	Data  string // Data lines joined with "\n"
+}
+
+// readSSE parses a text/event-stream body, passing each event that carries
+// data to handle, until the stream ends. It returns the last retry hint the
+// server sent (0 when none) and the id of the last event.
+func readSSE(r io.Reader, lastID string, handle func(sseEvent)) (retry time.Duration, id string, err error) {
+	reader := bufio.NewReader(r)
+	var event sseEvent
+	var data []string
+	id = lastID
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && line == "" {
+			if err == io.EOF {
+				err = nil
+			}
+			return retry, id, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			// A blank line dispatches the event
+			if len(data) > 0 {
+				event.ID, event.Data = id, strings.Join(data, "\n")
+				handle(event)
+			}
+			event, data = sseEvent{}, nil
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "": // Comment, e.g. a keep-alive
+		case "data":
+			data = append(data, value)
+		case "event":
+			event.Event = value
+		case "id":
+			id = value
+		case "retry":
+			if ms, err := strconv.Atoi(value); err == nil && ms >= 0 {
+				retry = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+}
+
+// lacksEventStream reports whether resp shows the server has no event
+// stream endpoint: a 404/405/406, or a success that isn't an event stream
+// (e.g. plain JSON). Other failures are worth retrying.
+func lacksEventStream(resp *http.Response) bool {
+	switch {
+	case resp.StatusCode == http.StatusNotFound, resp.StatusCode == http.StatusMethodNotAllowed, resp.StatusCode == http.StatusNotAcceptable:
+		return true
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return false
+	}
+	return !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream")
+}
+
+// sseEntries decodes the entries in an event's data: a JSON object, or an
+// array of them
+func sseEntries(data string) ([]map[string]any, error) {
+	decoder := json.NewDecoder(strings.NewReader(data))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	switch v := value.(type) {
+	case map[string]any:
+		return []map[string]any{v}, nil
+	case []any:
+		entries := make([]map[string]any, 0, len(v))
+		for _, item := range v {
+			if entry, ok := item.(map[string]any); ok {
+				entries = append(entries, entry)
+			}
 		}
+		return entries, nil
+	}
+	return nil, fmt.Errorf("expected a JSON object, got %s", data)
+}
 
-		return pageFiltered, hasMore, pagePayload.Meta.Total, nextCursor, nil
+// streamSSE follows a stream over the server's event stream endpoint
+// ({base}/api/streams/{id}/logs/stream), passing new entries that match
+// terms and opts.Filters to emit until opts.Context is done. Dropped
+// connections are retried after the server's retry hint, resuming with
+// Last-Event-ID and f's newest timestamp. It returns errNoSSE when the first
+// connection shows the server has no such endpoint, so the caller can poll
+// instead.
+func streamSSE(baseURL, token, streamID string, f *follower, base url.Values, terms []string, opts fetchOptions, emit func(map[string]any)) error {
+	endpoint := strings.TrimRight(baseURL, "/") + "/api/streams/" + url.PathEscape(strings.TrimSpace(streamID)) + "/logs/stream"
+	ctx := opts.Context
+	if ctx == nil {
+		ctx = context.Background()
 	}
+	if f.since.IsZero() {
+		f.since = now()
+	}
+	client := getHTTPClient(0) // The connection stays open indefinitely
+	lastID, retry := "", defaultSSERetry
+	for connected := false; ; {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"?"+f.query(base).Encode(), nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Accept", "text/event-stream")
+		req.Header.Set("Cache-Control", "no-cache")
+		setAuthorization(req, token)
+		if lastID != "" {
+			req.Header.Set("Last-Event-ID", lastID)
+		}
+
+		resp, err := client.Do(req)
+		switch {
+		case ctx.Err() != nil:
+			return nil
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "Warning: live stream disconnected: %v\n", diagnoseRequestError(baseURL, err))
+		case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+			resp.Body.Close()
+			return fmt.Errorf("live stream failed: %s%s", resp.Status, statusHint(resp.StatusCode))
+		case !connected && lacksEventStream(resp):
+			resp.Body.Close()
+			return errNoSSE
+		case resp.StatusCode < 200 || resp.StatusCode >= 300:
+			resp.Body.Close()
+			fmt.Fprintf(os.Stderr, "Warning: live stream failed: %s; reconnecting\n", resp.Status)
+		default:
+			connected = true
+			hint, id, err := readSSE(resp.Body, lastID, func(event sseEvent) {
+				if event.Event != "" && event.Event != "message" && event.Event != "log" {
+					return // Heartbeats and other event types
+				}
+				entries, err := sseEntries(event.Data)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: skipping unreadable live entry: %v\n", err)
+					return
+				}
+				for _, entry := range entries {
+					if len(terms) > 0 && !opts.Match.matches(entry, terms) || !filtersMatch(entry, opts.Filters) {
+						continue
+					}
+					if f.accept(entry) {
+						emit(entry)
+					}
+				}
+			})
+			resp.Body.Close()
+			lastID = id
+			if hint > 0 {
+				retry = hint
+			}
+			if ctx.Err() != nil {
+				return nil
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: live stream disconnected: %v\n", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(retry):
+		}
+	}
+}
+
+// measureClockOffset estimates how far the local clock is behind the server's
+// by reading the Date header of a HEAD request to the base URL. The request
+// round-trip is split in half to approximate when the server stamped the response.
+func measureClockOffset(client *http.Client, baseURL string) (time.Duration, error) {
+	req, err := http.NewRequest(http.MethodHead, strings.TrimRight(baseURL, "/")+"/", nil)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	received := time.Now()
+
+	return clockOffsetFromResponse(resp, sent.Add(received.Sub(sent)/2))
+}
+
+// supportedAPIMajor is the major API version this client understands
+const supportedAPIMajor = 1
+
+var apiVersionWarning sync.Once
+
+// warnAPIVersion prints a one-time warning to stderr when the server reports
+// an X-API-Version with a different major version than the client supports
+func warnAPIVersion(resp *http.Response) {
+	version := resp.Header.Get("X-API-Version")
+	if !apiVersionMismatch(version) {
+		return
+	}
+	apiVersionWarning.Do(func() {
+		fmt.Fprintf(os.Stderr, "Warning: server API version %s differs from the supported version %d.x; results may be incomplete. Consider upgrading tailstream-client.\n", version, supportedAPIMajor)
+	})
 }
 
-// normalizeQueries converts search terms to lowercase and trims whitespace
+// apiVersionMismatch reports whether an API version like "1", "v2", or
+// "2.3.0" has a different major version than supportedAPIMajor. Missing or
+// unparseable versions are not considered a mismatch.
+func apiVersionMismatch(version string) bool {
+	version = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(version)), "v")
+	major, _, _ := strings.Cut(version, ".")
+	n, err := strconv.Atoi(major)
+	if err != nil {
+		return false
+	}
+	return n != supportedAPIMajor
+}
+
+// clockOffsetFromResponse returns the difference between the server time in
+// the response's Date header and the given local time
+func clockOffsetFromResponse(resp *http.Response, local time.Time) (time.Duration, error) {
+	header := resp.Header.Get("Date")
+	if header == "" {
+		return 0, fmt.Errorf("server did not send a Date header")
+	}
+	serverTime, err := http.ParseTime(header)
+	if err != nil {
+		return 0, fmt.Errorf("invalid Date header %q: %w", header, err)
+	}
+	return serverTime.Sub(local), nil
+}
+
+// normalizeQueries converts search terms to lowercase and trims whitespace.
+// Words with a leading '-' become exclusions, kept as separate "-word"
+// terms; the remaining words stay together as one phrase, so
+// "timeout -healthcheck" is ["timeout", "-healthcheck"]. A phrase that
+// starts with '-' keeps a '\' in front so searchTerm doesn't exclude it.
 func normalizeQueries(values []string) []string {
 	if len(values) == 0 {
 		return nil
 	}
 	terms := make([]string, 0, len(values))
 	for _, v := range values {
-		v = strings.TrimSpace(v)
-		if v == "" {
-			continue
+		phrase, excluded := splitExclusions(strings.ToLower(v))
+		if strings.HasPrefix(phrase, "-") {
+			phrase = `\` + phrase
+		}
+		if phrase != "" {
+			terms = append(terms, phrase)
 		}
-		terms = append(terms, strings.ToLower(v))
+		terms = append(terms, excluded...)
 	}
 	return terms
 }
 
-// entryMatches checks if an entry matches all search terms
+// splitExclusions separates a search query into the phrase to find and its
+// "-word" exclusions. A lone '-' is an ordinary word, and "\-word" searches
+// for "-word" itself. The phrase keeps its original spacing when there is
+// nothing to exclude or unescape.
+func splitExclusions(query string) (phrase string, excluded []string) {
+	var kept []string
+	escaped := false
+	for _, w := range strings.Fields(query) {
+		if len(w) > 1 && w[0] == '-' {
+			excluded = append(excluded, w)
+		} else if strings.HasPrefix(w, `\-`) {
+			kept = append(kept, w[1:])
+			escaped = true
+		} else {
+			kept = append(kept, w)
+		}
+	}
+	if len(excluded) == 0 && !escaped {
+		return strings.TrimSpace(query), nil
+	}
+	return strings.Join(kept, " "), excluded
+}
+
+// searchTerm splits a normalized term into its text and whether it is an
+// exclusion ("-word", which must not appear). A phrase like "- 200" is not,
+// nor is one escaped as "\-200" by normalizeQueries.
+func searchTerm(term string) (text string, excluded bool) {
+	if text, ok := strings.CutPrefix(term, `\`); ok && strings.HasPrefix(text, "-") {
+		return text, false
+	}
+	if text, ok := strings.CutPrefix(term, "-"); ok && text != "" && text[0] != ' ' {
+		return text, true
+	}
+	return term, false
+}
+
+// entryMatches checks if an entry contains every search term and none of
+// the excluded ones
 func entryMatches(entry map[string]any, terms []string) bool {
 	if len(terms) == 0 {
 		return true
 	}
-	blob, err := json.Marshal(entry)
-	if err != nil {
+	haystack, ok := searchText(entry)
+	if !ok {
 		return false
 	}
-	haystack := strings.ToLower(string(blob))
+	return textMatches(haystack, terms)
+}
+
+// textMatches checks if an entry's searchText contains every search term and
+// none of the excluded ones
+func textMatches(haystack string, terms []string) bool {
 	for _, term := range terms {
-		if !strings.Contains(haystack, term) {
+		text, excluded := searchTerm(term)
+		if strings.Contains(haystack, text) == excluded {
+			return false
+		}
+	}
+	return true
+}
+
+// matchMode selects how client-side search terms match entries (--match-mode)
+type matchMode string
+
+const (
+	matchSubstring matchMode = "substring" // Anywhere in the entry's JSON, keys included
+	matchWord      matchMode = "word"      // Whole words within values, so "error" skips "errorless"
+	matchValue     matchMode = "value"     // Within field values only, never keys
+)
+
+// parseMatchMode validates a --match-mode value ("field:value" is accepted
+// as another name for value)
+func parseMatchMode(s string) (matchMode, error) {
+	switch mode := matchMode(strings.ToLower(strings.TrimSpace(s))); mode {
+	case "", matchSubstring:
+		return matchSubstring, nil
+	case matchWord, matchValue:
+		return mode, nil
+	case "field:value":
+		return matchValue, nil
+	}
+	return "", fmt.Errorf("invalid --match-mode %q: use substring, word, or value", s)
+}
+
+// wordPatterns caches the compiled whole-word pattern for each search term.
+// The term must be bounded by non-word characters or the ends of the value,
+// which unlike \b also works for terms like "/api" or "c++".
+var wordPatterns sync.Map
+
+// matches checks if an entry matches all (lowercased) search terms
+func (m matchMode) matches(entry map[string]any, terms []string) bool {
+	switch m {
+	case matchWord:
+		var values []string
+		collectValues(entry, &values)
+		for _, term := range terms {
+			text, excluded := searchTerm(term)
+			re, ok := wordPatterns.Load(text)
+			if !ok {
+				re, _ = wordPatterns.LoadOrStore(text, regexp.MustCompile(`(^|\W)`+regexp.QuoteMeta(text)+`(\W|$)`))
+			}
+			if slices.ContainsFunc(values, re.(*regexp.Regexp).MatchString) == excluded {
+				return false
+			}
+		}
+		return true
+	case matchValue:
+		var values []string
+		collectValues(entry, &values)
+		for _, term := range terms {
+			text, excluded := searchTerm(term)
+			if slices.ContainsFunc(values, func(v string) bool { return strings.Contains(v, text) }) == excluded {
+				return false
+			}
+		}
+		return true
+	}
+	return entryMatches(entry, terms)
+}
+
+// collectValues appends every scalar value in a decoded JSON value, lowercased
+func collectValues(value any, out *[]string) {
+	switch v := value.(type) {
+	case map[string]any:
+		for _, child := range v {
+			collectValues(child, out)
+		}
+	case []any:
+		for _, child := range v {
+			collectValues(child, out)
+		}
+	case nil:
+	default:
+		*out = append(*out, strings.ToLower(tailstream.Stringify(v)))
+	}
+}
+
+// fieldFilter is a parsed --filter expression such as "fields.http.status>=500"
+type fieldFilter struct {
+	Field    string
+	Operator string
+	Value    string
+}
+
+// filterOperators are checked longest first so ">=" isn't read as ">"
+var filterOperators = []string{">=", "<=", "!=", "=", ">", "<"}
+
+// parseFieldFilter parses "field<op>value", where field may be a dotted path
+// and op is one of =, !=, >, >=, <, <=
+func parseFieldFilter(expr string) (fieldFilter, error) {
+	for i := 0; i < len(expr); i++ {
+		for _, op := range filterOperators {
+			if !strings.HasPrefix(expr[i:], op) {
+				continue
+			}
+			field := strings.TrimSpace(expr[:i])
+			if field == "" {
+				return fieldFilter{}, fmt.Errorf("invalid filter %q: missing field name", expr)
+			}
+			return fieldFilter{Field: field, Operator: op, Value: strings.TrimSpace(expr[i+len(op):])}, nil
+		}
+	}
+	return fieldFilter{}, fmt.Errorf("invalid filter %q: expected field<op>value with one of %s", expr, strings.Join(filterOperators, " "))
+}
+
+// parseFiltersJSON parses a --filters-json value: a JSON array of filter
+// objects in the API's format, each with at least a "field". Anything else in
+// an object (operators, values, nested groups) is passed through untouched,
+// numbers included.
+func parseFiltersJSON(value string) ([]map[string]any, error) {
+	var filters []map[string]any
+	decoder := json.NewDecoder(strings.NewReader(value))
+	decoder.UseNumber()
+	if err := decoder.Decode(&filters); err != nil {
+		return nil, fmt.Errorf("invalid --filters-json: expected a JSON array of filter objects: %v", err)
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("invalid --filters-json: unexpected data after the array")
+	}
+	for i, f := range filters {
+		if field, ok := f["field"].(string); !ok || field == "" {
+			return nil, fmt.Errorf("invalid --filters-json: filter %d has no \"field\"", i+1)
+		}
+	}
+	return filters, nil
+}
+
+// serverFilter returns the filter in the API's filters format. Numeric
+// values are sent as JSON numbers so range comparisons work server-side.
+func (f fieldFilter) serverFilter() map[string]any {
+	var value any = f.Value
+	if _, err := strconv.ParseFloat(f.Value, 64); err == nil {
+		value = json.Number(f.Value)
+	}
+	return map[string]any{
+		"field":    f.Field,
+		"operator": f.Operator,
+		"value":    value,
+	}
+}
+
+// matches reports whether the entry satisfies the filter, resolving dotted
+// paths through nested objects. Values compare numerically when possible.
+func (f fieldFilter) matches(entry map[string]any) bool {
+	v, ok := tailstream.LogEntry(entry).Field(f.Field)
+	if !ok {
+		return f.Operator == "!="
+	}
+	cmp := compareValues(v, f.Value)
+	switch f.Operator {
+	case "=":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	}
+	return false
+}
+
+// filtersMatch reports whether the entry satisfies every filter
+func filtersMatch(entry map[string]any, filters []fieldFilter) bool {
+	for _, f := range filters {
+		if !f.matches(entry) {
 			return false
 		}
 	}
@@ -1,10 +1,35 @@
 package main
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
+
+	"tailstream/client/pkg/tailstream"
 )
 
 func TestFetchUserStreams(t *testing.T) {
@@ -89,6 +114,72 @@ func TestEntryMatches(t *testing.T) {
 	}
 }
 
+func TestNormalizeQueriesExclusions(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"error -healthcheck", []string{"error", "-healthcheck"}},
+		{"Connection  Refused -GET -ping", []string{"connection refused", "-get", "-ping"}},
+		{"-healthcheck", []string{"-healthcheck"}},
+		{"GET - 200", []string{"get - 200"}}, // A lone '-' is text
+		{`exit \-1 -debug`, []string{"exit -1", "-debug"}},
+		{`\-1`, []string{`\-1`}}, // Still escaped, so it isn't read as an exclusion
+	}
+	for _, tt := range tests {
+		if got := normalizeQueries([]string{tt.input}); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("normalizeQueries(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestEntryMatchesExclusions(t *testing.T) {
+	healthcheck := map[string]any{"message": "GET /healthcheck error", "level": "info"}
+	orders := map[string]any{"message": "GET /orders error", "level": "error"}
+	terms := normalizeQueries([]string{"error -healthcheck"})
+	if entryMatches(healthcheck, terms) {
+		t.Error("expected the excluded term to reject the entry")
+	}
+	if !entryMatches(orders, terms) {
+		t.Error("expected an entry without the excluded term to match")
+	}
+	// Exclusions alone match everything else
+	if !entryMatches(orders, []string{"-healthcheck"}) || entryMatches(healthcheck, []string{"-healthcheck"}) {
+		t.Error("unexpected result for an exclusion-only search")
+	}
+	if !matchWord.matches(orders, []string{"error", "-order"}) {
+		t.Error("word mode should only exclude whole words")
+	}
+	// An escaped '-' searches for the word itself
+	exit := map[string]any{"message": "worker exited with -1"}
+	if !entryMatches(exit, normalizeQueries([]string{`\-1`})) || entryMatches(orders, normalizeQueries([]string{`\-1`})) {
+		t.Error(`expected "\-1" to search for "-1"`)
+	}
+}
+
+func TestFetcherAppliesExclusionsClientSide(t *testing.T) {
+	var serverQuery string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serverQuery = r.URL.Query().Get("filters")
+		json.NewEncoder(w).Encode(map[string]any{"data": []map[string]any{
+			{"raw_message": "timeout on /healthcheck"},
+			{"raw_message": "timeout on /orders"},
+		}})
+	}))
+	defer server.Close()
+
+	entries, _, _, _, err := createFetcher(server.URL, "token", "s", url.Values{}, nil, fetchOptions{})("", "timeout -healthcheck")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(serverQuery, `"value":"timeout"`) || strings.Contains(serverQuery, "healthcheck") {
+		t.Errorf("expected only the phrase to be sent to the server, got %s", serverQuery)
+	}
+	if len(entries) != 1 || entries[0]["raw_message"] != "timeout on /orders" {
+		t.Errorf("expected the excluded entry to be dropped, got %v", entries)
+	}
+}
+
 func TestEntryMatchesMultipleTerms(t *testing.T) {
 	entry := map[string]any{
 		"message": "Database connection error",
@@ -112,3 +203,756 @@ func TestEntryMatchesMultipleTerms(t *testing.T) {
 	}
 }
 
+
+func TestNewRateLimiter(t *testing.T) {
+	if newRateLimiter(0) != nil {
+		t.Error("expected nil limiter when rate limiting is disabled")
+	}
+	if newRateLimiter(-1) != nil {
+		t.Error("expected nil limiter for negative rate")
+	}
+	limiter := newRateLimiter(2)
+	if limiter == nil {
+		t.Fatal("expected limiter for positive rate")
+	}
+	if limiter.Limit() != 2 {
+		t.Errorf("expected limit 2, got %v", limiter.Limit())
+	}
+}
+
+func TestMeasureClockOffset(t *testing.T) {
+	// Server clock runs 10 minutes ahead
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			t.Errorf("unexpected method: %s", r.Method)
+		}
+		w.Header().Set("Date", time.Now().Add(10*time.Minute).UTC().Format(http.TimeFormat))
+	}))
+	defer server.Close()
+
+	offset, err := measureClockOffset(server.Client(), server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if offset < 9*time.Minute || offset > 11*time.Minute {
+		t.Errorf("expected offset around 10m, got %v", offset)
+	}
+}
+
+func TestClockOffsetFromResponseMissingDate(t *testing.T) {
+	resp := &http.Response{Header: http.Header{}}
+	if _, err := clockOffsetFromResponse(resp, time.Now()); err == nil {
+		t.Fatal("expected error when Date header is missing")
+	}
+}
+
+func TestFetcherFollowsNextLink(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "2" {
+			json.NewEncoder(w).Encode(map[string]any{
+				"data": []map[string]any{{"raw_message": "second"}},
+				"meta": map[string]any{"has_more": false},
+			})
+			return
+		}
+		if r.URL.Query().Get("cursor") != "" {
+			t.Errorf("link-based request should not send a cursor: %s", r.URL)
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"data":  []map[string]any{{"raw_message": "first"}},
+			"meta":  map[string]any{"has_more": true},
+			"links": map[string]any{"next": "/api/streams/s/logs?page=2"},
+		})
+	}))
+	defer server.Close()
+
+	fetcher := createFetcher(server.URL, "token", "s", url.Values{}, nil, fetchOptions{})
+	entries, hasMore, _, next, err := fetcher("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 1 || !hasMore || !tailstream.IsNextLink(next) {
+		t.Fatalf("unexpected first page: %v %v %q", entries, hasMore, next)
+	}
+
+	entries, hasMore, _, _, err = fetcher(next, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 1 || entries[0]["raw_message"] != "second" || hasMore {
+		t.Fatalf("unexpected second page: %v %v", entries, hasMore)
+	}
+}
+
+func TestFetcherRejectsForeignNextLink(t *testing.T) {
+	requested := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = true
+	}))
+	defer server.Close()
+
+	fetcher := createFetcher("https://app.example.com", "token", "s", url.Values{}, nil, fetchOptions{})
+	if _, _, _, _, err := fetcher(server.URL+"/api/streams/s/logs?page=2", ""); !errors.Is(err, tailstream.ErrForeignNextLink) {
+		t.Errorf("expected ErrForeignNextLink, got %v", err)
+	}
+	if requested {
+		t.Error("expected the foreign link not to be requested")
+	}
+}
+
+func TestFetcherPostsLongQueries(t *testing.T) {
+	var method, filters, contentType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		method, filters, contentType = r.Method, r.FormValue("filters"), r.Header.Get("Content-Type")
+		json.NewEncoder(w).Encode(map[string]any{"data": []map[string]any{}})
+	}))
+	defer server.Close()
+
+	short := url.Values{"filters": {`[{"field":"level","value":"error"}]`}}
+	if _, _, _, _, err := createFetcher(server.URL, "token", "s", short, nil, fetchOptions{})("", ""); err != nil {
+		t.Fatal(err)
+	}
+	if method != http.MethodGet || filters != short.Get("filters") {
+		t.Errorf("expected a GET with the filters, got %s %q", method, filters)
+	}
+
+	long := url.Values{"filters": {strings.Repeat("x", tailstream.MaxGetURLLength)}}
+	if _, _, _, _, err := createFetcher(server.URL, "token", "s", long, nil, fetchOptions{})("", ""); err != nil {
+		t.Fatal(err)
+	}
+	if method != http.MethodPost || filters != long.Get("filters") || contentType != "application/x-www-form-urlencoded" {
+		t.Errorf("expected a form POST for a long query, got %s with %d filter bytes (%s)", method, len(filters), contentType)
+	}
+
+	if _, _, _, _, err := createFetcher(server.URL, "token", "s", short, nil, fetchOptions{Post: true})("", ""); err != nil {
+		t.Fatal(err)
+	}
+	if method != http.MethodPost || filters != short.Get("filters") {
+		t.Errorf("expected --post to force a POST, got %s %q", method, filters)
+	}
+}
+
+func TestFetcherAuthorizationHeader(t *testing.T) {
+	var got []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Values("Authorization")
+		json.NewEncoder(w).Encode(map[string]any{"data": []map[string]any{}})
+	}))
+	defer server.Close()
+
+	if _, _, _, _, err := createFetcher(server.URL, "token", "s", url.Values{}, nil, fetchOptions{})("", ""); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []string{"Bearer token"}) {
+		t.Errorf("expected bearer token, got %q", got)
+	}
+
+	// Public streams (--public) are queried without any Authorization header
+	if _, _, _, _, err := createFetcher(server.URL, "", "s", url.Values{}, nil, fetchOptions{})("", ""); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Errorf("expected no Authorization header without a token, got %q", got)
+	}
+}
+
+func TestFetcherTimeouts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		json.NewEncoder(w).Encode(map[string]any{"data": []map[string]any{}})
+	}))
+	defer server.Close()
+
+	// Per-request timeout shorter than the response time fails the page
+	fetcher := createFetcher(server.URL, "token", "s", url.Values{}, nil, fetchOptions{Timeout: 10 * time.Millisecond})
+	if _, _, _, _, err := fetcher("", ""); err == nil {
+		t.Error("expected per-request timeout to fail the fetch")
+	}
+
+	// An expired overall context stops fetching even with a generous per-request timeout
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	fetcher = createFetcher(server.URL, "token", "s", url.Values{}, nil, fetchOptions{Context: ctx, Timeout: time.Second})
+	if _, _, _, _, err := fetcher("", ""); err == nil {
+		t.Error("expected cancelled overall context to fail the fetch")
+	}
+
+	// Each page gets a fresh deadline
+	fetcher = createFetcher(server.URL, "token", "s", url.Values{}, nil, fetchOptions{Timeout: time.Second})
+	for i := 0; i < 2; i++ {
+		if _, _, _, _, err := fetcher("", ""); err != nil {
+			t.Fatalf("page %d: unexpected error: %v", i+1, err)
+		}
+	}
+}
+
+func TestResolveStreamName(t *testing.T) {
+	streams := []Stream{
+		{Name: "Production API", StreamID: "prod-api"},
+		{Name: "Production API Workers", StreamID: "prod-workers"},
+		{Name: "Staging", StreamID: "staging"},
+	}
+
+	// Exact match wins even when it's also a partial match of another stream
+	stream, err := resolveStreamName(streams, "production api")
+	if err != nil || stream.StreamID != "prod-api" {
+		t.Errorf("expected prod-api, got %v (%v)", stream.StreamID, err)
+	}
+
+	// Unique partial match
+	stream, err = resolveStreamName(streams, "stag")
+	if err != nil || stream.StreamID != "staging" {
+		t.Errorf("expected staging, got %v (%v)", stream.StreamID, err)
+	}
+
+	// Ambiguous partial match lists candidates
+	_, err = resolveStreamName(streams, "Production")
+	if err == nil || !strings.Contains(err.Error(), "prod-workers") {
+		t.Errorf("expected ambiguity error listing matches, got %v", err)
+	}
+
+	if _, err := resolveStreamName(streams, "missing"); err == nil {
+		t.Error("expected error for unknown stream")
+	}
+}
+
+func TestAPIVersionMismatch(t *testing.T) {
+	tests := []struct {
+		version  string
+		mismatch bool
+	}{
+		{"", false},
+		{"1", false},
+		{"v1", false},
+		{"1.4.2", false},
+		{"2", true},
+		{"V2.0", true},
+		{"0.9", true},
+		{"beta", false},
+	}
+	for _, tt := range tests {
+		if got := apiVersionMismatch(tt.version); got != tt.mismatch {
+			t.Errorf("apiVersionMismatch(%q) = %v, want %v", tt.version, got, tt.mismatch)
+		}
+	}
+}
+
+func TestParseFieldFilter(t *testing.T) {
+	tests := []struct {
+		expr string
+		want fieldFilter
+	}{
+		{"status>=500", fieldFilter{"status", ">=", "500"}},
+		{"fields.http.status = 404", fieldFilter{"fields.http.status", "=", "404"}},
+		{"env!=prod", fieldFilter{"env", "!=", "prod"}},
+		{"duration_ms<250", fieldFilter{"duration_ms", "<", "250"}},
+		{"path=/a=b", fieldFilter{"path", "=", "/a=b"}},
+	}
+	for _, tt := range tests {
+		got, err := parseFieldFilter(tt.expr)
+		if err != nil {
+			t.Fatalf("parseFieldFilter(%q) failed: %v", tt.expr, err)
+		}
+		if got != tt.want {
+			t.Errorf("parseFieldFilter(%q) = %+v, want %+v", tt.expr, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"status", ">=500", ""} {
+		if _, err := parseFieldFilter(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+
+	// Numeric values are sent to the server as numbers
+	raw, _ := json.Marshal(fieldFilter{"fields.http.status", ">=", "500"}.serverFilter())
+	var decoded map[string]any
+	json.Unmarshal(raw, &decoded)
+	if decoded["field"] != "fields.http.status" || decoded["operator"] != ">=" || decoded["value"] != float64(500) {
+		t.Errorf("unexpected server filter: %s", raw)
+	}
+}
+
+func TestParseFiltersJSON(t *testing.T) {
+	filters, err := parseFiltersJSON(`[{"field":"status","operator":">=","value":500}]`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(filters) != 1 || filters[0]["value"] != json.Number("500") {
+		t.Errorf("unexpected filters: %v", filters)
+	}
+
+	for _, bad := range []string{
+		`{"field":"status"}`,  // Not an array
+		`[{"field":"status"}`, // Truncated
+		`[{"operator":"="}]`,  // No field
+		`[{"field":""}]`,
+		`[] []`,
+		`not json`,
+	} {
+		if _, err := parseFiltersJSON(bad); err == nil {
+			t.Errorf("expected error for %s", bad)
+		}
+	}
+}
+
+func TestFieldFilterMatches(t *testing.T) {
+	entry := map[string]any{
+		"env": "prod",
+		"fields": map[string]any{
+			"http": map[string]any{"status": float64(503)},
+		},
+	}
+
+	tests := []struct {
+		filter fieldFilter
+		want   bool
+	}{
+		{fieldFilter{"fields.http.status", ">=", "500"}, true},
+		{fieldFilter{"http.status", "<", "500"}, false},
+		{fieldFilter{"http.status", "=", "503"}, true},
+		{fieldFilter{"env", "!=", "prod"}, false},
+		{fieldFilter{"missing", "=", "x"}, false},
+		{fieldFilter{"missing", "!=", "x"}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.filter.matches(entry); got != tt.want {
+			t.Errorf("%+v.matches() = %v, want %v", tt.filter, got, tt.want)
+		}
+	}
+
+	if !filtersMatch(entry, nil) {
+		t.Error("expected no filters to match every entry")
+	}
+}
+
+func TestCursorLoop(t *testing.T) {
+	var loop cursorLoop
+	if loop.advance("", "a") || loop.advance("a", "b") || loop.advance("b", "c") {
+		t.Error("advancing cursors reported a loop")
+	}
+	if !loop.advance("c", "c") {
+		t.Error("same cursor returned again was not detected")
+	}
+
+	// A cycle through an earlier cursor is caught too
+	loop = cursorLoop{}
+	loop.advance("a", "b")
+	if !loop.advance("b", "a") {
+		t.Error("cycle back to an earlier cursor was not detected")
+	}
+
+	// Only recent cursors are remembered
+	loop = cursorLoop{}
+	for i := 0; i < cursorHistory+1; i++ {
+		loop.advance(strconv.Itoa(i), strconv.Itoa(i+1))
+	}
+	if loop.advance("x", "0") {
+		t.Error("cursor older than the history window reported as a loop")
+	}
+}
+
+func TestDiagnoseRequestError(t *testing.T) {
+	// A closed listener gives a real "connection refused"
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	refusedURL := "http://" + ln.Addr().String()
+	ln.Close()
+
+	tlsServer := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	tlsServer.Config.ErrorLog = log.New(io.Discard, "", 0) // Expected handshake failures
+	tlsServer.StartTLS()
+	defer tlsServer.Close()
+	plainServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer plainServer.Close()
+	plainAsTLS := strings.Replace(plainServer.URL, "http://", "https://", 1)
+
+	tests := []struct {
+		name    string
+		baseURL string
+		err     error
+		want    string
+	}{
+		{"dns", "https://logs.invalid", &url.Error{Op: "Get", URL: "https://logs.invalid", Err: &net.DNSError{Err: "no such host", Name: "logs.invalid", IsNotFound: true}}, "cannot resolve host logs.invalid"},
+		{"refused", refusedURL, nil, "refused"},
+		{"untrusted certificate", tlsServer.URL, nil, "not trusted"},
+		{"plain HTTP server", plainAsTLS, nil, "did not answer with TLS"},
+	}
+	for _, tt := range tests {
+		err := tt.err
+		if err == nil {
+			_, err = http.Get(tt.baseURL)
+			if err == nil {
+				t.Fatalf("%s: expected request to fail", tt.name)
+			}
+		}
+		got := diagnoseRequestError(tt.baseURL, err)
+		if !strings.Contains(got.Error(), tt.want) || !strings.Contains(got.Error(), "Hint:") {
+			t.Errorf("%s: diagnoseRequestError = %q, want %q with a hint", tt.name, got, tt.want)
+		}
+	}
+
+	// Unrecognized errors pass through untouched
+	other := errors.New("boom")
+	if got := diagnoseRequestError("https://example.com", other); got != other {
+		t.Errorf("unexpected rewrite of unrelated error: %v", got)
+	}
+}
+
+func TestStatusHint(t *testing.T) {
+	if !strings.Contains(statusHint(http.StatusUnauthorized), "--login") {
+		t.Error("401 hint should suggest --login")
+	}
+	if statusHint(http.StatusInternalServerError) != "" {
+		t.Error("unexpected hint for 500")
+	}
+}
+
+func TestErrorBody(t *testing.T) {
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	zw.Write([]byte("  token expired\n"))
+	zw.Close()
+	resp := &http.Response{
+		Header: http.Header{"Content-Encoding": {"gzip"}},
+		Body:   io.NopCloser(&compressed),
+	}
+	if got := errorBody(resp); got != "token expired" {
+		t.Errorf("expected the decompressed error text, got %q", got)
+	}
+}
+
+func TestGetHTTPClientInsecure(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Config.ErrorLog = log.New(io.Discard, "", 0) // Expected handshake failures
+	server.StartTLS()
+	defer server.Close()
+
+	defer func(orig bool) { insecureTLS = orig }(insecureTLS)
+
+	insecureTLS = false
+	if _, err := getHTTPClient(time.Second).Get(server.URL); err == nil {
+		t.Error("self-signed certificate accepted without --insecure")
+	}
+
+	insecureTLS = true
+	resp, err := getHTTPClient(time.Second).Get(server.URL)
+	if err != nil {
+		t.Fatalf("request with --insecure failed: %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestLoadCACert(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	defer server.Close()
+
+	dir := t.TempDir()
+	caPath := filepath.Join(dir, "ca.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caPath, certPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	pool, err := loadCACert(caPath)
+	if err != nil {
+		t.Fatalf("loadCACert: %v", err)
+	}
+	defer func(orig *x509.CertPool) { customRootCAs = orig }(customRootCAs)
+	customRootCAs = pool
+	resp, err := getHTTPClient(time.Second).Get(server.URL)
+	if err != nil {
+		t.Fatalf("request trusting the test CA failed: %v", err)
+	}
+	resp.Body.Close()
+
+	// Files without certificates are rejected
+	badPath := filepath.Join(dir, "bad.pem")
+	os.WriteFile(badPath, []byte("not a certificate"), 0600)
+	if _, err := loadCACert(badPath); err == nil || !strings.Contains(err.Error(), "no PEM certificates") {
+		t.Errorf("expected parse error, got %v", err)
+	}
+	if _, err := loadCACert(filepath.Join(dir, "missing.pem")); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+func TestLoadClientCert(t *testing.T) {
+	// Self-signed client certificate
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "tailstream-test-client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	certPath, keyPath := filepath.Join(dir, "client.pem"), filepath.Join(dir, "client.key")
+	os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+
+	if _, err := loadClientCert(certPath, ""); err == nil {
+		t.Error("expected error for certificate without key")
+	}
+	if _, err := loadClientCert(certPath, certPath); err == nil {
+		t.Error("expected error for mismatched key file")
+	}
+	cert, err := loadClientCert(certPath, keyPath)
+	if err != nil {
+		t.Fatalf("loadClientCert: %v", err)
+	}
+
+	// The server only answers clients presenting a certificate
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) == 0 {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	defer func(orig bool, certs []tls.Certificate) {
+		insecureTLS, clientCertificates = orig, certs
+	}(insecureTLS, clientCertificates)
+	insecureTLS = true // Trust the test server's own certificate
+
+	clientCertificates = nil
+	if _, err := getHTTPClient(time.Second).Get(server.URL); err == nil {
+		t.Error("request without a client certificate succeeded")
+	}
+
+	clientCertificates = []tls.Certificate{cert}
+	resp, err := getHTTPClient(time.Second).Get(server.URL)
+	if err != nil {
+		t.Fatalf("request with client certificate failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestUploadShare(t *testing.T) {
+	var received string
+	var contentType string
+	reply := func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received, contentType = string(body), r.Header.Get("Content-Type")
+		switch r.URL.Path {
+		case "/location":
+			w.Header().Set("Location", "/p/abc")
+			w.WriteHeader(http.StatusCreated)
+		case "/json":
+			w.Write([]byte(`{"url": "https://paste.example/p/json"}`))
+		case "/plain":
+			w.Write([]byte("https://paste.example/p/plain\n"))
+		case "/empty":
+		default:
+			http.Error(w, "nope", http.StatusForbidden)
+		}
+	}
+	server := httptest.NewServer(http.HandlerFunc(reply))
+	defer server.Close()
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/location", server.URL + "/p/abc"},
+		{"/json", "https://paste.example/p/json"},
+		{"/plain", "https://paste.example/p/plain"},
+	}
+	for _, tt := range tests {
+		link, err := uploadShare(server.URL+tt.path, []byte("line 1\n"), "text/plain")
+		if err != nil || link != tt.want {
+			t.Errorf("%s: uploadShare = %q, %v; want %q", tt.path, link, err, tt.want)
+		}
+	}
+	if received != "line 1\n" || contentType != "text/plain" {
+		t.Errorf("service received %q (%s)", received, contentType)
+	}
+
+	if _, err := uploadShare(server.URL+"/empty", []byte("x"), "text/plain"); err == nil {
+		t.Error("expected error when the service returns no link")
+	}
+	if _, err := uploadShare(server.URL+"/forbidden", []byte("x"), "text/plain"); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("expected status error, got %v", err)
+	}
+}
+
+func TestMatchModes(t *testing.T) {
+	entry := map[string]any{
+		"message": "request errorless, took 5s",
+		"fields":  map[string]any{"error": nil, "path": "/api/orders", "tags": []any{"Timeout", "C++"}},
+	}
+	tests := []struct {
+		mode  matchMode
+		terms []string
+		want  bool
+	}{
+		{matchSubstring, []string{"error"}, true}, // Matches "errorless" and the key
+		{matchWord, []string{"error"}, false},     // Neither "errorless" nor the key
+		{matchWord, []string{"errorl"}, false},
+		{matchWord, []string{"orders", "5s"}, true},
+		{matchWord, []string{"/api/orders"}, true}, // Punctuation at the edges
+		{matchWord, []string{"c++"}, true},
+		{matchWord, []string{"path"}, false},
+		{matchValue, []string{"error"}, true}, // "errorless" is a value
+		{matchValue, []string{"path"}, false}, // Keys don't count
+		{matchValue, []string{"timeout", "/api"}, true},
+		{matchValue, []string{"fields"}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.mode.matches(entry, tt.terms); got != tt.want {
+			t.Errorf("%s.matches(%v) = %v, want %v", tt.mode, tt.terms, got, tt.want)
+		}
+	}
+
+	for input, want := range map[string]matchMode{"": matchSubstring, "WORD": matchWord, "field:value": matchValue} {
+		if got, err := parseMatchMode(input); err != nil || got != want {
+			t.Errorf("parseMatchMode(%q) = %q, %v; want %q", input, got, err, want)
+		}
+	}
+	if _, err := parseMatchMode("regex"); err == nil {
+		t.Error("expected error for unknown mode")
+	}
+}
+
+func TestFetcherPageSizeStopsAtLimit(t *testing.T) {
+	// An endless stream that honors the limit param and records it
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit := r.URL.Query().Get("limit")
+		requested = append(requested, limit)
+		n, _ := strconv.Atoi(limit)
+		data := make([]map[string]any, n)
+		for i := range data {
+			data[i] = map[string]any{"message": "x"}
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"data": data,
+			"meta": map[string]any{"has_more": true, "next_cursor": "c" + strconv.Itoa(len(requested))},
+		})
+	}))
+	defer server.Close()
+
+	// --per-page 4 --limit 10, with the first page of 4 already printed
+	const perPage, limit = 4, 10
+	printed := 4
+	fetcher := createFetcher(server.URL, "token", "s", url.Values{"limit": {"4"}}, nil, fetchOptions{
+		PageSize: func() int { return pageSizeFor(perPage, limit, printed, false) },
+	})
+	pages, err := walkPages(fetcher, true, "c0", 0, func(page []map[string]any) bool {
+		printed += len(page)
+		return printed < limit
+	})
+	if err != nil || printed != limit || pages != 2 {
+		t.Errorf("got %d entries over %d pages, want %d over 2", printed, pages, limit)
+	}
+	if !reflect.DeepEqual(requested, []string{"4", "2"}) {
+		t.Errorf("requested limits %v, want [4 2]", requested)
+	}
+}
+
+func TestReadSSE(t *testing.T) {
+	stream := ": keep-alive\r\n" +
+		"retry: 500\r\n" +
+		"id: 7\r\n" +
+		"data: {\"message\":\r\n" +
+		"data:  \"two lines\"}\r\n" +
+		"\r\n" +
+		"event: ping\n" +
+		"data: {}\n" +
+		"\n" +
+		"data: {\"message\":\"unterminated\"}" // Never dispatched without a blank line
+	var events []sseEvent
+	retry, id, err := readSSE(strings.NewReader(stream), "", func(e sseEvent) { events = append(events, e) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []sseEvent{
+		{ID: "7", Data: "{\"message\":\n \"two lines\"}"},
+		{ID: "7", Event: "ping", Data: "{}"},
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("events = %q, want %q", events, want)
+	}
+	if retry != 500*time.Millisecond || id != "7" {
+		t.Errorf("retry, id = %v, %q; want 500ms, 7", retry, id)
+	}
+}
+
+func TestStreamSSEReconnects(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	connections := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/streams/s/logs/stream" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		connections++
+		switch connections {
+		case 1:
+			fmt.Fprint(w, "retry: 1\nid: a\ndata: {\"id\":\"1\",\"timestamp\":\"2024-01-01T00:00:01Z\",\"message\":\"first\"}\n\n")
+			fmt.Fprint(w, "data: {\"id\":\"2\",\"timestamp\":\"2024-01-01T00:00:02Z\",\"message\":\"healthcheck\"}\n\n")
+		default:
+			if got := r.Header.Get("Last-Event-ID"); got != "a" {
+				t.Errorf("expected Last-Event-ID a on reconnect, got %q", got)
+			}
+			// A replay of the first entry is deduplicated
+			fmt.Fprint(w, "id: b\ndata: [{\"id\":\"1\",\"timestamp\":\"2024-01-01T00:00:01Z\",\"message\":\"first\"},{\"id\":\"3\",\"timestamp\":\"2024-01-01T00:00:03Z\",\"message\":\"second\"}]\n\n")
+		}
+	}))
+	defer server.Close()
+
+	var got []string
+	f := &follower{since: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	err := streamSSE(server.URL, "token", "s", f, url.Values{}, normalizeQueries([]string{"-healthcheck"}), fetchOptions{Context: ctx}, func(entry map[string]any) {
+		got = append(got, entry["message"].(string))
+		if len(got) == 2 {
+			cancel()
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []string{"first", "second"}) {
+		t.Errorf("emitted %q, want first and second", got)
+	}
+}
+
+func TestStreamSSEUnsupported(t *testing.T) {
+	for name, handler := range map[string]http.HandlerFunc{
+		"404": func(w http.ResponseWriter, r *http.Request) { http.NotFound(w, r) },
+		"json": func(w http.ResponseWriter, r *http.Request) {
+			json.NewEncoder(w).Encode(map[string]any{"data": []any{}})
+		},
+	} {
+		server := httptest.NewServer(handler)
+		err := streamSSE(server.URL, "token", "s", &follower{}, url.Values{}, nil, fetchOptions{}, func(map[string]any) {})
+		server.Close()
+		if !errors.Is(err, errNoSSE) {
+			t.Errorf("%s: expected errNoSSE, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+// Package main - cache.go
+//
+// Optional on-disk cache for fetched log pages.
+//
+// When enabled with --cache (or `cache: true` in the config), raw page
+// responses are stored under the user's cache directory and reused for
+// identical requests within the TTL. This avoids repeated API calls when
+// re-running the same query during development or demos.
+//
+// Cache keys are a hash of the access token and the full request URL
+// (stream, normalized query, and cursor), so pages are never shared
+// between different tokens or users. Relative time bounds such as "-1h"
+// are keyed by the expression rather than the millisecond they resolved to,
+// so re-running the same relative query within the TTL hits the cache.
+//
+// The same directory holds the stream list from the last `streams` command,
+// so --stream-index can refer to a stream by its listed number.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	cacheDirName    = "tailstream-client"
+	defaultCacheTTL = 5 * time.Minute
+	streamListFile  = "streams.json" // Ordered stream list from the last `streams` command
+)
+
+// pageCache stores raw page responses on disk. A nil *pageCache is a valid,
+// disabled cache: lookups always miss and stores are ignored.
+type pageCache struct {
+	dir      string
+	ttl      time.Duration
+	relative map[string]string // Time params keyed by their relative expression (see relativeBound)
+}
+
+// defaultCacheDir returns the platform cache directory for the client
+func defaultCacheDir() (string, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, cacheDirName), nil
+}
+
+// determineCacheDir returns the cache directory to use (config > default)
+func determineCacheDir(config *ClientConfig) (string, error) {
+	if config != nil && config.CacheDir != "" {
+		return config.CacheDir, nil
+	}
+	return defaultCacheDir()
+}
+
+// newPageCache creates the cache directory (private to the user) if needed
+func newPageCache(dir string, ttl time.Duration) (*pageCache, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &pageCache{dir: dir, ttl: ttl}, nil
+}
+
+// relativeBound keys the time param (start_time or end_time) by value when
+// it is a relative expression like "-1h" or "now", instead of by the
+// timestamp it resolved to on this run
+func (c *pageCache) relativeBound(param, value string) {
+	if c == nil || !isRelativeTime(value) {
+		return
+	}
+	if c.relative == nil {
+		c.relative = make(map[string]string)
+	}
+	c.relative[param] = strings.TrimSpace(value)
+}
+
+// key returns the request URL with relative time bounds put back
+func (c *pageCache) key(requestURL string) string {
+	if len(c.relative) == 0 {
+		return requestURL
+	}
+	u, err := url.Parse(requestURL)
+	if err != nil {
+		return requestURL
+	}
+	q := u.Query()
+	for param, expr := range c.relative {
+		if q.Has(param) {
+			q.Set(param, "relative:"+expr)
+		}
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// path returns the cache file for a token and request URL
+func (c *pageCache) path(token, requestURL string) string {
+	sum := sha256.Sum256([]byte(token + "\n" + c.key(requestURL)))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// get returns the cached body for a request if it exists and is within the TTL
+func (c *pageCache) get(token, requestURL string) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+	path := c.path(token, requestURL)
+	info, err := os.Stat(path)
+	if err != nil || time.Since(info.ModTime()) > c.ttl {
+		return nil, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// put stores a response body. Failures are ignored since the cache is best-effort.
+func (c *pageCache) put(token, requestURL string, body []byte) {
+	if c == nil {
+		return
+	}
+	os.WriteFile(c.path(token, requestURL), body, 0600)
+}
+
+// clearCache removes every cached page in dir, and the saved stream list.
+// Nothing else is touched, since cache_dir may point at a directory that
+// holds other files.
+func clearCache(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || (entry.Name() != streamListFile && !isPageFile(entry.Name())) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// isPageFile reports whether name is a cached page, as named by pageCache.path
+func isPageFile(name string) bool {
+	hash, ok := strings.CutSuffix(name, ".json")
+	if !ok || len(hash) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(hash)
+	return err == nil
+}
+
+// streamList is the cached result of the `streams` command
+type streamList struct {
+	BaseURL string    `json:"base_url"`
+	SavedAt time.Time `json:"saved_at"`
+	Streams []Stream  `json:"streams"`
+}
+
+// saveStreamList stores the streams of baseURL in listed order
+func saveStreamList(dir, baseURL string, streams []Stream) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	data, err := json.Marshal(streamList{BaseURL: baseURL, SavedAt: time.Now(), Streams: streams})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, streamListFile), data, 0600)
+}
+
+// loadStreamList returns the streams saved for baseURL by the last `streams`
+// command. A list saved for another server doesn't count.
+func loadStreamList(dir, baseURL string) ([]Stream, error) {
+	data, err := os.ReadFile(filepath.Join(dir, streamListFile))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no saved stream list; run tailstream-client streams first")
+	}
+	if err != nil {
+		return nil, err
+	}
+	var list streamList
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("saved stream list is unreadable (%v); run tailstream-client streams again", err)
+	}
+	if list.BaseURL != baseURL {
+		return nil, fmt.Errorf("saved stream list is for %s; run tailstream-client streams again", list.BaseURL)
+	}
+	return list.Streams, nil
+}
+
+// streamAtIndex returns the stream ID listed as number index (1-based)
+func streamAtIndex(streams []Stream, index int) (string, error) {
+	if index < 1 || index > len(streams) {
+		return "", fmt.Errorf("stream index %d is out of range (1-%d); run tailstream-client streams to see the list", index, len(streams))
+	}
+	return streams[index-1].StreamID, nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPageCacheRoundTrip(t *testing.T) {
+	cache, err := newPageCache(filepath.Join(t.TempDir(), "pages"), time.Minute)
+	if err != nil {
+		t.Fatalf("newPageCache failed: %v", err)
+	}
+
+	const u = "https://example.com/api/streams/s1/logs?cursor=abc"
+	if _, ok := cache.get("tok", u); ok {
+		t.Fatal("expected miss on empty cache")
+	}
+
+	cache.put("tok", u, []byte(`{"data":[]}`))
+	body, ok := cache.get("tok", u)
+	if !ok || string(body) != `{"data":[]}` {
+		t.Fatalf("expected cached body, got %q (ok=%v)", body, ok)
+	}
+
+	if _, ok := cache.get("other-token", u); ok {
+		t.Error("expected pages not to be shared between tokens")
+	}
+}
+
+func TestPageCacheExpiry(t *testing.T) {
+	cache, err := newPageCache(t.TempDir(), time.Minute)
+	if err != nil {
+		t.Fatalf("newPageCache failed: %v", err)
+	}
+
+	const u = "https://example.com/logs"
+	cache.put("tok", u, []byte("old"))
+	stale := time.Now().Add(-2 * time.Minute)
+	if err := os.Chtimes(cache.path("tok", u), stale, stale); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := cache.get("tok", u); ok {
+		t.Error("expected expired page to miss")
+	}
+}
+
+func TestPageCacheRelativeBounds(t *testing.T) {
+	cache, err := newPageCache(t.TempDir(), time.Minute)
+	if err != nil {
+		t.Fatalf("newPageCache failed: %v", err)
+	}
+	cache.relativeBound("start_time", "-1h")
+	cache.relativeBound("end_time", "2024-01-02") // Absolute bounds stay as they are
+
+	cache.put("tok", "https://example.com/logs?end_time=1704239999999&start_time=1700000000000", []byte("x"))
+	if _, ok := cache.get("tok", "https://example.com/logs?end_time=1704239999999&start_time=1700000060000"); !ok {
+		t.Error("expected a later run of the same relative range to hit")
+	}
+	if _, ok := cache.get("tok", "https://example.com/logs?end_time=1704153599999&start_time=1700000000000"); ok {
+		t.Error("expected a different absolute bound to miss")
+	}
+}
+
+func TestPageCacheNil(t *testing.T) {
+	var cache *pageCache
+	cache.put("tok", "u", []byte("x"))
+	if _, ok := cache.get("tok", "u"); ok {
+		t.Error("nil cache should always miss")
+	}
+}
+
+func TestClearCache(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "pages")
+	cache, err := newPageCache(dir, time.Minute)
+	if err != nil {
+		t.Fatalf("newPageCache failed: %v", err)
+	}
+	cache.put("tok", "u", []byte("x"))
+	if err := saveStreamList(dir, "https://app.tailstream.io", nil); err != nil {
+		t.Fatal(err)
+	}
+	// cache_dir may be shared with unrelated files, which must survive
+	other := filepath.Join(dir, "notes.json")
+	if err := os.WriteFile(other, []byte("{}"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := clearCache(dir); err != nil {
+		t.Fatalf("clearCache failed: %v", err)
+	}
+	if _, err := os.Stat(cache.path("tok", "u")); !os.IsNotExist(err) {
+		t.Errorf("expected the cached page to be removed, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, streamListFile)); !os.IsNotExist(err) {
+		t.Errorf("expected the stream list to be removed, got %v", err)
+	}
+	if _, err := os.Stat(other); err != nil {
+		t.Errorf("expected other files to be kept, got %v", err)
+	}
+	if err := clearCache(filepath.Join(dir, "missing")); err != nil {
+		t.Errorf("clearing a missing cache should succeed, got %v", err)
+	}
+}
+
+func TestStreamList(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := loadStreamList(dir, "https://app.tailstream.io"); err == nil || !strings.Contains(err.Error(), "run tailstream-client streams") {
+		t.Errorf("expected a hint to run streams without a saved list, got %v", err)
+	}
+
+	streams := []Stream{{Name: "API", StreamID: "api-1"}, {Name: "Worker", StreamID: "worker-2"}}
+	if err := saveStreamList(dir, "https://app.tailstream.io", streams); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := loadStreamList(dir, "https://app.tailstream.io")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, err := streamAtIndex(loaded, 2); err != nil || id != "worker-2" {
+		t.Errorf("streamAtIndex(2) = %q, %v; want worker-2", id, err)
+	}
+	for _, index := range []int{0, 3, -1} {
+		if _, err := streamAtIndex(loaded, index); err == nil {
+			t.Errorf("expected index %d to be out of range", index)
+		}
+	}
+
+	// A list from another server must not be used
+	if _, err := loadStreamList(dir, "https://self-hosted.example"); err == nil {
+		t.Error("expected a list saved for another base URL to be rejected")
+	}
+}
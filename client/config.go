@@ -4,29 +4,58 @@
 //
 // This file handles loading and saving client configuration to ~/.tailstream-client.yaml,
 // including OAuth credentials, base URL, and default stream preferences.
-// It provides functions to determine the effective base URL from flags, config, or defaults.
+// It provides functions to determine the effective base URL from flags, config, or defaults,
+// and the access token from flags, an external credential helper, or the stored config.
+// It also loads query files (--query-file) and watches them for edits, and
+// runs `config doctor` to check a setup end to end.
 
 package main
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
 	"os"
+	"os/exec"
 	"os/user"
 	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 const (
-	configFileName = ".tailstream-client.yaml"
+	configFileName  = ".tailstream-client.yaml"
+	historyFileName = ".tailstream-client-history" // Interactive search history, one query per line
 )
 
 // ClientConfig stores the user's authentication and preferences
 type ClientConfig struct {
-	BaseURL       string `yaml:"base_url"`
-	AccessToken   string `yaml:"access_token"`
-	RefreshToken  string `yaml:"refresh_token"`
-	DefaultStream string `yaml:"default_stream"`
-	UpdatedAt     string `yaml:"updated_at"`
+	BaseURL       string            `yaml:"base_url"`
+	AccessToken   string            `yaml:"access_token"`
+	RefreshToken  string            `yaml:"refresh_token"`
+	DefaultStream string            `yaml:"default_stream"`
+	DefaultRange  string            `yaml:"default_range,omitempty"`     // e.g. "-1h", applied when no --from/--to is given
+	ClientID      string            `yaml:"client_id,omitempty"`         // OAuth client ID for self-hosted deployments
+	Scope         string            `yaml:"scope,omitempty"`             // OAuth scope requested at login
+	StreamNames   map[string]string `yaml:"stream_names,omitempty"`      // Cached --stream name -> stream_id resolutions
+	Cache         bool              `yaml:"cache,omitempty"`             // Enable the on-disk page cache by default
+	CacheDir      string            `yaml:"cache_dir,omitempty"`         // Overrides the default cache directory
+	EntryURL      string            `yaml:"entry_url,omitempty"`         // Web UI link pattern for a single entry
+	CredHelper    string            `yaml:"credential_helper,omitempty"` // Command that prints the access token on stdout
+	Keychain      string            `yaml:"keychain,omitempty"`          // OS keychain reference holding the tokens (--use-keychain)
+	ShareURL      string            `yaml:"share_url,omitempty"`         // Paste service endpoint for --share (POST, returns the link)
+	OutputFormat  string            `yaml:"output_format,omitempty"`     // Default --format for direct output, e.g. "ndjson"
+	UpdatedAt     string            `yaml:"updated_at"`
+
+	StreamDefaults map[string]StreamSettings `yaml:"stream_defaults,omitempty"`    // Per-stream defaults, keyed by stream_id
+	PrefetchAt     int                       `yaml:"prefetch_threshold,omitempty"` // Default --prefetch-threshold for interactive mode
 }
 
 // getConfigPath returns the path to the config file
@@ -70,7 +99,135 @@ func saveConfig(config *ClientConfig) error {
 		return err
 	}
 
-	return os.WriteFile(path, data, 0600)
+	return writeFileAtomic(path, data, 0600)
+}
+
+// writeFileAtomic replaces path with data without ever leaving a partly
+// written file: data goes to a temporary file in the same directory, which
+// is then renamed over path. On failure the temporary file is removed and
+// path is untouched. A symlinked path (e.g. a config kept in a dotfiles
+// repo) stays a symlink: the file it points to is replaced instead.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) (err error) {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+	if err = tmp.Chmod(perm); err != nil {
+		return err
+	}
+	if _, err = tmp.Write(data); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// loadSearchHistory returns the searches saved by earlier interactive
+// sessions, oldest first. A missing or unreadable file yields no history.
+func loadSearchHistory() []string {
+	usr, err := user.Current()
+	if err != nil {
+		return nil
+	}
+	data, err := os.ReadFile(filepath.Join(usr.HomeDir, historyFileName))
+	if err != nil {
+		return nil
+	}
+	var history []string
+	for _, line := range strings.Split(string(data), "\n") {
+		history = addHistory(history, line)
+	}
+	return history
+}
+
+// appendSearchHistory saves a search for future sessions, trimming the file
+// to the most recent entries. Failures are ignored; history is a convenience.
+func appendSearchHistory(query string) {
+	usr, err := user.Current()
+	if err != nil {
+		return
+	}
+	history := addHistory(loadSearchHistory(), query)
+	data := strings.Join(history, "\n") + "\n"
+	os.WriteFile(filepath.Join(usr.HomeDir, historyFileName), []byte(data), 0600)
+}
+
+// QueryFile holds query settings kept in a YAML file (--query-file). They
+// combine with the equivalent command-line flags.
+type QueryFile struct {
+	Levels   []string `yaml:"levels"`    // Like --level
+	MinLevel string   `yaml:"min_level"` // Like --min-level
+	Methods  []string `yaml:"methods"`   // Like --method
+	Search   []string `yaml:"search"`    // Like --search
+	Filters  []string `yaml:"filters"`   // Like --filter, e.g. "status>=500"
+
+	fieldFilters []fieldFilter // Parsed Filters
+}
+
+// loadQueryFile reads and validates a query file, expanding min_level into
+// levels and parsing the filter expressions
+func loadQueryFile(path string) (*QueryFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var qf QueryFile
+	if err := yaml.Unmarshal(data, &qf); err != nil {
+		return nil, fmt.Errorf("invalid query file %s: %v", path, err)
+	}
+	if qf.MinLevel != "" {
+		expanded, ok := levelsAtOrAbove(qf.MinLevel)
+		if !ok {
+			return nil, fmt.Errorf("invalid query file %s: unknown min_level %q", path, qf.MinLevel)
+		}
+		qf.Levels = append(qf.Levels, expanded...)
+	}
+	for _, expr := range qf.Filters {
+		f, err := parseFieldFilter(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid query file %s: %v", path, err)
+		}
+		qf.fieldFilters = append(qf.fieldFilters, f)
+	}
+	return &qf, nil
+}
+
+// fileWatch detects changes to a file by polling its modification time
+type fileWatch struct {
+	path    string
+	modTime time.Time
+}
+
+// newFileWatch starts watching path from its current state
+func newFileWatch(path string) *fileWatch {
+	w := &fileWatch{path: path}
+	w.changed()
+	return w
+}
+
+// changed reports whether the file was modified since the last call. A file
+// that can't be read is reported unchanged.
+func (w *fileWatch) changed() bool {
+	info, err := os.Stat(w.path)
+	if err != nil || info.ModTime().Equal(w.modTime) {
+		return false
+	}
+	w.modTime = info.ModTime()
+	return true
 }
 
 // determineBaseURL returns the base URL to use
@@ -83,3 +240,268 @@ func determineBaseURL(flagValue string, config *ClientConfig) string {
 	}
 	return defaultBaseURL
 }
+
+// determineToken returns the access token to use (flag > credential helper > keychain > config)
+func determineToken(flagValue string, config *ClientConfig) (string, error) {
+	if flagValue != "" {
+		return flagValue, nil
+	}
+	if config == nil {
+		return "", nil
+	}
+	if config.CredHelper != "" {
+		return runCredentialHelper(config.CredHelper)
+	}
+	if config.Keychain != "" {
+		return keychainToken(config.Keychain)
+	}
+	return config.AccessToken, nil
+}
+
+// readTokenFile reads a token from a file such as a mounted secret,
+// trimming the surrounding whitespace and trailing newline
+func readTokenFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("--token-file: %w", err)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("--token-file: %s is empty", path)
+	}
+	return token, nil
+}
+
+// runCredentialHelper runs the configured command through the shell and
+// returns the token it prints. The helper's stderr is passed through so it
+// can prompt for a keychain password or similar. It reads from the terminal
+// (as the viewer does) rather than stdin, which may be piped input meant for
+// the client; without a terminal it gets no input at all.
+func runCredentialHelper(command string) (string, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	if tty, err := openKeyboard(); err == nil {
+		cmd.Stdin = tty
+		if tty != os.Stdin {
+			defer tty.Close()
+		}
+	}
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("credential helper %q failed: %v", command, err)
+	}
+	token := strings.TrimSpace(stdout.String())
+	if token == "" {
+		return "", fmt.Errorf("credential helper %q printed no token", command)
+	}
+	return token, nil
+}
+
+// StreamSettings are defaults for one stream, applied whenever it is used.
+// Levels and filters apply when neither flags nor a query file give any; the
+// format applies without --format, ahead of output_format.
+type StreamSettings struct {
+	Levels   []string `yaml:"levels,omitempty"`    // Like --level
+	MinLevel string   `yaml:"min_level,omitempty"` // Like --min-level
+	Filters  []string `yaml:"filters,omitempty"`   // Like --filter, e.g. "status>=500"
+	Fields   []string `yaml:"fields,omitempty"`    // Shown on each line as name=value (see the v key)
+	Format   string   `yaml:"format,omitempty"`    // Like --format
+}
+
+// streamDefaults returns the configured defaults for a stream, or none
+func streamDefaults(config *ClientConfig, streamID string) StreamSettings {
+	if config == nil {
+		return StreamSettings{}
+	}
+	return config.StreamDefaults[streamID]
+}
+
+// applyStreamDefaults returns the levels, minimum level, and filters to query
+// with: those from the flags and query file, with the stream's defaults
+// filling in the levels (and minimum level) or the filters when none are given
+func applyStreamDefaults(levels []string, minLevel string, filters []fieldFilter, defaults StreamSettings) ([]string, string, []fieldFilter, error) {
+	if len(levels) == 0 && minLevel == "" {
+		levels, minLevel = slices.Clone(defaults.Levels), defaults.MinLevel
+	}
+	if len(filters) == 0 {
+		for _, expr := range defaults.Filters {
+			f, err := parseFieldFilter(expr)
+			if err != nil {
+				return nil, "", nil, err
+			}
+			filters = append(filters, f)
+		}
+	}
+	return levels, minLevel, filters, nil
+}
+
+// determineOutputFormat returns the --format to use (flag > config > default)
+func determineOutputFormat(flagValue string, config *ClientConfig) string {
+	if flagValue != "" {
+		return flagValue
+	}
+	if config != nil && config.OutputFormat != "" {
+		return config.OutputFormat
+	}
+	return "default"
+}
+
+// determinePrefetchThreshold returns the interactive auto-load threshold
+// (flag > config); 0 leaves it to --prefetch
+func determinePrefetchThreshold(flagValue int, config *ClientConfig) int {
+	if flagValue > 0 {
+		return flagValue
+	}
+	if config != nil && config.PrefetchAt > 0 {
+		return config.PrefetchAt
+	}
+	return 0
+}
+
+// determineStartTime returns the start time to use, falling back to the
+// configured default range when neither --from nor --to was given
+func determineStartTime(from, to string, noDefaultRange bool, config *ClientConfig) string {
+	from = strings.TrimSpace(from)
+	if from != "" || strings.TrimSpace(to) != "" || noDefaultRange {
+		return from
+	}
+	if config != nil {
+		return strings.TrimSpace(config.DefaultRange)
+	}
+	return ""
+}
+
+// determineOAuthClient returns the OAuth client ID and scope to use for login
+// (flag > config > default)
+func determineOAuthClient(flagClientID, flagScope string, config *ClientConfig) (string, string) {
+	clientID, scope := defaultClientID, defaultScope
+	if config != nil && config.ClientID != "" {
+		clientID = config.ClientID
+	}
+	if config != nil && config.Scope != "" {
+		scope = config.Scope
+	}
+	if flagClientID != "" {
+		clientID = flagClientID
+	}
+	if flagScope != "" {
+		scope = flagScope
+	}
+	return clientID, scope
+}
+
+// validateBaseURL checks that a base URL is an absolute http(s) URL
+func validateBaseURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid base URL %q: %v", raw, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("base URL %q must start with https:// (or http://)", raw)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("base URL %q has no host", raw)
+	}
+	return nil
+}
+
+// doctorCheck is one line of the `config doctor` checklist
+type doctorCheck struct {
+	name   string
+	ok     bool
+	detail string
+	fix    string // Remediation, shown for failed checks
+}
+
+// failedCheck builds a failed check from an error. A "Hint:" line in the
+// error (see diagnoseRequestError and statusHint) is more specific than
+// the given fix and replaces it.
+func failedCheck(name string, err error, fix string) doctorCheck {
+	detail, hint, found := strings.Cut(err.Error(), "\nHint: ")
+	if found {
+		fix = hint
+	}
+	return doctorCheck{name: name, detail: strings.ReplaceAll(detail, "\n", " "), fix: fix}
+}
+
+// runConfigDoctor validates the config file (as loaded by loadConfig, with
+// its error) step by step: the file, the base URL and whether it answers,
+// the token, and the default stream. It prints a checklist to out and
+// reports whether every check passed. Later checks are skipped once one
+// they depend on fails.
+func runConfigDoctor(out io.Writer, config *ClientConfig, loadErr error) bool {
+	var checks []doctorCheck
+	report := func() bool {
+		passed := true
+		for _, c := range checks {
+			mark := "✔"
+			if !c.ok {
+				mark = "✘"
+				passed = false
+			}
+			fmt.Fprintf(out, "%s %-15s %s\n", mark, c.name, c.detail)
+			if !c.ok && c.fix != "" {
+				fmt.Fprintf(out, "  %-15s Fix: %s\n", "", c.fix)
+			}
+		}
+		return passed
+	}
+
+	path, _ := getConfigPath()
+	switch {
+	case errors.Is(loadErr, fs.ErrNotExist):
+		checks = append(checks, failedCheck("Config file", fmt.Errorf("%s not found", path), "run tailstream-client --login"))
+		return report()
+	case loadErr != nil:
+		checks = append(checks, failedCheck("Config file", loadErr, "fix the YAML in "+path+", or remove it and log in again"))
+		return report()
+	default:
+		checks = append(checks, doctorCheck{name: "Config file", ok: true, detail: path})
+	}
+
+	baseURL := determineBaseURL("", config)
+	if err := validateBaseURL(baseURL); err != nil {
+		checks = append(checks, failedCheck("Base URL", err, "set base_url in "+path+" to e.g. "+defaultBaseURL))
+		return report()
+	}
+	checks = append(checks, doctorCheck{name: "Base URL", ok: true, detail: baseURL})
+
+	if err := checkReachable(baseURL); err != nil {
+		checks = append(checks, failedCheck("Server", err, ""))
+		return report()
+	}
+	checks = append(checks, doctorCheck{name: "Server", ok: true, detail: "reachable"})
+
+	token, err := determineToken("", config)
+	switch {
+	case err != nil:
+		checks = append(checks, failedCheck("Token", err, "check credential_helper / keychain in "+path))
+		return report()
+	case token == "":
+		checks = append(checks, failedCheck("Token", errors.New("no token configured"), "run tailstream-client --login"))
+		return report()
+	}
+	streams, err := fetchUserStreams(baseURL, token)
+	if err != nil {
+		checks = append(checks, failedCheck("Token", err, "run tailstream-client --login"))
+		return report()
+	}
+	checks = append(checks, doctorCheck{name: "Token", ok: true, detail: fmt.Sprintf("valid (%d streams)", len(streams))})
+
+	switch i := slices.IndexFunc(streams, func(s Stream) bool { return s.StreamID == config.DefaultStream }); {
+	case config.DefaultStream == "":
+		checks = append(checks, doctorCheck{name: "Default stream", ok: true, detail: "not set (you'll pick a stream when querying)"})
+	case i < 0:
+		checks = append(checks, failedCheck("Default stream", fmt.Errorf("%s no longer exists or isn't accessible", config.DefaultStream),
+			"change default_stream in "+path+", or remove it and pick a stream when prompted"))
+	default:
+		checks = append(checks, doctorCheck{name: "Default stream", ok: true, detail: fmt.Sprintf("%s (%s)", streams[i].Name, config.DefaultStream)})
+	}
+	return report()
+}
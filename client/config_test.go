@@ -1,9 +1,19 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -125,3 +135,367 @@ func TestLoadConfigNotExist(t *testing.T) {
 		t.Errorf("expected os.IsNotExist error, got: %v", err)
 	}
 }
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, configFileName)
+	if err := os.WriteFile(path, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeFileAtomic(path, []byte("access_token: new\n"), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	info, _ := os.Stat(path)
+	if string(data) != "access_token: new\n" || info.Mode().Perm() != 0600 {
+		t.Errorf("unexpected file after write: %q, mode %v", data, info.Mode().Perm())
+	}
+
+	// When the rename fails (here, a non-empty directory is in the way), the
+	// target stays as it was and no temporary file is left behind
+	blocked := filepath.Join(dir, "blocked")
+	if err := os.MkdirAll(filepath.Join(blocked, "child"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeFileAtomic(blocked, []byte("x"), 0600); err == nil {
+		t.Fatal("expected the write to fail")
+	}
+	entries, _ := os.ReadDir(dir)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if strings.Join(names, ",") != configFileName+",blocked" {
+		t.Errorf("expected no temporary files to remain, found %v", names)
+	}
+
+	// A symlinked config stays a link; the file it points to is replaced
+	link := filepath.Join(dir, "link.yaml")
+	if err := os.Symlink(path, link); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+	if err := writeFileAtomic(link, []byte("access_token: linked\n"), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("expected the symlink to remain, got %v (%v)", info, err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "access_token: linked\n" {
+		t.Errorf("expected the target to be written, got %q", data)
+	}
+}
+
+func TestDetermineOutputFormat(t *testing.T) {
+	config := &ClientConfig{OutputFormat: "ndjson"}
+	if got := determineOutputFormat("short", config); got != "short" {
+		t.Errorf("expected the flag to take precedence, got %q", got)
+	}
+	if got := determineOutputFormat("", config); got != "ndjson" {
+		t.Errorf("expected the configured format, got %q", got)
+	}
+	if got := determineOutputFormat("", &ClientConfig{}); got != "default" {
+		t.Errorf("expected the built-in default, got %q", got)
+	}
+	if got := determineOutputFormat("", nil); got != "default" {
+		t.Errorf("expected the built-in default without a config, got %q", got)
+	}
+}
+
+func TestDeterminePrefetchThreshold(t *testing.T) {
+	config := &ClientConfig{PrefetchAt: 40}
+	if got := determinePrefetchThreshold(15, config); got != 15 {
+		t.Errorf("expected the flag to take precedence, got %d", got)
+	}
+	if got := determinePrefetchThreshold(0, config); got != 40 {
+		t.Errorf("expected the configured threshold, got %d", got)
+	}
+	if got := determinePrefetchThreshold(0, nil); got != 0 {
+		t.Errorf("expected no threshold without a config, got %d", got)
+	}
+}
+
+func TestStreamDefaults(t *testing.T) {
+	var config ClientConfig
+	data := `
+stream_defaults:
+  access:
+    levels: [error]
+    filters: ["status>=500"]
+    fields: [status, path]
+    format: short
+`
+	if err := yaml.Unmarshal([]byte(data), &config); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	got := streamDefaults(&config, "access")
+	if got.Format != "short" || len(got.Levels) != 1 || got.Filters[0] != "status>=500" || len(got.Fields) != 2 {
+		t.Errorf("unexpected stream defaults: %+v", got)
+	}
+	if got := streamDefaults(&config, "other"); got.Format != "" || got.Levels != nil {
+		t.Errorf("expected no defaults for another stream, got %+v", got)
+	}
+	if got := streamDefaults(nil, "access"); got.Format != "" {
+		t.Errorf("expected no defaults without a config, got %+v", got)
+	}
+}
+
+func TestApplyStreamDefaults(t *testing.T) {
+	defaults := StreamSettings{Levels: []string{"error"}, Filters: []string{"status>=500"}}
+	status := fieldFilter{Field: "status", Operator: ">=", Value: "500"}
+	path := fieldFilter{Field: "path", Operator: "=", Value: "/"}
+
+	tests := []struct {
+		name        string
+		levels      []string
+		minLevel    string
+		filters     []fieldFilter
+		wantLevels  []string
+		wantMin     string
+		wantFilters []fieldFilter
+	}{
+		{"nothing given", nil, "", nil, []string{"error"}, "", []fieldFilter{status}},
+		{"levels given", []string{"info"}, "", nil, []string{"info"}, "", []fieldFilter{status}},
+		{"min level given", nil, "warn", nil, nil, "warn", []fieldFilter{status}},
+		{"filters given", nil, "", []fieldFilter{path}, []string{"error"}, "", []fieldFilter{path}},
+	}
+	for _, tt := range tests {
+		levels, minLevel, filters, err := applyStreamDefaults(tt.levels, tt.minLevel, tt.filters, defaults)
+		if err != nil || !reflect.DeepEqual(levels, tt.wantLevels) || minLevel != tt.wantMin || !reflect.DeepEqual(filters, tt.wantFilters) {
+			t.Errorf("%s: got %v %q %v (%v), want %v %q %v", tt.name, levels, minLevel, filters, err, tt.wantLevels, tt.wantMin, tt.wantFilters)
+		}
+	}
+
+	// A reloaded query file merges with the flags alone: once it sets levels,
+	// the defaults no longer apply, and they return when it drops them again
+	var flagLevels []string
+	if levels, _, _, _ := applyStreamDefaults(append(slices.Clone(flagLevels), "debug"), "", nil, defaults); !reflect.DeepEqual(levels, []string{"debug"}) {
+		t.Errorf("expected only the query file's levels, got %v", levels)
+	}
+	if levels, _, _, _ := applyStreamDefaults(slices.Clone(flagLevels), "", nil, defaults); !reflect.DeepEqual(levels, []string{"error"}) {
+		t.Errorf("expected the default levels back, got %v", levels)
+	}
+
+	if _, _, _, err := applyStreamDefaults(nil, "", nil, StreamSettings{Filters: []string{"status"}}); err == nil {
+		t.Error("expected an error for an invalid default filter")
+	}
+}
+
+func TestDetermineStartTime(t *testing.T) {
+	config := &ClientConfig{DefaultRange: "-1h"}
+
+	tests := []struct {
+		name           string
+		from           string
+		to             string
+		noDefaultRange bool
+		config         *ClientConfig
+		expected       string
+	}{
+		{"explicit from wins", "-30m", "", false, config, "-30m"},
+		{"default applied when no bounds", "", "", false, config, "-1h"},
+		{"default skipped when to is set", "", "now", false, config, ""},
+		{"default skipped when disabled", "", "", true, config, ""},
+		{"no default configured", "", "", false, &ClientConfig{}, ""},
+		{"nil config", "", "", false, nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := determineStartTime(tt.from, tt.to, tt.noDefaultRange, tt.config)
+			if result != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestDetermineOAuthClient(t *testing.T) {
+	custom := &ClientConfig{ClientID: "self-hosted", Scope: "stream:read stream:write"}
+
+	clientID, scope := determineOAuthClient("", "", nil)
+	if clientID != defaultClientID || scope != defaultScope {
+		t.Errorf("expected defaults, got %s / %s", clientID, scope)
+	}
+
+	clientID, scope = determineOAuthClient("", "", custom)
+	if clientID != "self-hosted" || scope != "stream:read stream:write" {
+		t.Errorf("expected config values, got %s / %s", clientID, scope)
+	}
+
+	clientID, scope = determineOAuthClient("flag-client", "admin", custom)
+	if clientID != "flag-client" || scope != "admin" {
+		t.Errorf("expected flag values, got %s / %s", clientID, scope)
+	}
+}
+
+func TestDetermineToken(t *testing.T) {
+	config := &ClientConfig{AccessToken: "stored", CredHelper: "echo '  from-helper  '"}
+
+	// Flag wins without running the helper
+	if got, err := determineToken("flag-token", config); err != nil || got != "flag-token" {
+		t.Errorf("expected flag token, got %q (%v)", got, err)
+	}
+
+	// The helper takes precedence over the stored token
+	if got, err := determineToken("", config); err != nil || got != "from-helper" {
+		t.Errorf("expected helper token, got %q (%v)", got, err)
+	}
+
+	// Without a helper the stored token is used
+	if got, _ := determineToken("", &ClientConfig{AccessToken: "stored"}); got != "stored" {
+		t.Errorf("expected stored token, got %q", got)
+	}
+	if got, _ := determineToken("", nil); got != "" {
+		t.Errorf("expected no token without config, got %q", got)
+	}
+
+	// Failing or silent helpers are errors
+	for _, helper := range []string{"exit 3", "true"} {
+		if _, err := determineToken("", &ClientConfig{CredHelper: helper}); err == nil {
+			t.Errorf("expected error for helper %q", helper)
+		}
+	}
+}
+
+func TestReadTokenFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "token")
+	if err := os.WriteFile(path, []byte("  secret-token\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := readTokenFile(path); err != nil || got != "secret-token" {
+		t.Errorf("expected the trimmed token, got %q (%v)", got, err)
+	}
+
+	empty := filepath.Join(dir, "empty")
+	if err := os.WriteFile(empty, []byte("\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readTokenFile(empty); err == nil {
+		t.Error("expected an error for an empty token file")
+	}
+	if _, err := readTokenFile(filepath.Join(dir, "missing")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected a not-exist error, got %v", err)
+	}
+}
+
+func TestLoadQueryFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "query.yaml")
+	content := `min_level: error
+methods: [POST]
+search: [timeout]
+filters:
+  - status>=500
+`
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	qf, err := loadQueryFile(path)
+	if err != nil {
+		t.Fatalf("loadQueryFile: %v", err)
+	}
+	if len(qf.Levels) == 0 || qf.Levels[0] != "ERROR" {
+		t.Errorf("min_level not expanded into levels: %v", qf.Levels)
+	}
+	if len(qf.Methods) != 1 || len(qf.Search) != 1 {
+		t.Errorf("unexpected methods/search: %v %v", qf.Methods, qf.Search)
+	}
+	if len(qf.fieldFilters) != 1 || qf.fieldFilters[0] != (fieldFilter{Field: "status", Operator: ">=", Value: "500"}) {
+		t.Errorf("unexpected parsed filters: %+v", qf.fieldFilters)
+	}
+
+	for name, bad := range map[string]string{
+		"yaml":      "levels: [unclosed",
+		"min_level": "min_level: LOUD",
+		"filter":    "filters: [\"no operator\"]",
+	} {
+		os.WriteFile(path, []byte(bad), 0600)
+		if _, err := loadQueryFile(path); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestFileWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "query.yaml")
+	os.WriteFile(path, []byte("levels: [ERROR]"), 0600)
+
+	w := newFileWatch(path)
+	if w.changed() {
+		t.Error("unchanged file reported as changed")
+	}
+
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(path, later, later)
+	if !w.changed() {
+		t.Error("modified file not reported")
+	}
+	if w.changed() {
+		t.Error("change reported twice")
+	}
+
+	// A missing file keeps the last state
+	os.Remove(path)
+	if w.changed() {
+		t.Error("removed file reported as changed")
+	}
+}
+
+func TestValidateBaseURL(t *testing.T) {
+	for raw, wantErr := range map[string]bool{
+		"https://app.tailstream.io":  false,
+		"http://localhost:8080/":     false,
+		"app.tailstream.io":          true, // No scheme
+		"ftp://app.tailstream.io":    true,
+		"https://":                   true,
+		"https://app.tail stream.io": true,
+	} {
+		if err := validateBaseURL(raw); (err != nil) != wantErr {
+			t.Errorf("validateBaseURL(%q) = %v, want error %v", raw, err, wantErr)
+		}
+	}
+}
+
+func TestRunConfigDoctor(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/user/streams" {
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer good" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"streams": []Stream{{Name: "Production", StreamID: "prod"}}})
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		config  *ClientConfig
+		loadErr error
+		pass    bool
+		want    string
+	}{
+		{"healthy", &ClientConfig{BaseURL: server.URL, AccessToken: "good", DefaultStream: "prod"}, nil, true, "✔ Default stream  Production (prod)"},
+		{"stale stream", &ClientConfig{BaseURL: server.URL, AccessToken: "good", DefaultStream: "gone"}, nil, false, "✘ Default stream  gone no longer exists"},
+		{"expired token", &ClientConfig{BaseURL: server.URL, AccessToken: "old"}, nil, false, "Fix: the token is missing or expired"},
+		{"no token", &ClientConfig{BaseURL: server.URL}, nil, false, "✘ Token           no token configured"},
+		{"bad base URL", &ClientConfig{BaseURL: "app.tailstream.io"}, nil, false, "✘ Base URL"},
+		{"no config", nil, fs.ErrNotExist, false, "Fix: run tailstream-client --login"},
+		{"broken config", nil, errors.New("yaml: line 2: mapping values are not allowed"), false, "✘ Config file     yaml: line 2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if got := runConfigDoctor(&out, tt.config, tt.loadErr); got != tt.pass {
+				t.Errorf("passed = %v, want %v\n%s", got, tt.pass, out.String())
+			}
+			if !strings.Contains(out.String(), tt.want) {
+				t.Errorf("expected %q in output:\n%s", tt.want, out.String())
+			}
+		})
+	}
+}
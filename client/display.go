@@ -4,6 +4,7 @@
 //
 // This file handles:
 // - Log entry formatting with color-coded log levels
+// - Named output presets and custom --template formats
 // - Text styling and ANSI color codes
 // - Query normalization and entry matching for search
 // - Loading spinners for async operations
@@ -15,48 +16,140 @@
 package main
 
 import (
+	"bytes"
+	"cmp"
+	"encoding/csv"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"maps"
 	"net/url"
 	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"syscall"
+	"text/template"
 	"time"
+	"unicode/utf8"
+
+	"tailstream/client/pkg/tailstream"
 )
 
-// formatEntry formats a log entry for display
-func formatEntry(entry map[string]any, withColor bool) string {
-	// Prioritize raw_message - this is the actual log line
-	rawMessage := firstString(entry, "raw_message", "message", "msg", "body", "description")
+// safeRender escapes control characters and invalid UTF-8 in rendered
+// output (--safe-render) so binary content can't corrupt the terminal
+var safeRender bool
+
+// renderSafe applies sanitizeText when safeRender is enabled
+func renderSafe(s string) string {
+	if !safeRender {
+		return s
+	}
+	return sanitizeText(s)
+}
+
+// inferLevel colors raw messages by a level found in their text when the
+// entry has no level field (--infer-level)
+var inferLevel bool
 
-	// Helper to get parsed field from 'fields' object or top-level
-	getField := func(name string) string {
-		// First check if there's a 'fields' object with parsed data
-		if fields, ok := entry["fields"].(map[string]any); ok {
-			if val, exists := fields[name]; exists {
-				return stringify(val)
+// levelPatterns find a log level embedded in unstructured text, most
+// explicit first: level=warn / "level":"warn", then [ERROR], then a bare
+// upper-case level word. Lower-case words alone are too common in prose.
+var levelPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)\b(?:level|lvl|severity)"?\s*[=:]\s*"?([a-z]+)`),
+	regexp.MustCompile(`\[([A-Za-z]+)\]`),
+	regexp.MustCompile(`\b(TRACE|DEBUG|INFO|WARN|WARNING|ERROR|ERR|CRITICAL|FATAL)\b`),
+}
+
+// inferLevelFromText returns the upper-case level named in text, or "" when
+// none of levelPatterns finds a known level
+func inferLevelFromText(text string) string {
+	for _, re := range levelPatterns {
+		for _, m := range re.FindAllStringSubmatch(text, -1) {
+			if level := strings.ToUpper(m[1]); colorForLevel(level) != colorForLevel("") {
+				return level
 			}
 		}
-		// Fallback to top-level (for backwards compatibility)
-		return firstString(entry, name, strings.ToLower(name))
 	}
+	return ""
+}
 
-	// If we have raw_message, just return it (it's already formatted)
+// sanitizeText replaces invalid UTF-8 with the replacement rune and escapes
+// control characters (\n, \r, \xNN, \uNNNN) so they are shown, not executed
+func sanitizeText(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+		switch {
+		case r == utf8.RuneError && size == 1:
+			b.WriteRune(utf8.RuneError)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteRune(r)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&b, `\x%02x`, r)
+		case r >= 0x80 && r <= 0x9f:
+			fmt.Fprintf(&b, `\u%04x`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// formatEntry formats a log entry for display
+func formatEntry(entry map[string]any, withColor bool) string {
+	e := tailstream.LogEntry(entry)
+
+	// HTTP status code, when the entry has one (colored by class). It is
+	// shown the same with or without color; only the styling differs.
+	status := e.FieldString("status")
+	if status == "" {
+		status = e.FieldString("status_code")
+	}
+	statusColor := ""
+	if code, err := strconv.Atoi(status); err == nil {
+		statusColor = colorForStatus(code)
+	}
+
+	// If we have raw_message, just return it (it's already formatted).
+	// Multi-line messages such as stack traces keep their line breaks and
+	// indentation.
 	if rawMsg, ok := entry["raw_message"].(string); ok && rawMsg != "" {
+		rawMsg = renderSafe(normalizeNewlines(rawMsg))
 		// Use level for styling if available (check fields object first)
-		level := strings.ToUpper(getField("level"))
+		level := e.Level()
+		if level == "" && inferLevel {
+			level = inferLevelFromText(rawMsg)
+		}
+		levelColor := ""
+		if level != "" {
+			levelColor = colorForLevel(level)
+		}
+		if statusColor != "" && withColor {
+			rawMsg = highlightToken(rawMsg, status, statusColor, levelColor)
+		}
 		if level != "" && withColor {
-			// Apply subtle color based on level
-			return style(rawMsg, colorForLevel(level), withColor)
+			// Apply subtle color based on level, per line so each line of a
+			// multi-line message is colored on its own (pagers, grep)
+			return styleLines(rawMsg, levelColor, withColor)
 		}
 		return rawMsg
 	}
 
 	// Fallback to structured format if no raw_message
-	timestamp := firstString(entry, "timestamp", "time", "created_at", "datetime", "logged_at")
-	level := strings.ToUpper(getField("level"))
-	message := rawMessage
+	timestamp := renderSafe(e.TimeText())
+	level := renderSafe(e.Level())
+	message := renderSafe(normalizeNewlines(e.Message()))
 
 	var builder strings.Builder
 	if timestamp != "" {
@@ -67,6 +160,10 @@ func formatEntry(entry map[string]any, withColor bool) string {
 		builder.WriteString(style(level, colorForLevel(level), withColor))
 		builder.WriteString(" ")
 	}
+	if statusColor != "" {
+		builder.WriteString(style(renderSafe(status), statusColor, withColor))
+		builder.WriteString(" ")
+	}
 	if message != "" {
 		builder.WriteString(message)
 	}
@@ -78,52 +175,370 @@ func formatEntry(entry map[string]any, withColor bool) string {
 	return builder.String()
 }
 
-// firstString returns the first non-empty string value from the entry for the given keys
-func firstString(entry map[string]any, keys ...string) string {
-	for _, k := range keys {
-		if v, ok := entry[k]; ok {
-			if s := stringify(v); s != "" {
-				return s
+// entryFormatter renders a single entry as one line of output
+type entryFormatter func(entry map[string]any) string
+
+// formatPresets maps --format names to built-in output templates
+var formatPresets = map[string]string{
+	"short": `{{time}} {{level}} {{message}}`,
+	"combined": `{{or (field "remote_addr" "client_ip" "ip") "-"}} - {{or (field "remote_user" "user") "-"}} [{{clftime}}] ` +
+		`"{{field "method"}} {{field "path" "request_uri" "url"}} {{or (field "protocol") "HTTP/1.1"}}" ` +
+		`{{or (field "status" "status_code") "-"}} {{or (field "bytes" "body_bytes_sent" "size") "-"}} ` +
+		`"{{or (field "referer" "referrer") "-"}}" "{{or (field "user_agent") "-"}}"`,
+}
+
+// newTimestampFormatter returns the --timestamps-only formatter: just the
+// entry's timestamp in UTC, as RFC3339 with sub-second precision, Unix
+// seconds (with milliseconds), Unix milliseconds, or a Go time layout.
+// Entries without a timestamp print "-" so line counts still match.
+func newTimestampFormatter(layout string) (entryFormatter, error) {
+	var render func(t time.Time) string
+	switch strings.ToLower(layout) {
+	case "", "rfc3339":
+		render = func(t time.Time) string { return t.UTC().Format(time.RFC3339Nano) }
+	case "unix":
+		render = func(t time.Time) string { return strconv.FormatFloat(float64(t.UnixMilli())/1000, 'f', 3, 64) }
+	case "unix-ms":
+		render = func(t time.Time) string { return strconv.FormatInt(t.UnixMilli(), 10) }
+	default:
+		// A layout must mention at least one time element to be one
+		if time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC).Format(layout) == layout {
+			return nil, fmt.Errorf("unknown timestamp format %q (use rfc3339, unix, unix-ms, or a Go layout like \"2006-01-02 15:04:05\")", layout)
+		}
+		render = func(t time.Time) string { return t.UTC().Format(layout) }
+	}
+	return func(entry map[string]any) string {
+		t, ok := tailstream.LogEntry(entry).Timestamp()
+		if !ok {
+			return "-"
+		}
+		return render(t)
+	}, nil
+}
+
+// truncatingFormatter wraps format so each output line is cut to width
+// (see truncateText)
+func truncatingFormatter(format entryFormatter, width int) entryFormatter {
+	return func(entry map[string]any) string {
+		return truncateText(format(entry), width)
+	}
+}
+
+// jsonArrayFormat is the --format that writes all entries as one JSON array
+const jsonArrayFormat = "json-array"
+
+// ndjsonFormat is the --format that writes each entry as one line of JSON
+const ndjsonFormat = "ndjson"
+
+// newEntryFormatter returns the formatter for --format/--template. A custom
+// template takes precedence over a preset; "default" (or empty) uses formatEntry.
+func newEntryFormatter(format, tmpl string, withColor bool) (entryFormatter, error) {
+	if tmpl == "" {
+		switch format {
+		case "", "default":
+			return func(entry map[string]any) string { return formatEntry(entry, withColor) }, nil
+		case jsonArrayFormat, ndjsonFormat:
+			// Each entry is one line, or one element that jsonArrayWriter
+			// wraps in brackets
+			return func(entry map[string]any) string {
+				b, _ := json.Marshal(entry)
+				return string(b)
+			}, nil
+		}
+		preset, ok := formatPresets[format]
+		if !ok {
+			names := make([]string, 0, len(formatPresets)+3)
+			names = append(names, "default", jsonArrayFormat, ndjsonFormat)
+			for name := range formatPresets {
+				names = append(names, name)
 			}
+			sort.Strings(names[1:])
+			return nil, fmt.Errorf("unknown format %q (available: %s)", format, strings.Join(names, ", "))
 		}
-		if v, ok := entry[strings.ToLower(k)]; ok {
-			if s := stringify(v); s != "" {
-				return s
+		tmpl = preset
+	}
+
+	// Functions are bound to the current entry so templates can call
+	// {{field "status"}} without threading the entry through
+	var current tailstream.LogEntry
+	funcs := template.FuncMap{
+		"field": func(names ...string) string {
+			for _, name := range names {
+				if v, ok := current.Field(name); ok {
+					if s := tailstream.Stringify(v); s != "" {
+						return s
+					}
+				}
 			}
+			return ""
+		},
+		"time": func() string {
+			return current.TimeText()
+		},
+		"clftime": func() string {
+			ts := current.TimeText()
+			if t, err := time.Parse(time.RFC3339, ts); err == nil {
+				return t.Format("02/Jan/2006:15:04:05 -0700")
+			}
+			return ts
+		},
+		"level": func() string {
+			return current.Level()
+		},
+		"message": func() string {
+			// Prefer the parsed message over the full raw line
+			return current.String("message", "msg", "body", "description", "raw_message")
+		},
+		"json": func(v any) string {
+			b, _ := json.Marshal(v)
+			return string(b)
+		},
+	}
+	parsed, err := template.New("format").Funcs(funcs).Parse(tmpl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+
+	return func(entry map[string]any) string {
+		current = entry
+		var b strings.Builder
+		if err := parsed.Execute(&b, entry); err != nil {
+			return fmt.Sprintf("template error: %v", err)
 		}
+		return renderSafe(b.String())
+	}, nil
+}
+
+// parseSortSpec parses a client-side sort specification like "duration_ms:desc".
+// The direction defaults to ascending when omitted.
+func parseSortSpec(spec string) (field string, desc bool, err error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return "", false, nil
+	}
+	field, dir, _ := strings.Cut(spec, ":")
+	field = strings.TrimSpace(field)
+	if field == "" {
+		return "", false, fmt.Errorf("invalid sort %q: missing field name", spec)
+	}
+	switch strings.ToLower(strings.TrimSpace(dir)) {
+	case "", "asc":
+		return field, false, nil
+	case "desc":
+		return field, true, nil
+	default:
+		return "", false, fmt.Errorf("invalid sort direction %q: use asc or desc", dir)
 	}
-	return ""
 }
 
-// stringify converts a value to a string representation
-func stringify(value any) string {
+// Display orders accepted by --display-order
+const (
+	oldestFirst = "oldest-first"
+	newestFirst = "newest-first"
+)
+
+// parseDisplayOrder turns a --display-order value into the client-side sort
+// that produces it, given the fetch direction (--sort). The field is "" when
+// entries already arrive in that order, so no reordering is needed.
+func parseDisplayOrder(order, fetchDir string) (field string, desc bool, err error) {
+	switch order {
+	case "":
+		return "", false, nil
+	case oldestFirst, newestFirst:
+	default:
+		return "", false, fmt.Errorf("invalid display order %q: use %s or %s", order, oldestFirst, newestFirst)
+	}
+	desc = order == newestFirst
+	if fetchDir = strings.ToLower(fetchDir); fetchDir == "desc" && desc || fetchDir == "asc" && !desc {
+		return "", desc, nil
+	}
+	return "timestamp", desc, nil
+}
+
+// sortEntries sorts entries in place by the given field. Values are compared
+// numerically when both parse as numbers, otherwise as strings; timestamp
+// fields are compared chronologically (see sortByTime). Entries missing the
+// field always sort last.
+func sortEntries(entries []map[string]any, field string, desc bool) {
+	order := sortOrder(entries, field, desc)
+	sorted := make([]map[string]any, len(entries))
+	for i, j := range order {
+		sorted[i] = entries[j]
+	}
+	copy(entries, sorted)
+}
+
+// sortOrder returns the indices of entries in the order sortEntries would
+// put them, leaving entries untouched. The sort is stable.
+func sortOrder(entries []map[string]any, field string, desc bool) []int {
+	order := make([]int, len(entries))
+	for i := range order {
+		order[i] = i
+	}
+	less := entryLess(field, desc)
+	sort.SliceStable(order, func(i, j int) bool {
+		return less(entries[order[i]], entries[order[j]])
+	})
+	return order
+}
+
+// entryLess returns the ordering of entries by field used by sortEntries
+func entryLess(field string, desc bool) func(x, y map[string]any) bool {
+	if tailstream.IsTimestampField(field) {
+		return func(x, y map[string]any) bool {
+			a, aOK := tailstream.LogEntry(x).Timestamp()
+			b, bOK := tailstream.LogEntry(y).Timestamp()
+			if !aOK || !bOK {
+				return aOK && !bOK
+			}
+			if desc {
+				return a.After(b)
+			}
+			return a.Before(b)
+		}
+	}
+	return func(x, y map[string]any) bool {
+		a, aOK := tailstream.LogEntry(x).Field(field)
+		b, bOK := tailstream.LogEntry(y).Field(field)
+		if !aOK || !bOK {
+			return aOK && !bOK
+		}
+		cmp := compareValues(a, b)
+		if desc {
+			return cmp > 0
+		}
+		return cmp < 0
+	}
+}
+
+// sortByTime sorts entries chronologically in place. Entries without a
+// timestamp sort last in either direction, keeping their relative order.
+func sortByTime(entries []map[string]any, desc bool) {
+	sortEntries(entries, "timestamp", desc)
+}
+
+// compareValues compares two field values, numerically when possible.
+// Integers are compared exactly, since 64-bit IDs don't fit in a float64.
+func compareValues(a, b any) int {
+	as, bs := tailstream.Stringify(a), tailstream.Stringify(b)
+	if ai, aErr := strconv.ParseInt(as, 10, 64); aErr == nil {
+		if bi, bErr := strconv.ParseInt(bs, 10, 64); bErr == nil {
+			return cmp.Compare(ai, bi)
+		}
+	}
+	af, aErr := strconv.ParseFloat(as, 64)
+	bf, bErr := strconv.ParseFloat(bs, 64)
+	if aErr == nil && bErr == nil {
+		switch {
+		case af < bf:
+			return -1
+		case af > bf:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(as, bs)
+}
+
+// redactedValue replaces sensitive values in output
+const redactedValue = "***"
+
+// redactor masks sensitive data in entries before display or export.
+// Field names match keys at any depth (case-insensitive); patterns are
+// matched against every string value, including raw_message.
+type redactor struct {
+	fields   map[string]bool
+	patterns []*regexp.Regexp
+}
+
+// newRedactor builds a redactor from field names (each may be comma-separated)
+// and regex patterns. Returns nil when there is nothing to redact.
+func newRedactor(fields []string, patterns []string) (*redactor, error) {
+	r := &redactor{fields: make(map[string]bool)}
+	for _, value := range fields {
+		for _, field := range strings.Split(value, ",") {
+			if field = strings.TrimSpace(field); field != "" {
+				r.fields[strings.ToLower(field)] = true
+			}
+		}
+	}
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redact pattern %q: %w", pattern, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	if len(r.fields) == 0 && len(r.patterns) == 0 {
+		return nil, nil
+	}
+	return r, nil
+}
+
+// apply returns a redacted copy of the entry. A nil redactor returns the entry unchanged.
+func (r *redactor) apply(entry map[string]any) map[string]any {
+	if r == nil {
+		return entry
+	}
+	return r.redactValue(entry).(map[string]any)
+}
+
+// applyAll redacts every entry in place and returns the slice
+func (r *redactor) applyAll(entries []map[string]any) []map[string]any {
+	if r == nil {
+		return entries
+	}
+	for i, entry := range entries {
+		entries[i] = r.apply(entry)
+	}
+	return entries
+}
+
+// redactValue walks a decoded JSON value, masking named fields and pattern matches
+func (r *redactor) redactValue(value any) any {
 	switch v := value.(type) {
-	case nil:
-		return ""
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for key, val := range v {
+			if r.fields[strings.ToLower(key)] {
+				out[key] = redactedValue
+			} else {
+				out[key] = r.redactValue(val)
+			}
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, val := range v {
+			out[i] = r.redactValue(val)
+		}
+		return out
 	case string:
+		for _, re := range r.patterns {
+			v = re.ReplaceAllString(v, redactedValue)
+		}
 		return v
-	case fmt.Stringer:
-		return v.String()
-	case time.Time:
-		return v.Format(time.RFC3339)
-	case float64:
-		if v == float64(int64(v)) {
-			return fmt.Sprintf("%d", int64(v))
-		}
-		return fmt.Sprintf("%f", v)
-	case json.Number:
-		return v.String()
-	case bool:
-		if v {
-			return "true"
-		}
-		return "false"
 	default:
-		if b, err := json.Marshal(v); err == nil {
-			return string(b)
-		}
+		return value
 	}
-	return ""
+}
+
+// redactResponseBody redacts the entries in a raw API response body,
+// leaving the rest of the envelope intact
+func (r *redactor) redactResponseBody(body []byte) ([]byte, error) {
+	if r == nil {
+		return body, nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber() // Keep numbers exactly as sent
+	var envelope map[string]any
+	if err := decoder.Decode(&envelope); err != nil {
+		return nil, err
+	}
+	if data, ok := envelope["data"]; ok {
+		envelope["data"] = r.redactValue(data)
+	}
+	return json.Marshal(envelope)
 }
 
 // colorForLevel returns the ANSI color code for a log level
@@ -144,6 +559,75 @@ func colorForLevel(level string) string {
 	}
 }
 
+// colorForStatus returns the ANSI color code for an HTTP status code's class,
+// or "" for codes outside 2xx-5xx
+func colorForStatus(code int) string {
+	switch code / 100 {
+	case 2:
+		return "32"
+	case 3:
+		return "36"
+	case 4:
+		return "33"
+	case 5:
+		return "31"
+	default:
+		return ""
+	}
+}
+
+// highlightToken colors the first whitespace-delimited occurrence of token in
+// line, then switches back to baseColor (the line's own color, if any)
+func highlightToken(line, token, color, baseColor string) string {
+	for start := 0; start < len(line); {
+		i := strings.Index(line[start:], token)
+		if i < 0 {
+			break
+		}
+		i += start
+		end := i + len(token)
+		if (i == 0 || line[i-1] == ' ') && (end == len(line) || line[end] == ' ') {
+			resume := ""
+			if baseColor != "" {
+				resume = "\x1b[" + baseColor + "m"
+			}
+			return line[:i] + style(token, color, true) + resume + line[end:]
+		}
+		start = end
+	}
+	return line
+}
+
+// levelSeverity lists log levels from least to most severe. Each rank
+// includes common aliases so severity filters match them too.
+var levelSeverity = [][]string{
+	{"TRACE"},
+	{"DEBUG"},
+	{"INFO"},
+	{"WARN", "WARNING"},
+	{"ERROR", "ERR"},
+	{"FATAL", "CRITICAL"},
+}
+
+// levelsAtOrAbove returns every known level at or above the given severity.
+// Returns false if the level is not in the severity table.
+func levelsAtOrAbove(minLevel string) ([]string, bool) {
+	minLevel = strings.ToUpper(strings.TrimSpace(minLevel))
+	for rank, names := range levelSeverity {
+		for _, name := range names {
+			if name != minLevel {
+				continue
+			}
+			var levels []string
+			for _, higher := range levelSeverity[rank:] {
+				levels = append(levels, higher...)
+			}
+			return levels, true
+		}
+	}
+	return nil, false
+}
+
 // style applies ANSI color codes to text
 func style(text, color string, enabled bool) string {
 	if !enabled || color == "" {
@@ -152,37 +636,318 @@ func style(text, color string, enabled bool) string {
 	return "\x1b[" + color + "m" + text + "\x1b[0m"
 }
 
-// startSpinner starts a visual spinner with a message
-func startSpinner(message string) func() {
+// truncateText cuts each line of text to at most width visible runes, ending
+// cut lines with an ellipsis. ANSI escape sequences pass through without
+// counting, and a reset follows a cut so its color doesn't leak.
+func truncateText(text string, width int) string {
+	if width <= 0 {
+		return text
+	}
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = truncateLineWidth(line, width)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// truncateLineWidth is truncateText for a single line
+func truncateLineWidth(line string, width int) string {
+	if utf8.RuneCountInString(stripANSI(line)) <= width {
+		return line
+	}
+	var b strings.Builder
+	visible, styled := 0, false
+	for i := 0; i < len(line); {
+		// Copy escape sequences through without counting them
+		if line[i] == 0x1b {
+			if loc := ansiPattern.FindStringIndex(line[i:]); loc != nil && loc[0] == 0 {
+				b.WriteString(line[i : i+loc[1]])
+				styled = true
+				i += loc[1]
+				continue
+			}
+		}
+		if visible >= width-1 {
+			break
+		}
+		_, size := utf8.DecodeRuneInString(line[i:])
+		b.WriteString(line[i : i+size])
+		visible++
+		i += size
+	}
+	b.WriteString("…")
+	if styled {
+		b.WriteString("\x1b[0m")
+	}
+	return b.String()
+}
+
+// stripANSI removes ANSI escape sequences from s
+func stripANSI(s string) string {
+	return ansiPattern.ReplaceAllString(s, "")
+}
+
+// ansiPattern matches ANSI CSI escape sequences such as colors
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-?]*[ -/]*[@-~]`)
+
+// styleLines applies style to each line of text separately, so no escape
+// sequence spans a line break
+func styleLines(text, color string, enabled bool) string {
+	if !enabled || color == "" || !strings.Contains(text, "\n") {
+		return style(text, color, enabled)
+	}
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = style(line, color, enabled)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// normalizeNewlines turns CRLF line endings into LF and drops trailing line
+// breaks, so a multi-line message renders as clean lines with no blank tail
+func normalizeNewlines(s string) string {
+	if !strings.ContainsAny(s, "\r\n") {
+		return s
+	}
+	return strings.TrimRight(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+}
+
+// startSpinner starts a visual spinner with a message on stderr. The returned
+// stop function ends it with ✔ (ok) or ✘ and only returns once the final line
+// is written, so it's safe to call right before fatal (which skips deferred
+// calls). Calls after the first are no-ops, so a deferred stop can back up an
+// explicit one.
+func startSpinner(message string) func(ok bool) {
+	return spinTo(os.Stderr, message)
+}
+
+// spinTo runs the spinner on w
+func spinTo(w io.Writer, message string) func(ok bool) {
 	frames := []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
-	stop := make(chan struct{})
-	stopped := false
-	var mu sync.Mutex
+	stop := make(chan bool)
+	done := make(chan struct{})
+	var once sync.Once
 
 	go func() {
+		defer close(done)
 		ticker := time.NewTicker(90 * time.Millisecond)
 		defer ticker.Stop()
 		i := 0
 		for {
 			select {
-			case <-stop:
-				fmt.Fprintf(os.Stderr, "\r%s ✔\n", message)
+			case ok := <-stop:
+				mark := "✔"
+				if !ok {
+					mark = "✘"
+				}
+				fmt.Fprintf(w, "\r%s %s\n", message, mark)
 				return
 			case <-ticker.C:
-				fmt.Fprintf(os.Stderr, "\r%s %s", message, frames[i%len(frames)])
+				fmt.Fprintf(w, "\r%s %s", message, frames[i%len(frames)])
 				i++
 			}
 		}
 	}()
 
-	return func() {
-		mu.Lock()
-		defer mu.Unlock()
-		if !stopped {
-			stopped = true
-			close(stop)
+	return func(ok bool) {
+		once.Do(func() {
+			stop <- ok
+			<-done
+		})
+	}
+}
+
+// entriesSize returns the total size in bytes of the entries encoded as JSON
+func entriesSize(entries []map[string]any) int {
+	total := 0
+	for _, entry := range entries {
+		if b, err := json.Marshal(entry); err == nil {
+			total += len(b)
 		}
 	}
+	return total
+}
+
+// exportFormats are the formats the interactive s key can save entries in
+var exportFormats = []string{"text", "json", "ndjson", "csv"}
+
+// exportFormatFor guesses the export format from a file name's extension,
+// defaulting to text
+func exportFormatFor(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return "json"
+	case ".ndjson", ".jsonl":
+		return "ndjson"
+	case ".csv":
+		return "csv"
+	}
+	return "text"
+}
+
+// writeEntries exports entries in one of exportFormats: formatted log lines
+// (without color), an indented JSON array, one JSON object per line, or CSV
+// with a column for every top-level field (nested values as JSON)
+func writeEntries(w io.Writer, entries []map[string]any, format string) error {
+	switch format {
+	case "text":
+		for _, entry := range entries {
+			if _, err := fmt.Fprintln(w, formatEntry(entry, false)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "json":
+		if entries == nil {
+			entries = []map[string]any{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	case "ndjson":
+		enc := json.NewEncoder(w)
+		for _, entry := range entries {
+			if err := enc.Encode(entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "csv":
+		columns := make(map[string]bool)
+		for _, entry := range entries {
+			for k := range entry {
+				columns[k] = true
+			}
+		}
+		header := slices.Sorted(maps.Keys(columns))
+		cw := csv.NewWriter(w)
+		cw.Write(header)
+		row := make([]string, len(header))
+		for _, entry := range entries {
+			for i, k := range header {
+				switch v := entry[k].(type) {
+				case map[string]any, []any:
+					b, _ := json.Marshal(v)
+					row[i] = string(b)
+				default:
+					row[i] = tailstream.Stringify(v)
+				}
+			}
+			cw.Write(row)
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+	return fmt.Errorf("unknown format %q (available: %s)", format, strings.Join(exportFormats, ", "))
+}
+
+// formatBytes renders a byte count in human-readable units (B, KB, MB, GB)
+func formatBytes(n int) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	value := float64(n)
+	for _, suffix := range []string{"KB", "MB", "GB"} {
+		value /= unit
+		if value < unit || suffix == "GB" {
+			return fmt.Sprintf("%.1f %s", value, suffix)
+		}
+	}
+	return fmt.Sprintf("%d B", n)
+}
+
+// withFields adds the named fields of an entry to its formatted line as
+// "name=value" pairs, for the fields picked with the v key. They go at the
+// end of the first line, so they stay visible when a multi-line message is
+// collapsed. Fields the entry lacks are left out.
+func withFields(line string, entry map[string]any, names []string, withColor bool) string {
+	var pairs strings.Builder
+	for _, name := range names {
+		v, ok := tailstream.LogEntry(entry).Field(name)
+		if !ok {
+			continue
+		}
+		value, isText := v.(string)
+		if !isText {
+			raw, _ := json.Marshal(v)
+			value = string(raw)
+		}
+		pairs.WriteString(" " + style(renderSafe(name)+"=", "90", withColor) + renderSafe(value))
+	}
+	first, rest, multiline := strings.Cut(line, "\n")
+	if multiline {
+		return first + pairs.String() + "\n" + rest
+	}
+	return first + pairs.String()
+}
+
+// formatFetchSummary describes how many entries were fetched, across how many
+// pages, and how long it took
+func formatFetchSummary(entries, pages int, elapsed time.Duration) string {
+	entryWord, pageWord := "entries", "pages"
+	if entries == 1 {
+		entryWord = "entry"
+	}
+	if pages == 1 {
+		pageWord = "page"
+	}
+	return fmt.Sprintf("fetched %d %s across %d %s in %s", entries, entryWord, pages, pageWord, elapsed.Round(100*time.Millisecond))
+}
+
+// formatFailedPages describes the page fetches a best-effort run gave up on
+func formatFailedPages(failed int) string {
+	if failed == 1 {
+		return "1 page failed to fetch"
+	}
+	return fmt.Sprintf("%d pages failed to fetch", failed)
+}
+
+// writeOutput writes p to w immediately. When the reader has gone away (e.g.
+// piping into `head`), it exits quietly with status 0 so downstream tools can
+// stop early without the client hanging or reporting an error.
+func writeOutput(w io.Writer, p []byte) {
+	if _, err := w.Write(p); err != nil {
+		if isBrokenPipe(err) {
+			os.Exit(0)
+		}
+		fatal(err)
+	}
+}
+
+// jsonArrayWriter streams formatted JSON entries as the elements of a single
+// array, so the whole output parses as one document without being held in
+// memory. A nil writer is a no-op.
+type jsonArrayWriter struct {
+	w     io.Writer
+	count int
+}
+
+// write adds one JSON-encoded element to the array, opening it on first use
+func (a *jsonArrayWriter) write(element string) {
+	sep := ",\n"
+	if a.count == 0 {
+		sep = "[\n"
+	}
+	writeOutput(a.w, []byte(sep+element))
+	a.count++
+}
+
+// close ends the array; with no elements it writes an empty array
+func (a *jsonArrayWriter) close() {
+	if a == nil {
+		return
+	}
+	if a.count == 0 {
+		writeOutput(a.w, []byte("[]\n"))
+		return
+	}
+	writeOutput(a.w, []byte("\n]\n"))
+}
+
+// isBrokenPipe reports whether err came from writing to a closed pipe
+func isBrokenPipe(err error) bool {
+	return errors.Is(err, syscall.EPIPE)
 }
 
 // fatal prints an error message and exits
@@ -1,8 +1,17 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
 	"strings"
+	"syscall"
 	"testing"
+	"time"
+
+	"tailstream/client/pkg/tailstream"
 )
 
 func TestFormatEntry(t *testing.T) {
@@ -51,63 +60,6 @@ func TestFormatEntry(t *testing.T) {
 	}
 }
 
-func TestFirstString(t *testing.T) {
-	entry := map[string]any{
-		"message": "test message",
-		"level":   "ERROR",
-		"empty":   "",
-	}
-
-	// Should find message
-	result := firstString(entry, "message")
-	if result != "test message" {
-		t.Errorf("expected 'test message', got '%s'", result)
-	}
-
-	// Should find level
-	result = firstString(entry, "level")
-	if result != "ERROR" {
-		t.Errorf("expected 'ERROR', got '%s'", result)
-	}
-
-	// Should skip empty and find next
-	result = firstString(entry, "empty", "message")
-	if result != "test message" {
-		t.Errorf("expected 'test message', got '%s'", result)
-	}
-
-	// Should return empty for non-existent keys
-	result = firstString(entry, "nonexistent")
-	if result != "" {
-		t.Errorf("expected empty string, got '%s'", result)
-	}
-}
-
-func TestStringify(t *testing.T) {
-	tests := []struct {
-		name     string
-		input    any
-		expected string
-	}{
-		{"nil", nil, ""},
-		{"string", "test", "test"},
-		{"int-like float", 42.0, "42"},
-		{"float", 42.5, "42.500000"},
-		{"bool true", true, "true"},
-		{"bool false", false, "false"},
-		{"empty string", "", ""},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := stringify(tt.input)
-			if result != tt.expected {
-				t.Errorf("expected '%s', got '%s'", tt.expected, result)
-			}
-		})
-	}
-}
-
 func TestColorForLevel(t *testing.T) {
 	tests := []struct {
 		level    string
@@ -154,3 +106,661 @@ func TestStyle(t *testing.T) {
 	}
 }
 
+func TestEntriesSize(t *testing.T) {
+	entries := []map[string]any{
+		{"a": 1},    // {"a":1} = 7 bytes
+		{"bb": "x"}, // {"bb":"x"} = 10 bytes
+	}
+	if got := entriesSize(entries); got != 17 {
+		t.Errorf("expected 17 bytes, got %d", got)
+	}
+	if got := entriesSize(nil); got != 0 {
+		t.Errorf("expected 0 bytes for nil, got %d", got)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		input    int
+		expected string
+	}{
+		{0, "0 B"},
+		{512, "512 B"},
+		{1024, "1.0 KB"},
+		{1536, "1.5 KB"},
+		{5 * 1024 * 1024, "5.0 MB"},
+		{3 * 1024 * 1024 * 1024, "3.0 GB"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			if got := formatBytes(tt.input); got != tt.expected {
+				t.Errorf("expected '%s', got '%s'", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestLevelsAtOrAbove(t *testing.T) {
+	levels, ok := levelsAtOrAbove("warn")
+	if !ok {
+		t.Fatal("expected WARN to be a known level")
+	}
+	expected := []string{"WARN", "WARNING", "ERROR", "ERR", "FATAL", "CRITICAL"}
+	if strings.Join(levels, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected %v, got %v", expected, levels)
+	}
+
+	// Aliases resolve to their rank
+	levels, ok = levelsAtOrAbove("CRITICAL")
+	if !ok || strings.Join(levels, ",") != "FATAL,CRITICAL" {
+		t.Errorf("unexpected levels for CRITICAL: %v", levels)
+	}
+
+	// Custom level names are unknown
+	if _, ok := levelsAtOrAbove("NOTICE"); ok {
+		t.Error("expected NOTICE to be unknown")
+	}
+}
+
+func TestParseSortSpec(t *testing.T) {
+	tests := []struct {
+		spec      string
+		field     string
+		desc      bool
+		expectErr bool
+	}{
+		{"", "", false, false},
+		{"duration_ms", "duration_ms", false, false},
+		{"duration_ms:asc", "duration_ms", false, false},
+		{"duration_ms:DESC", "duration_ms", true, false},
+		{":desc", "", false, true},
+		{"duration_ms:sideways", "", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			field, desc, err := parseSortSpec(tt.spec)
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("unexpected error state: %v", err)
+			}
+			if field != tt.field || desc != tt.desc {
+				t.Errorf("expected (%q, %v), got (%q, %v)", tt.field, tt.desc, field, desc)
+			}
+		})
+	}
+}
+
+func TestParseDisplayOrder(t *testing.T) {
+	tests := []struct {
+		order, fetchDir string
+		field           string
+		desc            bool
+		expectErr       bool
+	}{
+		{"", "desc", "", false, false},
+		{"oldest-first", "desc", "timestamp", false, false},
+		{"oldest-first", "asc", "", false, false}, // Already fetched in order
+		{"newest-first", "asc", "timestamp", true, false},
+		{"newest-first", "DESC", "", true, false},
+		{"newest-first", "", "timestamp", true, false}, // Server default is unknown
+		{"upside-down", "desc", "", false, true},
+	}
+
+	for _, tt := range tests {
+		field, desc, err := parseDisplayOrder(tt.order, tt.fetchDir)
+		if (err != nil) != tt.expectErr {
+			t.Fatalf("parseDisplayOrder(%q, %q): unexpected error state: %v", tt.order, tt.fetchDir, err)
+		}
+		if err == nil && (field != tt.field || desc != tt.desc) {
+			t.Errorf("parseDisplayOrder(%q, %q) = (%q, %v), want (%q, %v)", tt.order, tt.fetchDir, field, desc, tt.field, tt.desc)
+		}
+	}
+}
+
+func TestSortEntries(t *testing.T) {
+	entries := []map[string]any{
+		{"id": "a", "fields": map[string]any{"duration_ms": 9.0}},
+		{"id": "b", "fields": map[string]any{"duration_ms": 120.0}},
+		{"id": "c"}, // missing field sorts last
+		{"id": "d", "duration_ms": "15"},
+	}
+
+	ids := func() string {
+		var out []string
+		for _, e := range entries {
+			out = append(out, e["id"].(string))
+		}
+		return strings.Join(out, ",")
+	}
+
+	// Numeric comparison (120 > 15 > 9, not string order)
+	sortEntries(entries, "duration_ms", true)
+	if got := ids(); got != "b,d,a,c" {
+		t.Errorf("unexpected desc order: %s", got)
+	}
+
+	sortEntries(entries, "duration_ms", false)
+	if got := ids(); got != "a,d,b,c" {
+		t.Errorf("unexpected asc order: %s", got)
+	}
+
+	// String comparison
+	sortEntries(entries, "id", true)
+	if got := ids(); got != "d,c,b,a" {
+		t.Errorf("unexpected string order: %s", got)
+	}
+}
+
+func TestSortOrder(t *testing.T) {
+	entries := []map[string]any{
+		{"status": 500.0},
+		{"status": 200.0},
+		{}, // missing field sorts last
+		{"status": 404.0},
+	}
+	if got := fmt.Sprint(sortOrder(entries, "status", false)); got != "[1 3 0 2]" {
+		t.Errorf("unexpected asc order: %s", got)
+	}
+	if got := fmt.Sprint(sortOrder(entries, "status", true)); got != "[0 3 1 2]" {
+		t.Errorf("unexpected desc order: %s", got)
+	}
+	if entries[0]["status"] != 500.0 {
+		t.Error("sortOrder should leave entries untouched")
+	}
+}
+
+func TestRedactor(t *testing.T) {
+	r, err := newRedactor([]string{"token, Password"}, []string{`Bearer \S+`})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	entry := map[string]any{
+		"raw_message": "GET /api with Authorization: Bearer abc123",
+		"token":       "secret",
+		"fields": map[string]any{
+			"password": "hunter2",
+			"user":     "alice",
+		},
+	}
+	redacted := r.apply(entry)
+
+	if redacted["token"] != redactedValue {
+		t.Errorf("expected top-level token redacted, got %v", redacted["token"])
+	}
+	fields := redacted["fields"].(map[string]any)
+	if fields["password"] != redactedValue {
+		t.Errorf("expected nested password redacted, got %v", fields["password"])
+	}
+	if fields["user"] != "alice" {
+		t.Errorf("expected user untouched, got %v", fields["user"])
+	}
+	if redacted["raw_message"] != "GET /api with Authorization: ***" {
+		t.Errorf("unexpected raw_message: %v", redacted["raw_message"])
+	}
+	// Original entry is not modified
+	if entry["token"] != "secret" {
+		t.Error("apply should not modify the original entry")
+	}
+}
+
+func TestRedactorDisabled(t *testing.T) {
+	r, err := newRedactor(nil, nil)
+	if err != nil || r != nil {
+		t.Fatalf("expected nil redactor, got %v, %v", r, err)
+	}
+	entry := map[string]any{"token": "secret"}
+	if r.apply(entry)["token"] != "secret" {
+		t.Error("nil redactor should leave entries unchanged")
+	}
+
+	if _, err := newRedactor(nil, []string{"("}); err == nil {
+		t.Error("expected error for invalid pattern")
+	}
+}
+
+func TestRedactResponseBody(t *testing.T) {
+	r, _ := newRedactor([]string{"token"}, nil)
+	body := []byte(`{"data":[{"id":12345678901234567890,"token":"x"}],"meta":{"has_more":false}}`)
+	out, err := r.redactResponseBody(body)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(string(out), `"token":"***"`) {
+		t.Errorf("expected token redacted: %s", out)
+	}
+	if !strings.Contains(string(out), `12345678901234567890`) {
+		t.Errorf("expected large id preserved: %s", out)
+	}
+	if !strings.Contains(string(out), `"has_more":false`) {
+		t.Errorf("expected meta preserved: %s", out)
+	}
+}
+
+func TestFormatFetchSummary(t *testing.T) {
+	got := formatFetchSummary(342, 2, 1420*time.Millisecond)
+	if got != "fetched 342 entries across 2 pages in 1.4s" {
+		t.Errorf("unexpected summary: %s", got)
+	}
+	got = formatFetchSummary(1, 1, 250*time.Millisecond)
+	if got != "fetched 1 entry across 1 page in 300ms" {
+		t.Errorf("unexpected summary: %s", got)
+	}
+}
+
+func TestIsBrokenPipe(t *testing.T) {
+	wrapped := &os.PathError{Op: "write", Path: "/dev/stdout", Err: syscall.EPIPE}
+	if !isBrokenPipe(wrapped) {
+		t.Error("expected wrapped EPIPE to be a broken pipe")
+	}
+	if isBrokenPipe(errors.New("disk full")) {
+		t.Error("expected unrelated error not to be a broken pipe")
+	}
+}
+
+func TestNewEntryFormatter(t *testing.T) {
+	entry := map[string]any{
+		"timestamp": "2024-01-02T15:04:05Z",
+		"message":   "GET /health",
+		"fields": map[string]any{
+			"level":       "info",
+			"remote_addr": "10.0.0.1",
+			"method":      "GET",
+			"path":        "/health",
+			"status":      float64(200),
+			"bytes":       float64(512),
+		},
+	}
+
+	tests := []struct {
+		format, tmpl string
+		want         string
+	}{
+		{"default", "", formatEntry(entry, false)},
+		{"short", "", "2024-01-02T15:04:05Z INFO GET /health"},
+		{"combined", "", `10.0.0.1 - - [02/Jan/2024:15:04:05 +0000] "GET /health HTTP/1.1" 200 512 "-" "-"`},
+		{"combined", `{{field "method"}} {{or (field "missing") "n/a"}}`, "GET n/a"},
+		{"ndjson", "", `{"fields":{"bytes":512,"level":"info","method":"GET","path":"/health","remote_addr":"10.0.0.1","status":200},"message":"GET /health","timestamp":"2024-01-02T15:04:05Z"}`},
+	}
+	for _, tt := range tests {
+		format, err := newEntryFormatter(tt.format, tt.tmpl, false)
+		if err != nil {
+			t.Fatalf("newEntryFormatter(%q, %q) failed: %v", tt.format, tt.tmpl, err)
+		}
+		if got := format(entry); got != tt.want {
+			t.Errorf("newEntryFormatter(%q, %q):\n got: %s\nwant: %s", tt.format, tt.tmpl, got, tt.want)
+		}
+	}
+
+	if _, err := newEntryFormatter("nope", "", false); err == nil || !strings.Contains(err.Error(), "combined") {
+		t.Errorf("expected unknown format error listing presets, got %v", err)
+	}
+	if _, err := newEntryFormatter("", "{{field", false); err == nil {
+		t.Error("expected invalid template to fail")
+	}
+}
+
+func TestSanitizeText(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"plain text", "plain text"},
+		{"tab\tkept", "tab\tkept"},
+		{"line1\nline2\r", `line1\nline2\r`},
+		{"\x1b[2Jclear", `\x1b[2Jclear`},
+		{"bell\x07 del\x7f", `bell\x07 del\x7f`},
+		{"c1\u009bcsi", `c1\u009bcsi`},
+		{"bad\xffbyte", "bad�byte"},
+		{"héllo ✓", "héllo ✓"},
+	}
+	for _, tt := range tests {
+		if got := sanitizeText(tt.input); got != tt.expected {
+			t.Errorf("sanitizeText(%q) = %q, want %q", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestFormatEntrySafeRender(t *testing.T) {
+	entry := map[string]any{"raw_message": "evil \x1b]0;title\x07 line"}
+
+	if got := formatEntry(entry, false); got != entry["raw_message"] {
+		t.Errorf("expected raw output without safe render, got %q", got)
+	}
+
+	safeRender = true
+	defer func() { safeRender = false }()
+	if got := formatEntry(entry, false); got != `evil \x1b]0;title\x07 line` {
+		t.Errorf("unexpected safe output: %q", got)
+	}
+}
+
+func TestSortByTime(t *testing.T) {
+	entries := []map[string]any{
+		{"id": "none"}, // Missing timestamps sort last
+		{"id": "b", "timestamp": "2024-01-01T12:00:00+02:00"}, // 10:00Z
+		{"id": "a", "timestamp_ms": float64(time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC).UnixMilli())},
+		{"id": "c", "timestamp": "2024-01-01T11:00:00Z"},
+	}
+	ids := func() string {
+		var out []string
+		for _, e := range entries {
+			out = append(out, e["id"].(string))
+		}
+		return strings.Join(out, ",")
+	}
+
+	sortByTime(entries, false)
+	if got := ids(); got != "a,b,c,none" {
+		t.Errorf("asc order = %s, want a,b,c,none", got)
+	}
+	// --sort-by timestamp compares chronologically, not as strings
+	sortEntries(entries, "timestamp", true)
+	if got := ids(); got != "c,b,a,none" {
+		t.Errorf("desc order = %s, want c,b,a,none", got)
+	}
+}
+
+func TestColorForStatus(t *testing.T) {
+	for code, want := range map[int]string{200: "32", 204: "32", 301: "36", 404: "33", 503: "31", 102: "", 0: ""} {
+		if got := colorForStatus(code); got != want {
+			t.Errorf("colorForStatus(%d) = %q, want %q", code, got, want)
+		}
+	}
+}
+
+func TestFormatEntryStatusColor(t *testing.T) {
+	entry := map[string]any{
+		"raw_message": "GET /api/v2000 500 12ms",
+		"fields":      map[string]any{"level": "INFO", "status": float64(500)},
+	}
+	// Only the standalone status token is colored, then the level color resumes
+	want := "\x1b[36mGET /api/v2000 \x1b[31m500\x1b[0m\x1b[36m 12ms\x1b[0m"
+	if got := formatEntry(entry, true); got != want {
+		t.Errorf("formatEntry = %q, want %q", got, want)
+	}
+	if got := formatEntry(entry, false); got != "GET /api/v2000 500 12ms" {
+		t.Errorf("uncolored output changed: %q", got)
+	}
+
+	// Structured entries get the status after the level
+	structured := map[string]any{"message": "done", "status_code": "404"}
+	if got := formatEntry(structured, true); !strings.Contains(got, "\x1b[33m404\x1b[0m done") {
+		t.Errorf("structured output missing colored status: %q", got)
+	}
+	if got := formatEntry(structured, false); got != "404 done" {
+		t.Errorf("expected the same fields without color, got %q", got)
+	}
+}
+
+func TestJSONArrayWriter(t *testing.T) {
+	format, err := newEntryFormatter(jsonArrayFormat, "", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	array := &jsonArrayWriter{w: &buf}
+	array.write(format(map[string]any{"id": "1", "message": "a"}))
+	array.write(format(map[string]any{"id": "2"}))
+	array.close()
+	var got []map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("output is not a JSON array: %v\n%s", err, buf.String())
+	}
+	if len(got) != 2 || got[0]["message"] != "a" || got[1]["id"] != "2" {
+		t.Errorf("unexpected array: %v", got)
+	}
+
+	buf.Reset()
+	(&jsonArrayWriter{w: &buf}).close()
+	if buf.String() != "[]\n" {
+		t.Errorf("expected empty array, got %q", buf.String())
+	}
+
+	var none *jsonArrayWriter
+	none.close() // No-op
+}
+
+func TestExportFormatFor(t *testing.T) {
+	tests := map[string]string{
+		"out.json":  "json",
+		"out.JSONL": "ndjson",
+		"out.csv":   "csv",
+		"out.log":   "text",
+		"out":       "text",
+	}
+	for path, want := range tests {
+		if got := exportFormatFor(path); got != want {
+			t.Errorf("exportFormatFor(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestWriteEntries(t *testing.T) {
+	entries := []map[string]any{
+		{"level": "error", "message": "boom", "status": 500.0},
+		{"level": "info", "message": "ok, fine", "fields": map[string]any{"user": "ann"}},
+	}
+
+	var buf bytes.Buffer
+	if err := writeEntries(&buf, entries, "ndjson"); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != 2 || !strings.Contains(lines[0], `"message":"boom"`) {
+		t.Errorf("unexpected ndjson: %q", buf.String())
+	}
+
+	buf.Reset()
+	if err := writeEntries(&buf, entries, "json"); err != nil {
+		t.Fatal(err)
+	}
+	var decoded []map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || len(decoded) != 2 {
+		t.Errorf("expected a JSON array of 2 entries, got %q (%v)", buf.String(), err)
+	}
+
+	buf.Reset()
+	if err := writeEntries(&buf, entries, "csv"); err != nil {
+		t.Fatal(err)
+	}
+	want := "fields,level,message,status\n" +
+		",error,boom,500\n" +
+		"\"{\"\"user\"\":\"\"ann\"\"}\",info,\"ok, fine\",\n"
+	if buf.String() != want {
+		t.Errorf("unexpected csv:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := writeEntries(&buf, entries, "text"); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(buf.String(), "\n") != 2 || strings.Contains(buf.String(), "\033[") {
+		t.Errorf("expected two uncolored lines, got %q", buf.String())
+	}
+
+	if err := writeEntries(&buf, entries, "xml"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+func TestInferLevelFromText(t *testing.T) {
+	tests := map[string]string{
+		"[ERROR] disk full":                          "ERROR",
+		"ts=1 level=warn msg=\"slow query\"":         "WARN",
+		`{"level":"debug","msg":"x"}`:                "DEBUG",
+		"2024-01-02 15:04:05 INFO server started":    "INFO",
+		"[main] FATAL: out of memory":                "FATAL",
+		"no error here, everything is fine":          "",
+		"[worker-3] processed 10 jobs":               "",
+		"severity: critical, error budget exhausted": "CRITICAL",
+	}
+	for text, want := range tests {
+		if got := inferLevelFromText(text); got != want {
+			t.Errorf("inferLevelFromText(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestFormatEntryInferLevel(t *testing.T) {
+	entry := map[string]any{"raw_message": "[ERROR] disk full"}
+	if got := formatEntry(entry, true); got != "[ERROR] disk full" {
+		t.Errorf("expected no inferred color by default, got %q", got)
+	}
+
+	inferLevel = true
+	defer func() { inferLevel = false }()
+	if got := formatEntry(entry, true); got != style("[ERROR] disk full", "31", true) {
+		t.Errorf("expected inferred error color, got %q", got)
+	}
+	// An explicit level field wins
+	entry["fields"] = map[string]any{"level": "info"}
+	if got := formatEntry(entry, true); got != style("[ERROR] disk full", "36", true) {
+		t.Errorf("expected the level field's color, got %q", got)
+	}
+}
+
+func TestSpinnerStopIsSynchronous(t *testing.T) {
+	var buf bytes.Buffer
+	stop := spinTo(&buf, "Fetching logs")
+	stop(false)
+	// The final line must be written by the time stop returns, since fatal
+	// exits right after
+	if !strings.HasSuffix(buf.String(), "\rFetching logs ✘\n") {
+		t.Errorf("output = %q, want it to end with the failure mark", buf.String())
+	}
+	stop(true) // Later calls are no-ops
+	if strings.Contains(buf.String(), "✔") {
+		t.Errorf("second stop wrote again: %q", buf.String())
+	}
+
+	buf.Reset()
+	spinTo(&buf, "Fetching logs")(true)
+	if !strings.HasSuffix(buf.String(), "\rFetching logs ✔\n") {
+		t.Errorf("output = %q, want it to end with the success mark", buf.String())
+	}
+}
+
+func TestLargeIntegerIDsStayExact(t *testing.T) {
+	const id = "1234567890123456789" // Not representable as a float64
+	payload, err := tailstream.DecodeLogResponse([]byte(`{"data":[{"id":` + id + `,"message":"hi","fields":{"trace":9007199254740993}}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := payload.Data[0]
+
+	if got := tailstream.LogEntry(entry).String("id"); got != id {
+		t.Errorf("String(id) = %q, want %q", got, id)
+	}
+	if got := tailstream.LogEntry(entry).FieldString("trace"); got != "9007199254740993" {
+		t.Errorf("FieldString(trace) = %q", got)
+	}
+
+	var buf bytes.Buffer
+	if err := writeEntries(&buf, plainEntries(payload.Data), "ndjson"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"id":`+id) {
+		t.Errorf("export lost precision: %s", buf.String())
+	}
+
+	if got := compareValues(json.Number(id), json.Number("1234567890123456788")); got != 1 {
+		t.Errorf("compareValues of adjacent large IDs = %d, want 1", got)
+	}
+}
+
+func TestFormatEntryMultiline(t *testing.T) {
+	entry := map[string]any{
+		"raw_message": "panic: boom\r\n\tat main.go:12\r\n\tat proc.go:250\r\n",
+		"fields":      map[string]any{"level": "error"},
+	}
+	want := "panic: boom\n\tat main.go:12\n\tat proc.go:250"
+	if got := formatEntry(entry, false); got != want {
+		t.Errorf("plain: got %q, want %q", got, want)
+	}
+
+	// Each line is colored on its own, indentation intact
+	lines := strings.Split(formatEntry(entry, true), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %q", lines)
+	}
+	for i, line := range lines {
+		if !strings.HasPrefix(line, "\x1b[31m") || !strings.HasSuffix(line, "\x1b[0m") {
+			t.Errorf("line %d not styled on its own: %q", i, line)
+		}
+	}
+	if !strings.HasPrefix(lines[1], "\x1b[31m\tat") {
+		t.Errorf("indentation lost: %q", lines[1])
+	}
+}
+
+func TestTruncateText(t *testing.T) {
+	tests := []struct {
+		text  string
+		width int
+		want  string
+	}{
+		{"short", 10, "short"},
+		{"exactly10!", 10, "exactly10!"},
+		{"a long log line", 8, "a long …"},
+		{"héllo wörld", 6, "héllo…"}, // Counts runes, not bytes
+		{"\x1b[31merror: disk full\x1b[0m", 6, "\x1b[31merror…\x1b[0m"},
+		{"\x1b[31mok\x1b[0m", 3, "\x1b[31mok\x1b[0m"}, // Escapes don't count
+		{"first line is long\n\tsecond", 8, "first l…\n\tsecond"},
+		{"anything", 0, "anything"}, // 0 = no truncation
+	}
+	for _, tt := range tests {
+		if got := truncateText(tt.text, tt.width); got != tt.want {
+			t.Errorf("truncateText(%q, %d) = %q, want %q", tt.text, tt.width, got, tt.want)
+		}
+	}
+}
+
+func TestWithFields(t *testing.T) {
+	entry := map[string]any{"fields": map[string]any{"status": json.Number("502"), "path": "/api"}, "tags": []any{"a"}}
+	got := withFields("GET failed", entry, []string{"status", "missing", "path", "tags"}, false)
+	if want := `GET failed status=502 path=/api tags=["a"]`; got != want {
+		t.Errorf("withFields = %q, want %q", got, want)
+	}
+
+	// Fields follow the first line of a multi-line message
+	got = withFields("panic: boom\n\tat main.go:1", entry, []string{"status"}, false)
+	if want := "panic: boom status=502\n\tat main.go:1"; got != want {
+		t.Errorf("withFields multi-line = %q, want %q", got, want)
+	}
+}
+
+func TestNewTimestampFormatter(t *testing.T) {
+	entry := map[string]any{"timestamp": "2024-01-02T16:04:05.250+01:00", "message": "x"}
+	tests := []struct {
+		layout, want string
+	}{
+		{"rfc3339", "2024-01-02T15:04:05.25Z"},
+		{"", "2024-01-02T15:04:05.25Z"},
+		{"unix", "1704207845.250"},
+		{"unix-ms", "1704207845250"},
+		{"2006-01-02 15:04", "2024-01-02 15:04"},
+	}
+	for _, tt := range tests {
+		format, err := newTimestampFormatter(tt.layout)
+		if err != nil {
+			t.Fatalf("newTimestampFormatter(%q) failed: %v", tt.layout, err)
+		}
+		if got := format(entry); got != tt.want {
+			t.Errorf("newTimestampFormatter(%q) = %q, want %q", tt.layout, got, tt.want)
+		}
+	}
+
+	format, _ := newTimestampFormatter("unix-ms")
+	if got := format(map[string]any{"timestamp_ms": json.Number("1704207845250")}); got != "1704207845250" {
+		t.Errorf("expected timestamp_ms to be used, got %q", got)
+	}
+	if got := format(map[string]any{"message": "no time"}); got != "-" {
+		t.Errorf("expected - for an entry without a timestamp, got %q", got)
+	}
+
+	if _, err := newTimestampFormatter("epoch"); err == nil {
+		t.Error("expected an error for a format that isn't a layout")
+	}
+}
@@ -0,0 +1,78 @@
+// Package main - entry.go
+//
+// Client-side helpers over log entries: the text search terms are matched
+// against, and the field paths offered by the interactive field picker.
+// Resolving an entry's level, message, fields, and timestamp is done by
+// tailstream.LogEntry.
+
+package main
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"tailstream/client/pkg/tailstream"
+)
+
+// searchText returns the entry as lower-case compact JSON, the text that
+// client-side search terms are matched against
+func searchText(entry map[string]any) (string, bool) {
+	blob, err := json.Marshal(entry)
+	if err != nil {
+		return "", false
+	}
+	return strings.ToLower(string(blob)), true
+}
+
+// searchTexts returns the searchText of each entry ("" for one that can't be
+// encoded), so repeated searches over the same entries encode them only once
+func searchTexts(entries []map[string]any) []string {
+	texts := make([]string, len(entries))
+	for i, entry := range entries {
+		texts[i], _ = searchText(entry)
+	}
+	return texts
+}
+
+// plainEntries returns decoded API entries as the maps the client works with
+func plainEntries(entries []tailstream.LogEntry) []map[string]any {
+	plain := make([]map[string]any, len(entries))
+	for i, entry := range entries {
+		plain[i] = entry
+	}
+	return plain
+}
+
+// discoverFields returns the sorted field paths found in entries, in the form
+// Field accepts: leaves of the parsed 'fields' object without the prefix, then
+// any other top-level leaves. Nested objects are walked into; arrays count as
+// one field.
+func discoverFields(entries []map[string]any) []string {
+	seen := make(map[string]bool)
+	var walk func(prefix string, m map[string]any)
+	walk = func(prefix string, m map[string]any) {
+		for k, v := range m {
+			if prefix == "" && k == "fields" {
+				continue
+			}
+			if child, ok := v.(map[string]any); ok {
+				walk(prefix+k+".", child)
+				continue
+			}
+			seen[prefix+k] = true
+		}
+	}
+	for _, entry := range entries {
+		if fields, ok := entry["fields"].(map[string]any); ok {
+			walk("", fields)
+		}
+		walk("", entry)
+	}
+	paths := make([]string, 0, len(seen))
+	for path := range seen {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"tailstream/client/pkg/tailstream"
+)
+
+func TestDiscoverFields(t *testing.T) {
+	entries := []map[string]any{
+		{"message": "a", "fields": map[string]any{"status": 200.0, "http": map[string]any{"path": "/"}}},
+		{"message": "b", "level": "info", "tags": []any{"x"}},
+	}
+	got := discoverFields(entries)
+	want := []string{"http.path", "level", "message", "status", "tags"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("discoverFields = %v, want %v", got, want)
+	}
+	// Every discovered path resolves with Field
+	for _, path := range got {
+		found := false
+		for _, e := range entries {
+			if _, ok := tailstream.LogEntry(e).Field(path); ok {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("discovered path %q doesn't resolve", path)
+		}
+	}
+}
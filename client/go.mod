@@ -2,6 +2,7 @@ module tailstream/client
 
 go 1.23
 
-require gopkg.in/yaml.v3 v3.0.1
-
-
+require (
+	golang.org/x/time v0.10.0
+	gopkg.in/yaml.v3 v3.0.1
+)
@@ -19,35 +19,146 @@ package main
 
 import (
 	"bufio"
+	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"net/http"
+	"io"
+	"maps"
 	"net/url"
 	"os"
 	"os/exec"
 	"os/signal"
+	"reflect"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
+	"unicode/utf8"
 	"unsafe"
+
+	"tailstream/client/pkg/tailstream"
 )
 
+// defaultContextWindow is the range on each side of an entry used by the c key
+const defaultContextWindow = 5 * time.Minute
+
+// autoRefreshInterval is how often auto-refresh (a key) reloads the view
+const autoRefreshInterval = 5 * time.Second
+
+// compactUIHeight is the terminal height below which the UI switches to the
+// compact layout (header and footer only)
+const compactUIHeight = 12
+
 // InteractiveContext holds the context needed for dynamic operations in interactive mode
 type InteractiveContext struct {
 	BaseURL   string
-	Token     string
+	API       *tailstream.Client // Sends reloads (token, HTTP client, --post)
 	StreamID  string
 	PerPage   int
 	SortDir   string
-	Client    *http.Client
 	Endpoint  string
 	BaseQuery url.Values
+	Redactor  *redactor // Masks sensitive values in reloaded entries (nil = off)
+	EntryURL  string    // Web UI link pattern for the o key (empty = default)
+
+	ContextWindow time.Duration // Range on each side of an entry for the c key
+	CompactUI     bool          // Always use the compact layout (--compact-ui)
+	Keyboard      *os.File      // Terminal for keystrokes and stty (nil = stdin)
+	Prefetch      int           // Pages to keep loaded ahead of the cursor (--prefetch)
+	Fields        []string      // Fields initially shown on each line (v key)
+
+	// Entries left below the cursor that trigger the next page load
+	// (--prefetch-threshold, +/- keys); 0 derives it from Prefetch
+	PrefetchThreshold int
+
+	// Start following new entries, reloading this often (--follow with
+	// --interactive); 0 opens a static view
+	Live time.Duration
+}
+
+// isTerminal reports whether the file is attached to a terminal (character device)
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// openKeyboard returns the terminal to read keystrokes from: stdin when it is
+// a terminal, otherwise the controlling terminal at /dev/tty, so the viewer
+// still works when stdin is a pipe. It fails when there is no terminal at all
+// (e.g. under cron or in CI).
+func openKeyboard() (*os.File, error) {
+	if isTerminal(os.Stdin) {
+		return os.Stdin, nil
+	}
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	if !isTerminal(tty) {
+		tty.Close()
+		return nil, fmt.Errorf("/dev/tty is not a terminal")
+	}
+	return tty, nil
+}
+
+// keyStream delivers keystrokes read from the terminal by a background
+// goroutine, so the input loop can wait for a key and for posted updates at
+// the same time
+type keyStream struct {
+	chunks  chan []byte // What each terminal read returned; closed at EOF
+	pending []byte      // Unread rest of the current chunk
+}
+
+// newKeyStream starts reading r in the background
+func newKeyStream(r io.Reader) *keyStream {
+	k := &keyStream{chunks: make(chan []byte)}
+	go func() {
+		defer close(k.chunks)
+		for {
+			buf := make([]byte, 64)
+			n, err := r.Read(buf)
+			if n > 0 {
+				k.chunks <- buf[:n]
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return k
+}
+
+// buffered reports whether input is left over from the last chunk
+func (k *keyStream) buffered() bool {
+	return len(k.pending) > 0
+}
+
+// Read returns the rest of the current chunk, waiting for the next one when
+// it's used up
+func (k *keyStream) Read(p []byte) (int, error) {
+	if len(k.pending) == 0 {
+		chunk, ok := <-k.chunks
+		if !ok {
+			return 0, io.EOF
+		}
+		k.pending = chunk
+	}
+	n := copy(p, k.pending)
+	k.pending = k.pending[n:]
+	return n, nil
 }
 
 // runInteractiveMode displays logs in an interactive viewer with navigation and pagination
-func runInteractiveMode(entries []map[string]any, withColor bool, hasMore bool, totalCount *int, nextCursor string, fetcher func(string, string) ([]map[string]any, bool, *int, string, error), ctx *InteractiveContext) {
+func runInteractiveMode(entries []map[string]any, withColor bool, hasMore bool, totalCount *int, nextCursor string, fetcher pageFetcher, ctx *InteractiveContext) {
 	if len(entries) == 0 {
 		return
 	}
@@ -64,21 +175,85 @@ func runInteractiveMode(entries []map[string]any, withColor bool, hasMore bool,
 	searchCursor := ""         // Cursor for search pagination
 	searchHasMore := false     // Whether search results have more pages
 	searchTotal := (*int)(nil) // Total search results (can be nil)
+	showStats := false         // Whether the footer shows loaded byte-size stats (i key)
+	showDeltas := false        // Whether lines start with the gap since the previous entry (t key)
+	entrySearchTerm := ""      // Term for searching within an expanded entry's JSON
+
+	// Earlier inputs recalled with ↑/↓ at the / and f prompts; searches also
+	// persist across sessions
+	searchHistory := loadSearchHistory()
+	var dateHistory []string
+
+	// Whether collapsed entries wrap across rows instead of scrolling horizontally (w key)
+	wrapLines := false
+
+	// Whether horizontal scrolling applies to every entry at once (H key), so
+	// a column stays in view while moving between entries
+	globalHScroll := false
+	globalHOffset := 0
+
+	// Entry actions menu (. key): open state and the highlighted action
+	menuOpen := false
+	menuIdx := 0
+
+	// Fields added to every line as name=value (v key), kept for the session
+	shownFields := slices.Clone(ctx.Fields)
+
+	// Field the loaded entries are sorted by client-side (S key); empty keeps
+	// the order they arrived in
+	sortField := ""
+	sortDesc := false
+	var sortHistory []string
+
+	// Auto-refresh (a key): a background ticker reloads the view while the
+	// cursor sits on the newest entry; closing stopAutoRefresh ends it.
+	// live marks the auto-refresh --follow starts with, shown in the footer.
+	autoRefresh := false
+	live := false
+	var stopAutoRefresh chan struct{}
+	defer func() {
+		// Stop the ticker on quit so it can't redraw over the shell
+		if stopAutoRefresh != nil {
+			close(stopAutoRefresh)
+		}
+	}()
+
+	// Entries shown as a single line of compact JSON (J key)
+	compact := make(map[int]bool)
+
+	// Entries marked with m; exactly two marks enable the = diff view
+	marked := make(map[int]bool)
+
+	// Entries hidden from the view with x, most recent last (for undo with X)
+	type hiddenEntry struct {
+		idx   int
+		entry map[string]any
+	}
+	var hiddenEntries []hiddenEntry
 
 	// Date filter state
 	activeStartTime := ""
 	activeEndTime := ""
+	activeQuery := ctx.BaseQuery // Effective query params, including any date filter
 
 	// Pagination state - cursor-based
 	allEntries := entries
 	currentCursor := nextCursor // Cursor for loading next page
+	var pageCursors cursorLoop   // Guards loadNextPage against a repeating cursor
 	hasNextPage := hasMore
 	totalAvailable := totalCount // Can be nil in tail mode
+	loadedBytes := entriesSize(entries)
+
+	// Keystrokes come from the terminal even when stdin is a pipe
+	keyboard := ctx.Keyboard
+	if keyboard == nil {
+		keyboard = os.Stdin
+	}
 
 	// Disable input buffering
 	runCmd := func(name string, args ...string) error {
 		cmd := exec.Command(name, args...)
-		cmd.Stdin = os.Stdin
+		cmd.Stdin = keyboard
 		cmd.Stdout = os.Stdout
 		cmd.Stderr = os.Stderr
 		return cmd.Run()
@@ -103,7 +278,7 @@ func runInteractiveMode(entries []map[string]any, withColor bool, hasMore bool,
 	getTerminalSize := func() (int, int) {
 		ws := &winsize{}
 		retCode, _, errno := syscall.Syscall(syscall.SYS_IOCTL,
-			uintptr(syscall.Stdin),
+			keyboard.Fd(),
 			uintptr(syscall.TIOCGWINSZ),
 			uintptr(unsafe.Pointer(ws)))
 
@@ -144,24 +319,13 @@ func runInteractiveMode(entries []map[string]any, withColor bool, hasMore bool,
 
 	termHeight := getTerminalHeight()
 	termWidth := getTerminalWidth()
-	// Reserve space for: header (1) + status (1) + separator (1) + separator (1) + footer (1) = 5 lines
-	// The remaining space is for log content
-	viewportHeight := termHeight - 5
+	// Reserve space for the UI chrome (see uiChromeLines); the remaining
+	// space is for log content
+	viewportHeight := termHeight - uiChromeLines(termHeight, ctx.CompactUI)
 	if viewportHeight < 1 {
 		viewportHeight = 1 // Absolute minimum
 	}
 
-	// Helper to truncate a line to fit terminal width
-	truncateLine := func(line string, maxWidth int) string {
-		if len(line) <= maxWidth {
-			return line
-		}
-		if maxWidth <= 3 {
-			return "..."
-		}
-		return line[:maxWidth-3] + "..."
-	}
-
 	// Helper to extract a horizontal window from a line with scroll offset
 	horizontalWindow := func(line string, offset int, maxWidth int) string {
 		lineLen := len(line)
@@ -212,138 +376,252 @@ func runInteractiveMode(entries []map[string]any, withColor bool, hasMore bool,
 		return result
 	}
 
+	// Background fetches and timers post their state changes to updates,
+	// which the input loop applies between keystrokes, so the view state is
+	// only ever touched by that one goroutine
+	updates := make(chan func())
+	done := make(chan struct{})
+	defer close(done)
+	post := func(update func()) {
+		select {
+		case updates <- update:
+		case <-done:
+		}
+	}
+
+	// fetch serialises page requests; the fetcher (sampling, caching) isn't
+	// safe for concurrent use. generation counts reloads and searches, so
+	// results of a fetch that was superseded meanwhile are dropped.
+	var fetchMu sync.Mutex
+	fetch := func(cursor, query string) ([]map[string]any, bool, *int, string, error) {
+		fetchMu.Lock()
+		defer fetchMu.Unlock()
+		return fetcher(cursor, query)
+	}
+	generation := 0
+
 	// Forward declare functions
 	var renderScreen func()
 	var loadNextPage func()
-	var performSearch func(query string)
-	var reloadWithDateFilter func(start, end string)
+	var prefetch func()
+	var performSearch func(query string, keepPosition bool)
+	var reloadWithDateFilter func(start, end string, keepPosition bool)
+
+	// clearStatusAfter clears the status line once d has passed
+	clearStatusAfter := func(d time.Duration) {
+		time.AfterFunc(d, func() {
+			post(func() {
+				status = ""
+				renderScreen()
+			})
+		})
+	}
 
-	// Reload data with date filter
-	reloadWithDateFilter = func(start, end string) {
-		loading = true
-		status = "Loading logs with date filter..."
-		renderScreen()
+	// newestIdx returns the index of the most recent loaded entry
+	newestIdx := func() int {
+		if ctx.SortDir == "asc" {
+			return len(allEntries) - 1
+		}
+		return 0
+	}
 
-		go func() {
-			// Build query with date filters
-			queryParams := url.Values{}
-			for k, v := range ctx.BaseQuery {
-				queryParams[k] = v
+	// entryLine renders a collapsed entry: the formatted log line, or its
+	// compact JSON when toggled with J
+	entryLine := func(i int) string {
+		line := formatEntry(allEntries[i], withColor)
+		if len(shownFields) > 0 {
+			line = withFields(line, allEntries[i], shownFields, withColor)
+		}
+		if compact[i] {
+			raw, _ := json.Marshal(allEntries[i])
+			line = renderSafe(string(raw))
+		}
+		if showDeltas {
+			delta := strings.Repeat(" ", deltaWidth)
+			if i > 0 {
+				if d, ok := entryDelta(allEntries[i-1], allEntries[i]); ok {
+					color := "90"
+					if d >= deltaHighlight {
+						color = "33" // Pauses stand out
+					}
+					delta = style(fmt.Sprintf("%*s", deltaWidth, formatDelta(d)), color, withColor)
+				}
 			}
+			line = delta + " " + line
+		}
+		return line
+	}
 
-			// Add date filters
-			if start != "" {
-				parsed, err := parseTimeArg(start)
-				if err != nil {
-					status = fmt.Sprintf("Invalid start time: %v", err)
-					loading = false
-					renderScreen()
-					return
-				}
-				t, err := time.Parse(time.RFC3339, parsed)
-				if err != nil {
-					status = fmt.Sprintf("Failed to parse start time: %v", err)
-					loading = false
-					renderScreen()
-					return
-				}
-				queryParams.Set("start_time", strconv.FormatInt(t.UnixMilli(), 10))
+	// collapsedLine is entryLine on a single row: a multi-line message (e.g.
+	// a stack trace) shows its first line and how many more there are
+	collapsedLine := func(i int) string {
+		return collapseLines(entryLine(i), withColor)
+	}
+
+	// entryRows renders a collapsed entry wrapped to the terminal width, each
+	// line of a multi-line message wrapped on its own
+	entryRows := func(i int, cursor string) []string {
+		var rows []string
+		for j, part := range strings.Split(entryLine(i), "\n") {
+			prefix := "  "
+			if j == 0 {
+				prefix = cursor
 			}
+			rows = append(rows, wrapLine(prefix+part, termWidth, "  ")...)
+		}
+		return rows
+	}
 
-			if end != "" {
-				parsed, err := parseTimeArg(end)
-				if err != nil {
-					status = fmt.Sprintf("Invalid end time: %v", err)
-					loading = false
-					renderScreen()
-					return
-				}
-				t, err := time.Parse(time.RFC3339, parsed)
-				if err != nil {
-					status = fmt.Sprintf("Failed to parse end time: %v", err)
-					loading = false
-					renderScreen()
-					return
-				}
-				queryParams.Set("end_time", strconv.FormatInt(t.UnixMilli(), 10))
+	// clampIdx keeps the current index within the loaded entries
+	clampIdx := func(idx int) int {
+		if idx >= len(allEntries) {
+			idx = len(allEntries) - 1
+		}
+		if idx < 0 {
+			idx = 0
+		}
+		return idx
+	}
+
+	// applySort reorders the loaded entries by sortField, carrying the cursor
+	// and per-entry state (expanded, marked, ...) along with each entry, so a
+	// page loaded in the background doesn't move the cursor off its entry.
+	// Hidden entries move with the entry they were hidden in front of.
+	applySort := func() {
+		if sortField == "" {
+			return
+		}
+		order := sortOrder(allEntries, sortField, sortDesc)
+		sorted := make([]map[string]any, len(allEntries))
+		for i, j := range order {
+			sorted[i] = allEntries[j]
+		}
+		allEntries = sorted
+		expanded = permuteIndexKeys(expanded, order)
+		marked = permuteIndexKeys(marked, order)
+		compact = permuteIndexKeys(compact, order)
+		expandedScrollOffset = permuteIndexKeys(expandedScrollOffset, order)
+		horizontalScrollOffset = permuteIndexKeys(horizontalScrollOffset, order)
+		moved := movedIndices(order)
+		for k, h := range hiddenEntries {
+			if h.idx < len(moved) {
+				hiddenEntries[k].idx = moved[h.idx]
 			}
+		}
+		if currentIdx < len(moved) {
+			currentIdx = moved[currentIdx]
+		}
+	}
 
-			// Make API request
-			fullURL := ctx.Endpoint + "?" + queryParams.Encode()
-			req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, fullURL, nil)
+	// Reload data with date filter. With keepPosition, the cursor stays on the
+	// same index (clamped) instead of jumping back to the top.
+	reloadWithDateFilter = func(start, end string, keepPosition bool) {
+		// Build query with date filters
+		queryParams := url.Values{}
+		for k, v := range ctx.BaseQuery {
+			queryParams[k] = v
+		}
+
+		// Add date filters
+		if start != "" {
+			parsed, err := parseTimeArg(start)
 			if err != nil {
-				status = fmt.Sprintf("Request error: %v", err)
-				loading = false
+				status = fmt.Sprintf("Invalid start time: %v", err)
 				renderScreen()
 				return
 			}
-			req.Header.Set("Accept", "application/json")
-			req.Header.Set("Authorization", "Bearer "+ctx.Token)
-
-			resp, err := ctx.Client.Do(req)
+			t, err := time.Parse(time.RFC3339, parsed)
 			if err != nil {
-				status = fmt.Sprintf("Request error: %v", err)
-				loading = false
+				status = fmt.Sprintf("Failed to parse start time: %v", err)
 				renderScreen()
 				return
 			}
-			defer resp.Body.Close()
+			queryParams.Set("start_time", strconv.FormatInt(t.UnixMilli(), 10))
+		}
 
-			if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-				status = fmt.Sprintf("Request failed: %s", resp.Status)
-				loading = false
+		if end != "" {
+			parsed, err := parseEndTimeArg(end)
+			if err != nil {
+				status = fmt.Sprintf("Invalid end time: %v", err)
 				renderScreen()
 				return
 			}
-
-			var payload logResponse
-			if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
-				status = fmt.Sprintf("Parse error: %v", err)
-				loading = false
+			t, err := time.Parse(time.RFC3339, parsed)
+			if err != nil {
+				status = fmt.Sprintf("Failed to parse end time: %v", err)
 				renderScreen()
 				return
 			}
+			queryParams.Set("end_time", strconv.FormatInt(t.UnixMilli(), 10))
+		}
 
-			// Update state
-			allEntries = payload.Data
-			hasNextPage = payload.Meta.HasMore
-			totalAvailable = payload.Meta.Total
-			if payload.Meta.NextCursor != nil {
-				currentCursor = *payload.Meta.NextCursor
-			} else {
-				currentCursor = ""
-			}
-			currentIdx = 0
-			expanded = make(map[int]bool)
-			expandedScrollOffset = make(map[int]int)
-			searchActive = false
-			searchQuery = ""
-			activeStartTime = start
-			activeEndTime = end
+		loading = true
+		generation++
+		gen := generation
+		status = "Loading logs with date filter..."
+		renderScreen()
 
-			loading = false
-			if len(payload.Data) == 0 {
-				status = "No logs found for the specified date range"
-			} else {
-				filterMsg := ""
-				if start != "" || end != "" {
-					filterMsg = " (filtered)"
+		go func() {
+			fetchMu.Lock()
+			payload, requestURL, err := fetchLogs(ctx, queryParams)
+			fetchMu.Unlock()
+			post(func() {
+				if gen != generation {
+					return // Superseded by a newer reload or search
+				}
+				loading = false
+				if err != nil {
+					status = err.Error()
+					renderScreen()
+					return
 				}
-				status = fmt.Sprintf("Loaded %d entries%s", len(payload.Data), filterMsg)
-			}
-			renderScreen()
 
-			// Clear status after 3 seconds
-			go func() {
-				time.Sleep(3 * time.Second)
-				status = ""
+				// Update state
+				allEntries = ctx.Redactor.applyAll(plainEntries(payload.Data))
+				loadedBytes = entriesSize(allEntries)
+				hasNextPage = payload.Meta.HasMore
+				totalAvailable = payload.Meta.Total
+				currentCursor = payload.NextPageToken(requestURL)
+				pageCursors = cursorLoop{}
+				position := currentIdx
+				expanded = make(map[int]bool)
+				expandedScrollOffset = make(map[int]int)
+				compact = make(map[int]bool)
+				marked = make(map[int]bool)
+				hiddenEntries = nil
+				searchActive = false
+				searchQuery = ""
+				activeStartTime = start
+				activeEndTime = end
+				activeQuery = queryParams
+				applySort()
+				// The cursor stays on its row of the (re)sorted view
+				if keepPosition {
+					currentIdx = clampIdx(position)
+				} else {
+					currentIdx = 0
+				}
+
+				if len(payload.Data) == 0 {
+					status = "No logs found for the specified date range"
+				} else {
+					filterMsg := ""
+					if start != "" || end != "" {
+						filterMsg = " (filtered)"
+					}
+					status = fmt.Sprintf("Loaded %d entries%s", len(payload.Data), filterMsg)
+				}
 				renderScreen()
-			}()
+				prefetch()
+
+				// Clear status after 3 seconds
+				clearStatusAfter(3 * time.Second)
+			})
 		}()
 	}
 
 	// Search function - performs server-side search
-	performSearch = func(query string) {
+	performSearch = func(query string, keepPosition bool) {
 		if query == "" {
 			// Clear search - restore to normal browsing mode
 			searchQuery = ""
@@ -358,50 +636,62 @@ func runInteractiveMode(entries []map[string]any, withColor bool, hasMore bool,
 		searchQuery = query
 		searchActive = true
 		searchCursor = "" // Start from beginning
-		currentIdx = 0
+		pageCursors = cursorLoop{}
+		if !keepPosition {
+			currentIdx = 0
+		}
 		loading = true
 		status = fmt.Sprintf("Searching for '%s'...", query)
 		renderScreen()
 
 		// Fetch search results from server
+		generation++
+		gen := generation
 		go func() {
-			results, hasMore, total, cursor, err := fetcher("", query) // Empty cursor for first search
-			if err != nil {
-				status = fmt.Sprintf("Search error: %v", err)
-				loading = false
-				renderScreen()
-				return
-			}
-
-			allEntries = results
-			searchHasMore = hasMore
-			searchTotal = total
-			searchCursor = cursor
-			loading = false
-
-			if len(results) > 0 {
-				// Build searchMatches for n/N navigation
-				searchMatches = make([]int, len(results))
-				for i := range results {
-					searchMatches[i] = i
+			results, hasMore, total, cursor, err := fetch("", query) // Empty cursor for first search
+			post(func() {
+				if gen != generation {
+					return // Superseded by a newer reload or search
 				}
-				moreMsg := ""
-				if hasMore {
-					moreMsg = " - scroll down to load more"
+				loading = false
+				if err != nil {
+					status = fmt.Sprintf("Search error: %v", err)
+					renderScreen()
+					return
 				}
-				// Use actual result count, not the (broken) total from backend
-				totalMsg := fmt.Sprintf("%d", len(results))
-				if total != nil && *total > 0 {
-					totalMsg = fmt.Sprintf("%d of %d", len(results), *total)
-				} else if hasMore {
-					totalMsg = fmt.Sprintf("%d+", len(results))
+
+				allEntries = results
+				loadedBytes = entriesSize(allEntries)
+				position := currentIdx
+				expanded = make(map[int]bool)
+				expandedScrollOffset = make(map[int]int)
+				compact = make(map[int]bool)
+				marked = make(map[int]bool)
+				hiddenEntries = nil
+				searchHasMore = hasMore
+				searchTotal = total
+				searchCursor = cursor
+				applySort()
+				currentIdx = clampIdx(position) // Same row of the sorted results
+
+				if len(results) > 0 {
+					// Build searchMatches for n/N navigation
+					searchMatches = make([]int, len(results))
+					for i := range results {
+						searchMatches[i] = i
+					}
+					moreMsg := ""
+					if hasMore {
+						moreMsg = " - scroll down to load more"
+					}
+					status = fmt.Sprintf("Found %d results%s%s - Esc to clear", len(results), totalInfo(len(results), total, hasMore), moreMsg)
+				} else {
+					searchMatches = []int{}
+					status = fmt.Sprintf("No matches for '%s' (Esc: clear)", query)
 				}
-				status = fmt.Sprintf("Found %s results%s - Esc to clear", totalMsg, moreMsg)
-			} else {
-				searchMatches = []int{}
-				status = fmt.Sprintf("No matches for '%s' (Esc: clear)", query)
-			}
-			renderScreen()
+				renderScreen()
+				prefetch()
+			})
 		}()
 	}
 
@@ -409,18 +699,24 @@ func runInteractiveMode(entries []map[string]any, withColor bool, hasMore bool,
 		// Update terminal dimensions in case of resize
 		termHeight = getTerminalHeight()
 		termWidth = getTerminalWidth()
-		// Calculate viewport height: we need to reserve exactly 5 lines for UI elements:
-		// - Header line (1)
-		// - Status line (1)
-		// - Top separator (1)
-		// - Bottom separator (1)
-		// - Footer line (1)
-		// Everything else is content
-		viewportHeight = termHeight - 5
+		// Calculate viewport height: everything but the UI chrome is content.
+		// Page up/down jump by the same amount.
+		chrome := uiChromeLines(termHeight, ctx.CompactUI)
+		compactLayout := chrome == 2 // Header and footer only
+		viewportHeight = termHeight - chrome
 		if viewportHeight < 1 {
 			viewportHeight = 1 // Absolute minimum
 		}
 
+		// While the current entry's JSON is scrolled past its first line, its
+		// summary stays pinned above the content so it's clear which entry
+		// is being read. The JSON gets one line less.
+		pinnedLine := ""
+		if currentIdx < len(allEntries) && expanded[currentIdx] && expandedScrollOffset[currentIdx] > 0 && viewportHeight > 1 {
+			pinnedLine = style("│ ", "36", withColor) + collapseLines(formatEntry(allEntries[currentIdx], withColor), withColor)
+			viewportHeight--
+		}
+
 		// Build entire screen content in a buffer to avoid tearing
 		var screen strings.Builder
 
@@ -436,6 +732,16 @@ func runInteractiveMode(entries []map[string]any, withColor bool, hasMore bool,
 			loadingText = " (loading...)"
 		}
 
+		// Auto-refresh indicator, paused while the user is browsing older entries
+		autoText := ""
+		if autoRefresh && !live {
+			if currentIdx == newestIdx() {
+				autoText = style("[AUTO] ", "32", withColor)
+			} else {
+				autoText = style("[AUTO paused] ", "90", withColor)
+			}
+		}
+
 		// Show active date filter if any
 		dateFilterText := ""
 		if activeStartTime != "" || activeEndTime != "" {
@@ -448,37 +754,51 @@ func runInteractiveMode(entries []map[string]any, withColor bool, hasMore bool,
 			}
 		}
 
+		if sortField != "" {
+			dateFilterText += fmt.Sprintf(" [sorted by %s]", sortText(sortField, sortDesc))
+		}
+		if len(shownFields) > 0 {
+			dateFilterText += fmt.Sprintf(" [+%s]", strings.Join(shownFields, ", "))
+		}
+		if globalHScroll {
+			dateFilterText += " [scrolling all lines]"
+		}
+
+		// The header and footer share one description of loaded vs total
+		loadedInfo := totalInfo(len(allEntries), totalAvailable, hasNextPage)
 		if searchActive {
-			totalInfo := ""
-			if searchTotal != nil && *searchTotal > 0 {
-				totalInfo = fmt.Sprintf(" of %d total", *searchTotal)
-			} else if searchHasMore {
-				totalInfo = " (more available)"
-			}
-			headerText = fmt.Sprintf("Search Results for '%s' (%d loaded%s)%s%s", searchQuery, len(allEntries), totalInfo, dateFilterText, loadingText)
+			loadedInfo = totalInfo(len(allEntries), searchTotal, searchHasMore)
+			headerText = fmt.Sprintf("Search Results for '%s' (%d loaded%s)%s%s", searchQuery, len(allEntries), loadedInfo, dateFilterText, loadingText)
 		} else {
-			totalInfo := ""
-			if totalAvailable != nil && *totalAvailable > 0 {
-				totalInfo = fmt.Sprintf(" of %d total", *totalAvailable)
-			} else if hasNextPage {
-				totalInfo = " (more available)"
-			}
-			headerText = fmt.Sprintf("Logs (%d loaded%s)%s%s", len(allEntries), totalInfo, dateFilterText, loadingText)
+			headerText = fmt.Sprintf("Logs (%d loaded%s)%s%s", len(allEntries), loadedInfo, dateFilterText, loadingText)
 		}
 
 		// Print header with line truncation
-		headerLine1 := headerText + " - Use j/k or ↓/↑ to navigate, Space/Enter to expand/collapse, q to quit"
-		screen.WriteString(truncateLine(headerLine1, termWidth))
-		screen.WriteString("\033[K\n")  // Clear to end of line
+		headerLine1 := autoText + headerText + " - Use j/k or ↓/↑ to navigate, Space/Enter to expand/collapse, q to quit"
+		separatorLine := strings.Repeat("─", termWidth)
+		if compactLayout {
+			// No status line: a pending status message takes the header's place
+			if status != "" {
+				headerLine1 = style(status, "33", withColor)
+			}
+			screen.WriteString(truncateLineWidth(headerLine1, termWidth))
+			screen.WriteString("\033[K\n")  // Clear to end of line
+		} else {
+			screen.WriteString(truncateLineWidth(headerLine1, termWidth))
+			screen.WriteString("\033[K\n")  // Clear to end of line
 
-		if status != "" {
-			screen.WriteString(truncateLine(style(status, "33", withColor), termWidth))
-		}
-		screen.WriteString("\033[K\n")  // Clear to end of line
+			if status != "" {
+				screen.WriteString(truncateLineWidth(style(status, "33", withColor), termWidth))
+			}
+			screen.WriteString("\033[K\n")  // Clear to end of line
 
-		separatorLine := strings.Repeat("─", termWidth)
-		screen.WriteString(separatorLine)
-		screen.WriteString("\033[K\n")  // Clear to end of line
+			screen.WriteString(separatorLine)
+			screen.WriteString("\033[K\n")  // Clear to end of line
+		}
+		if pinnedLine != "" {
+			screen.WriteString(truncateLineWidth(pinnedLine, termWidth))
+			screen.WriteString("\033[0m\033[K\n")
+		}
 
 		// Calculate viewport window
 		// Center the current index in the viewport when possible
@@ -495,6 +815,31 @@ func runInteractiveMode(entries []map[string]any, withColor bool, hasMore bool,
 			}
 		}
 
+		// Expanded and wrapped entries take several rows, so move the window
+		// down until the current entry fits. An expanded entry above the
+		// cursor is budgeted a full screen, which keeps this cheap when many
+		// are expanded (E key).
+		rows := func(i int) int {
+			switch {
+			case expanded[i] && (wrapLines || i < currentIdx):
+				return viewportHeight
+			case wrapLines:
+				return len(entryRows(i, "  "))
+			}
+			return 1
+		}
+		for viewportStart < currentIdx {
+			used := 0
+			for i := viewportStart; i <= currentIdx; i++ {
+				used += rows(i)
+			}
+			if used <= viewportHeight {
+				break
+			}
+			viewportStart++
+		}
+		viewportEnd = min(viewportStart+viewportHeight, len(allEntries))
+
 		// Render only visible entries
 		linesRendered := 0
 		for i := viewportStart; i < viewportEnd && i < len(allEntries) && linesRendered < viewportHeight; i++ {
@@ -503,9 +848,19 @@ func runInteractiveMode(entries []map[string]any, withColor bool, hasMore bool,
 			if i == currentIdx {
 				cursor = style("▶ ", "36", withColor)
 			}
+			if marked[i] {
+				if i == currentIdx {
+					cursor = style("▶*", "36", withColor)
+				} else {
+					cursor = style(" *", "35", withColor)
+				}
+			}
 
 			// Get horizontal scroll offset for this entry
 			hOffset := horizontalScrollOffset[i]
+			if globalHScroll {
+				hOffset = globalHOffset
+			}
 
 			if expanded[i] {
 				// Show full JSON when expanded - with scrolling support
@@ -528,7 +883,7 @@ func runInteractiveMode(entries []map[string]any, withColor bool, hasMore bool,
 					if lineIdx == scrollOffset {
 						prefix = cursor // Show cursor on first visible line
 					}
-					line := fmt.Sprintf("%s%s", prefix, jsonLines[lineIdx])
+					line := fmt.Sprintf("%s%s", prefix, renderSafe(jsonLines[lineIdx]))
 					// Apply horizontal scrolling
 					screen.WriteString(horizontalWindow(line, hOffset, termWidth))
 					screen.WriteString("\033[0m\033[K\n")  // Reset formatting and clear to end of line
@@ -544,9 +899,20 @@ func runInteractiveMode(entries []map[string]any, withColor bool, hasMore bool,
 						linesRendered++
 					}
 				}
+			} else if wrapLines {
+				// Show formatted log line wrapped across as many rows as it needs
+				for _, part := range entryRows(i, cursor) {
+					if linesRendered >= viewportHeight {
+						break
+					}
+					screen.WriteString(part)
+					screen.WriteString("\033[K\n")
+					linesRendered++
+				}
+				screen.WriteString("\033[0m")
 			} else {
 				// Show formatted log line with horizontal scrolling
-				line := fmt.Sprintf("%s%s", cursor, formatEntry(entry, withColor))
+				line := fmt.Sprintf("%s%s", cursor, collapsedLine(i))
 				screen.WriteString(horizontalWindow(line, hOffset, termWidth))
 				screen.WriteString("\033[0m\033[K\n")  // Reset formatting and clear to end of line
 				linesRendered++
@@ -558,19 +924,15 @@ func runInteractiveMode(entries []map[string]any, withColor bool, hasMore bool,
 			screen.WriteString("\033[K\n")  // Clear empty lines
 		}
 
-		screen.WriteString(separatorLine)
-		screen.WriteString("\033[K\n")  // Clear to end of line
+		if !compactLayout {
+			screen.WriteString(separatorLine)
+			screen.WriteString("\033[K\n")  // Clear to end of line
+		}
 
 		// Footer with navigation info
 		moreInfo := ""
-		if searchActive {
-			if searchHasMore {
-				moreInfo = " | More results (will auto-load)"
-			}
-		} else {
-			if hasNextPage {
-				moreInfo = " | More available (will auto-load)"
-			}
+		if (searchActive && searchHasMore) || (!searchActive && hasNextPage) {
+			moreInfo = " | Scroll to load more"
 		}
 
 		// Show viewport position indicator
@@ -580,13 +942,45 @@ func runInteractiveMode(entries []map[string]any, withColor bool, hasMore bool,
 			viewportInfo = fmt.Sprintf(" [%d%%]", percent)
 		}
 
-		helpText := "/: search | f: date filter"
+		// Optional size stats for gauging how heavy the stream is
+		statsInfo := ""
+		if showStats && len(allEntries) > 0 {
+			statsInfo = fmt.Sprintf(" | %s loaded, avg %s/entry", formatBytes(loadedBytes), formatBytes(loadedBytes/len(allEntries)))
+		}
+
+		helpText := "/: search | f: date filter | .: actions"
 		if searchActive {
-			helpText = "Esc: clear search | f: date filter"
+			helpText = "Esc: clear search | f: date filter | .: actions"
+		}
+
+		// The actions menu is drawn over the top right of the content, then
+		// the cursor goes back for the footer
+		if menuOpen {
+			top := 4 // Below the header, status, and separator lines
+			if compactLayout {
+				top = 2
+			}
+			menu := actionsMenu(menuIdx, withColor)
+			left := max(termWidth-utf8.RuneCountInString(stripANSI(menu[0]))-1, 1)
+			screen.WriteString("\0337") // Save cursor
+			for row, line := range menu {
+				fmt.Fprintf(&screen, "\033[%d;%dH%s\033[0m", top+row, left, line)
+			}
+			screen.WriteString("\0338") // Restore cursor
+		}
+
+		// Following (--follow) is shown first, paused like auto-refresh
+		liveInfo := ""
+		if live {
+			if currentIdx == newestIdx() {
+				liveInfo = style("● LIVE", "32", withColor) + " | "
+			} else {
+				liveInfo = style("LIVE paused", "90", withColor) + " | "
+			}
 		}
 
-		footerLine := fmt.Sprintf("Entry %d/%d%s%s | %s | Space: expand | q: quit", currentIdx+1, len(allEntries), viewportInfo, moreInfo, helpText)
-		screen.WriteString(truncateLine(footerLine, termWidth))
+		footerLine := fmt.Sprintf("%sEntry %d/%d%s%s%s%s | %s | Space: expand | q: quit", liveInfo, currentIdx+1, len(allEntries), loadedInfo, viewportInfo, moreInfo, statsInfo, helpText)
+		screen.WriteString(truncateLineWidth(footerLine, termWidth))
 		screen.WriteString("\033[0m\033[K")  // Reset formatting and clear to end of line (NO newline!)
 
 		// Clear any remaining lines below footer to prevent artifacts
@@ -597,7 +991,24 @@ func runInteractiveMode(entries []map[string]any, withColor bool, hasMore bool,
 		fmt.Print(screen.String())
 	}
 
-	// Load next page in background when approaching end
+	// prefetch loads the next page in the background once fewer than
+	// prefetchAhead entries are left below the cursor. Each page that
+	// arrives calls it again, so the buffer refills without blocking
+	// navigation; loading keeps it to one fetch at a time.
+	prefetch = func() {
+		more := hasNextPage
+		if searchActive {
+			more = searchHasMore
+		}
+		ahead := prefetchAhead(ctx.PrefetchThreshold, ctx.Prefetch, ctx.PerPage, viewportHeight)
+		if more && !loading && len(allEntries)-1-currentIdx < ahead {
+			loadNextPage()
+		}
+	}
+
+	// Load next page in background when approaching end. The page is fetched
+	// off the input goroutine and applied on it; loading keeps it to one
+	// page at a time.
 	loadNextPage = func() {
 		// In search mode, use search pagination
 		if searchActive {
@@ -608,35 +1019,44 @@ func runInteractiveMode(entries []map[string]any, withColor bool, hasMore bool,
 			status = "Loading more search results..."
 			renderScreen()
 
+			gen, pageCursor, query := generation, searchCursor, searchQuery
 			go func() {
-				newEntries, more, total, cursor, err := fetcher(searchCursor, searchQuery)
-				if err != nil {
-					status = fmt.Sprintf("Error loading: %v", err)
-				} else {
-					allEntries = append(allEntries, newEntries...)
-					searchHasMore = more
-					searchTotal = total
-					searchCursor = cursor
-					// Update searchMatches
-					startIdx := len(searchMatches)
-					for i := range newEntries {
-						searchMatches = append(searchMatches, startIdx+i)
+				newEntries, more, total, cursor, err := fetch(pageCursor, query)
+				post(func() {
+					if gen != generation {
+						return // The search was replaced while this page loaded
 					}
-					totalMsg := ""
-					if searchTotal != nil {
-						totalMsg = fmt.Sprintf(" (%d total)", *searchTotal)
+					loading = false
+					if err != nil {
+						status = fmt.Sprintf("Error loading: %v", err)
+					} else {
+						allEntries = append(allEntries, newEntries...)
+						loadedBytes += entriesSize(newEntries)
+						searchHasMore = more
+						searchTotal = total
+						if more && pageCursors.advance(pageCursor, cursor) {
+							searchHasMore = false
+						}
+						searchCursor = cursor
+						// Update searchMatches
+						startIdx := len(searchMatches)
+						for i := range newEntries {
+							searchMatches = append(searchMatches, startIdx+i)
+						}
+						applySort()
+						status = fmt.Sprintf("Loaded %d more results (%d%s)", len(newEntries), len(allEntries), totalInfo(len(allEntries), searchTotal, searchHasMore))
+						if more && !searchHasMore {
+							status = errCursorLoop.Error()
+						}
 					}
-					status = fmt.Sprintf("Loaded %d more results%s", len(newEntries), totalMsg)
-				}
-				loading = false
-				renderScreen()
-
-				// Clear status after 2 seconds
-				go func() {
-					time.Sleep(2 * time.Second)
-					status = ""
 					renderScreen()
-				}()
+					if err == nil {
+						prefetch() // Keep filling the buffer ahead
+					}
+
+					// Clear status after 2 seconds
+					clearStatusAfter(2 * time.Second)
+				})
 			}()
 			return
 		}
@@ -650,48 +1070,158 @@ func runInteractiveMode(entries []map[string]any, withColor bool, hasMore bool,
 		status = "Loading more..."
 		renderScreen()
 
+		gen, pageCursor := generation, currentCursor
 		go func() {
-			newEntries, more, total, cursor, err := fetcher(currentCursor, "")
-			if err != nil {
-				status = fmt.Sprintf("Error loading: %v", err)
-			} else {
-				allEntries = append(allEntries, newEntries...)
-				hasNextPage = more
-				totalAvailable = total
-				currentCursor = cursor
-				status = fmt.Sprintf("Loaded %d new entries", len(newEntries))
-			}
-			loading = false
-			renderScreen()
-
-			// Clear status after 2 seconds
-			go func() {
-				time.Sleep(2 * time.Second)
-				status = ""
+			newEntries, more, total, cursor, err := fetch(pageCursor, "")
+			post(func() {
+				if gen != generation {
+					return // The view was reloaded while this page loaded
+				}
+				loading = false
+				if err != nil {
+					status = fmt.Sprintf("Error loading: %v", err)
+				} else {
+					allEntries = append(allEntries, newEntries...)
+					loadedBytes += entriesSize(newEntries)
+					hasNextPage = more
+					totalAvailable = total
+					applySort()
+					status = fmt.Sprintf("Loaded %d new entries", len(newEntries))
+					if more && pageCursors.advance(pageCursor, cursor) {
+						hasNextPage = false
+						status = errCursorLoop.Error()
+					}
+					currentCursor = cursor
+				}
 				renderScreen()
-			}()
+				if err == nil {
+					prefetch() // Keep filling the buffer ahead
+				}
+
+				// Clear status after 2 seconds
+				clearStatusAfter(2 * time.Second)
+			})
 		}()
 	}
 
-	renderScreen()
-
-	// Handle resize signals in background
-	go func() {
-		for range sigwinch {
-			renderScreen()
-		}
-	}()
-
-	// Read input
+	// startAutoRefresh reloads the view every interval from now on. The
+	// ticker only posts ticks; the refresh itself runs on the input loop like
+	// every other state change.
+	startAutoRefresh := func(interval time.Duration) {
+		autoRefresh = true
+		stop := make(chan struct{})
+		stopAutoRefresh = stop
+		refresh := func() {
+			if stopAutoRefresh != stop {
+				return // A tick from before auto-refresh was toggled off
+			}
+			// Pause while loading, reading an entry, or scrolled away from the newest
+			if loading || expanded[currentIdx] || currentIdx != newestIdx() {
+				renderScreen()
+				return
+			}
+			if searchActive {
+				performSearch(searchQuery, true)
+			} else {
+				reloadWithDateFilter(activeStartTime, activeEndTime, true)
+			}
+		}
+		go func() {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-stop:
+					return
+				case <-ticker.C:
+					post(refresh)
+				}
+			}
+		}()
+	}
+
+	// --follow opens the viewer already following
+	if ctx.Live > 0 {
+		live = true
+		startAutoRefresh(ctx.Live)
+	}
+
+	renderScreen()
+	prefetch()
+
+	// Handle resize signals in background
+	go func() {
+		for range sigwinch {
+			post(renderScreen)
+		}
+	}()
+
+	// Read input. Prompts read from keys too, never from the terminal
+	// directly, since the key reader may already be waiting on it.
+	keys := newKeyStream(keyboard)
 	buf := make([]byte, 6)
 	for {
-		n, err := os.Stdin.Read(buf)
+		if !keys.buffered() {
+			select {
+			case update := <-updates:
+				update()
+				continue
+			case chunk := <-keys.chunks:
+				keys.pending = chunk // nil once input ends, so Read reports EOF
+			}
+		}
+		n, err := keys.Read(buf)
 		if err != nil {
 			break
 		}
 
 		input := buf[:n]
 
+		// While the actions menu is open it takes the keys. Choosing an
+		// action (Enter, or the action's own key) closes it and handles the
+		// action's key as if it had been pressed.
+		if menuOpen {
+			chosen := -1
+			switch {
+			case input[0] == 'j' || (n == 3 && input[0] == 27 && input[1] == 91 && input[2] == 66):
+				menuIdx = min(menuIdx+1, len(entryActions)-1)
+			case input[0] == 'k' || (n == 3 && input[0] == 27 && input[1] == 91 && input[2] == 65):
+				menuIdx = max(menuIdx-1, 0)
+			case input[0] == 13 || input[0] == 10:
+				chosen = menuIdx
+			case (input[0] == 27 && n == 1) || input[0] == 'q' || input[0] == '.':
+				menuOpen = false
+			default:
+				chosen = slices.IndexFunc(entryActions, func(a entryAction) bool { return a.key != 0 && a.key == input[0] })
+			}
+			if chosen < 0 {
+				renderScreen()
+				continue
+			}
+			menuOpen = false
+			if entryActions[chosen].key == 0 {
+				// Menu-only action: copy a curl command for the context
+				if len(allEntries) == 0 {
+					renderScreen()
+					continue
+				}
+				window := ctx.ContextWindow
+				if window <= 0 {
+					window = defaultContextWindow
+				}
+				if t, ok := tailstream.LogEntry(allEntries[currentIdx]).Timestamp(); ok {
+					fmt.Print(osc52(contextCurl(ctx.Endpoint, activeQuery, t, window)))
+					status = fmt.Sprintf("Copied a curl command for ±%s around the entry", window)
+				} else {
+					status = "Current entry has no timestamp"
+				}
+				renderScreen()
+				continue
+			}
+			input = []byte{entryActions[chosen].key}
+			n = 1
+		}
+
 		// Handle different key codes
 		switch {
 		case input[0] == 'q' || input[0] == 'Q':
@@ -702,50 +1232,446 @@ func runInteractiveMode(entries []map[string]any, withColor bool, hasMore bool,
 		case input[0] == 27 && n == 1:
 			// Escape key (plain, not part of arrow sequence) - clear search
 			if searchQuery != "" {
-				performSearch("") // Empty search clears filter
+				performSearch("", false) // Empty search clears filter
 				renderScreen()
 			}
 
-		case input[0] == '/':
-			// Search mode - read search query
+		case input[0] == '/' && expanded[currentIdx]:
+			// Search within the expanded entry's JSON (distinct from server-side search)
 			fmt.Print("\033[2J\033[H") // Clear screen
-			// Restore terminal for input
 			runCmd("stty", "echo", "icanon")
-			fmt.Print("Search: ")
-			scanner := bufio.NewScanner(os.Stdin)
+			fmt.Print("Find in entry: ")
+			scanner := bufio.NewScanner(keys)
 			if scanner.Scan() {
-				query := scanner.Text()
-				performSearch(query)
+				entrySearchTerm = strings.TrimSpace(scanner.Text())
 			}
-			// Restore raw mode
 			runCmd("stty", "-echo", "-icanon")
+
+			if entrySearchTerm != "" {
+				jsonBytes, _ := json.MarshalIndent(allEntries[currentIdx], "  ", "  ")
+				jsonLines := strings.Split(string(jsonBytes), "\n")
+				if idx := findLineMatch(jsonLines, entrySearchTerm, expandedScrollOffset[currentIdx], true); idx >= 0 {
+					expandedScrollOffset[currentIdx] = idx
+					status = fmt.Sprintf("Found '%s' at line %d (n/N: next/prev)", entrySearchTerm, idx+1)
+				} else {
+					status = fmt.Sprintf("'%s' not found in entry", entrySearchTerm)
+				}
+			}
+			renderScreen()
+
+		case input[0] == '/':
+			// Search mode - read search query (↑/↓ recall earlier searches, Esc cancels).
+			// Loaded entries are narrowed as you type; Enter searches the server.
+			fmt.Print("\033[2J\033[H") // Clear screen
+			texts := searchTexts(allEntries)
+			preview := func(query string) {
+				matches := localMatches(texts, query)
+				var b strings.Builder
+				b.WriteString("\033[2;1H\033[J") // Below the prompt
+				summary := fmt.Sprintf("%d of %d loaded entries match - Enter: search the server, Esc: cancel", len(matches), len(allEntries))
+				b.WriteString(truncateLineWidth(style(summary, "90", withColor), termWidth))
+				b.WriteString("\033[0m\033[K\n")
+				for _, i := range matches[:min(len(matches), max(termHeight-3, 0))] {
+					b.WriteString(truncateLineWidth("  "+collapseLines(formatEntry(allEntries[i], withColor), withColor), termWidth))
+					b.WriteString("\033[0m\033[K\n")
+				}
+				b.WriteString("\033[1;1H") // Back to the prompt
+				fmt.Print(b.String())
+			}
+			if query, ok := readLineLive(keys, os.Stdout, "Search: ", searchHistory, preview); ok {
+				searchHistory = addHistory(searchHistory, query)
+				appendSearchHistory(query)
+				performSearch(query, false)
+			}
 			renderScreen()
 
 		case input[0] == 'f' || input[0] == 'F':
-			// Filter by date range
+			// Filter by date range (↑/↓ recall earlier times, Esc cancels)
 			fmt.Print("\033[2J\033[H") // Clear screen
-			// Restore terminal for input
-			runCmd("stty", "echo", "icanon")
 			fmt.Println("Date Range Filter")
 			fmt.Println("Examples: -1h, -30m, -24h, 2025-01-01")
 			fmt.Println("Leave both blank to clear filters")
-			fmt.Print("Start time: ")
-			startScanner := bufio.NewScanner(os.Stdin)
-			startTime := ""
-			if startScanner.Scan() {
-				startTime = strings.TrimSpace(startScanner.Text())
-			}
-			fmt.Print("End time (optional): ")
-			endScanner := bufio.NewScanner(os.Stdin)
-			endTime := ""
-			if endScanner.Scan() {
-				endTime = strings.TrimSpace(endScanner.Text())
-			}
-			// Restore raw mode
-			runCmd("stty", "-echo", "-icanon")
+			startTime, ok := readLine(keys, os.Stdout, "Start time: ", dateHistory)
+			if !ok {
+				renderScreen()
+				break
+			}
+			endTime, ok := readLine(keys, os.Stdout, "End time (optional): ", dateHistory)
+			if !ok {
+				renderScreen()
+				break
+			}
+			startTime, endTime = strings.TrimSpace(startTime), strings.TrimSpace(endTime)
+			dateHistory = addHistory(addHistory(dateHistory, startTime), endTime)
 
 			// Apply the filter dynamically
-			reloadWithDateFilter(startTime, endTime)
+			reloadWithDateFilter(startTime, endTime, false)
+
+		case input[0] == ':':
+			// Go to entry number (1-based)
+			fmt.Print("\033[2J\033[H") // Clear screen
+			runCmd("stty", "echo", "icanon")
+			fmt.Printf("Go to entry (1-%d): ", len(allEntries))
+			scanner := bufio.NewScanner(keys)
+			if scanner.Scan() {
+				value := strings.TrimSpace(scanner.Text())
+				if num, err := strconv.Atoi(value); err == nil {
+					currentIdx = clampIdx(num - 1)
+					status = fmt.Sprintf("Jumped to entry %d", currentIdx+1)
+				} else if value != "" {
+					status = fmt.Sprintf("Invalid entry number: %s", value)
+				}
+			}
+			runCmd("stty", "-echo", "-icanon")
+			renderScreen()
+
+		case input[0] == 'x':
+			// Hide the current entry from the view (client-side only)
+			if len(allEntries) > 1 {
+				hidden := allEntries[currentIdx]
+				hiddenEntries = append(hiddenEntries, hiddenEntry{idx: currentIdx, entry: hidden})
+				allEntries = append(allEntries[:currentIdx:currentIdx], allEntries[currentIdx+1:]...)
+				loadedBytes -= entriesSize([]map[string]any{hidden})
+				expanded = shiftIndexKeys(expanded, currentIdx, -1)
+				marked = shiftIndexKeys(marked, currentIdx, -1)
+				compact = shiftIndexKeys(compact, currentIdx, -1)
+				expandedScrollOffset = shiftIndexKeys(expandedScrollOffset, currentIdx, -1)
+				horizontalScrollOffset = shiftIndexKeys(horizontalScrollOffset, currentIdx, -1)
+				currentIdx = clampIdx(currentIdx)
+				status = fmt.Sprintf("Hid entry (%d hidden, X to restore)", len(hiddenEntries))
+			} else {
+				status = "Cannot hide the last remaining entry"
+			}
+			renderScreen()
+
+		case input[0] == 'X':
+			// Restore the most recently hidden entry
+			if len(hiddenEntries) > 0 {
+				last := hiddenEntries[len(hiddenEntries)-1]
+				hiddenEntries = hiddenEntries[:len(hiddenEntries)-1]
+				idx := last.idx
+				if idx > len(allEntries) {
+					idx = len(allEntries)
+				}
+				allEntries = append(allEntries[:idx], append([]map[string]any{last.entry}, allEntries[idx:]...)...)
+				loadedBytes += entriesSize([]map[string]any{last.entry})
+				expanded = shiftIndexKeys(expanded, idx, 1)
+				marked = shiftIndexKeys(marked, idx, 1)
+				compact = shiftIndexKeys(compact, idx, 1)
+				expandedScrollOffset = shiftIndexKeys(expandedScrollOffset, idx, 1)
+				horizontalScrollOffset = shiftIndexKeys(horizontalScrollOffset, idx, 1)
+				currentIdx = idx
+				applySort() // Into its sorted place, if the view was sorted since
+				status = fmt.Sprintf("Restored entry (%d still hidden)", len(hiddenEntries))
+			} else {
+				status = "No hidden entries to restore"
+			}
+			renderScreen()
+
+		case input[0] == 'm':
+			// Toggle a mark on the current entry
+			if marked[currentIdx] {
+				delete(marked, currentIdx)
+			} else {
+				marked[currentIdx] = true
+			}
+			status = fmt.Sprintf("%d marked", len(marked))
+			if len(marked) == 2 {
+				status += " (= to diff)"
+			}
+			renderScreen()
+
+		case input[0] == '=':
+			// Diff the two marked entries
+			if len(marked) != 2 {
+				status = fmt.Sprintf("Mark exactly two entries with m to diff (%d marked)", len(marked))
+				renderScreen()
+				break
+			}
+			var pair []int
+			for idx := range marked {
+				pair = append(pair, idx)
+			}
+			sort.Ints(pair)
+			diffs := diffEntries(allEntries[pair[0]], allEntries[pair[1]], "")
+
+			var screen strings.Builder
+			screen.WriteString("\033[2J\033[H") // Clear screen
+			screen.WriteString(fmt.Sprintf("Diff of entry %d (-) and entry %d (+): %d difference(s)\n\n", pair[0]+1, pair[1]+1, len(diffs)))
+			maxLines := getTerminalHeight() - 4
+			for i, d := range diffs {
+				if i >= maxLines {
+					screen.WriteString(style(fmt.Sprintf("... %d more\n", len(diffs)-i), "90", withColor))
+					break
+				}
+				screen.WriteString(truncateLineWidth(formatDiffLine(d, withColor), termWidth))
+				screen.WriteString("\033[0m\n")
+			}
+			if len(diffs) == 0 {
+				screen.WriteString("Entries are identical\n")
+			}
+			screen.WriteString("\nPress any key to return...")
+			fmt.Print(screen.String())
+			keys.Read(make([]byte, 6))
+			renderScreen()
+
+		case input[0] == 'r':
+			// Reload the current view (same date range or search), keeping position
+			if !loading {
+				if searchActive {
+					performSearch(searchQuery, true)
+				} else {
+					reloadWithDateFilter(activeStartTime, activeEndTime, true)
+				}
+			}
+
+		case input[0] == 'y':
+			// Show a reproducible command line for the current view
+			var searches []string
+			if searchActive {
+				searches = []string{searchQuery}
+			}
+			fmt.Print("\033[2J\033[H") // Clear screen
+			fmt.Println("Reproduce this view with:")
+			fmt.Println()
+			fmt.Println(buildQueryCommand(ctx.BaseURL, ctx.StreamID, activeQuery, searches))
+			fmt.Println()
+			fmt.Print("Press any key to return...")
+			keys.Read(make([]byte, 6))
+			renderScreen()
+
+		case input[0] == 's':
+			// Save the loaded entries (as currently searched and filtered) to a file
+			fmt.Print("\033[2J\033[H") // Clear screen
+			fmt.Printf("Save %d loaded entries (Esc cancels)\n", len(allEntries))
+			path, ok := readLine(keys, os.Stdout, "File: ", nil)
+			path = strings.TrimSpace(path)
+			if !ok || path == "" {
+				renderScreen()
+				break
+			}
+			guess := exportFormatFor(path)
+			format, ok := readLine(keys, os.Stdout, fmt.Sprintf("Format (%s) [%s]: ", strings.Join(exportFormats, "/"), guess), nil)
+			if !ok {
+				renderScreen()
+				break
+			}
+			if format = strings.ToLower(strings.TrimSpace(format)); format == "" {
+				format = guess
+			}
+			var buf bytes.Buffer
+			err := writeEntries(&buf, allEntries, format)
+			if err == nil {
+				err = os.WriteFile(path, buf.Bytes(), 0644)
+			}
+			if err != nil {
+				status = fmt.Sprintf("Save failed: %v", err)
+			} else {
+				status = fmt.Sprintf("Saved %d entries to %s (%s)", len(allEntries), path, format)
+			}
+			renderScreen()
+
+		case input[0] == 'a':
+			// Toggle auto-refresh
+			if !autoRefresh {
+				startAutoRefresh(autoRefreshInterval)
+				status = fmt.Sprintf("Auto-refresh on (every %s)", autoRefreshInterval)
+			} else {
+				autoRefresh, live = false, false
+				close(stopAutoRefresh)
+				stopAutoRefresh = nil
+				status = "Auto-refresh off"
+			}
+			renderScreen()
+
+		case input[0] == 'c':
+			// Reload with a window around the current entry's timestamp
+			if loading || len(allEntries) == 0 {
+				break
+			}
+			t, ok := tailstream.LogEntry(allEntries[currentIdx]).Timestamp()
+			if !ok {
+				status = "Current entry has no timestamp"
+				renderScreen()
+				break
+			}
+			window := ctx.ContextWindow
+			if window <= 0 {
+				window = defaultContextWindow
+			}
+			reloadWithDateFilter(
+				t.Add(-window).UTC().Format(time.RFC3339Nano),
+				t.Add(window).UTC().Format(time.RFC3339Nano),
+				false,
+			)
+
+		case input[0] == 'o':
+			// Open the current entry in the web UI
+			if len(allEntries) == 0 {
+				break
+			}
+			link, err := buildEntryURL(ctx.EntryURL, ctx.BaseURL, ctx.StreamID, allEntries[currentIdx])
+			if err == nil {
+				err = openBrowser(link)
+			}
+			if err != nil {
+				status = fmt.Sprintf("Cannot open entry: %v", err)
+			} else {
+				status = "Opened " + link
+			}
+			renderScreen()
+
+		case input[0] == 'J':
+			// Toggle the current entry between its log line and one-line JSON
+			compact[currentIdx] = !compact[currentIdx]
+			if compact[currentIdx] {
+				expanded[currentIdx] = false
+				delete(expandedScrollOffset, currentIdx)
+			} else {
+				delete(compact, currentIdx)
+			}
+			renderScreen()
+
+		case input[0] == 'w':
+			// Toggle wrapping of long lines
+			wrapLines = !wrapLines
+			if wrapLines {
+				status = "Line wrapping on"
+			} else {
+				status = "Line wrapping off"
+			}
+			renderScreen()
+
+		case input[0] == 'S':
+			// Sort the loaded entries by a field; the same field again flips
+			// the direction, an empty field stops sorting new pages
+			fmt.Print("\033[2J\033[H") // Clear screen
+			fmt.Println("Sort loaded entries by field (empty clears the sort, Esc cancels)")
+			field, ok := readLine(keys, os.Stdout, "Field: ", sortHistory)
+			if !ok {
+				renderScreen()
+				break
+			}
+			field = strings.TrimSpace(field)
+			sortHistory = addHistory(sortHistory, field)
+			switch {
+			case field == "":
+				sortField, sortDesc = "", false
+				status = "Sort cleared (r reloads in the original order)"
+			case field == sortField:
+				sortDesc = !sortDesc
+			default:
+				sortField, sortDesc = field, false
+			}
+			if sortField != "" {
+				applySort()
+				status = fmt.Sprintf("Sorted %d loaded entries by %s", len(allEntries), sortText(sortField, sortDesc))
+			}
+			renderScreen()
+
+		case input[0] == 'v':
+			// Pick the fields shown on each line from those in the loaded
+			// entries, as a checklist toggled by number or name
+			available := discoverFields(allEntries)
+			problem := ""
+			for {
+				fmt.Print("\033[2J\033[H") // Clear screen
+				fmt.Println("Fields shown on each line (numbers or names toggle, Enter when done, Esc cancels)")
+				fmt.Println()
+				for n, name := range available {
+					box := "[ ]"
+					if slices.Contains(shownFields, name) {
+						box = style("[x]", "32", withColor)
+					}
+					fmt.Printf("%s %3d  %s\n", box, n+1, renderSafe(name))
+				}
+				fmt.Println()
+				if problem != "" {
+					fmt.Println(style(problem, "33", withColor))
+				}
+				answer, ok := readLine(keys, os.Stdout, "Toggle: ", nil)
+				if !ok || strings.TrimSpace(answer) == "" {
+					break
+				}
+				var err error
+				shownFields, err = toggleFields(shownFields, available, answer)
+				problem = ""
+				if err != nil {
+					problem = err.Error()
+				}
+			}
+			if len(shownFields) > 0 {
+				status = "Showing " + strings.Join(shownFields, ", ")
+			} else {
+				status = "Showing no extra fields"
+			}
+			renderScreen()
+
+		case input[0] == '.':
+			// Open the actions menu for the current entry
+			menuOpen = true
+			menuIdx = 0
+			renderScreen()
+
+		case input[0] == 'Y':
+			// Copy the current entry's JSON to the clipboard
+			if len(allEntries) == 0 {
+				break
+			}
+			raw, _ := json.MarshalIndent(allEntries[currentIdx], "", "  ")
+			fmt.Print(osc52(string(raw)))
+			status = "Copied the entry's JSON to the clipboard"
+			renderScreen()
+
+		case input[0] == '*':
+			// Search for the value of one of the current entry's fields
+			if len(allEntries) == 0 {
+				break
+			}
+			fmt.Print("\033[2J\033[H") // Clear screen
+			fmt.Println("Search for the value of a field of this entry (Esc cancels)")
+			field, ok := readLine(keys, os.Stdout, "Field: ", nil)
+			field = strings.TrimSpace(field)
+			if !ok || field == "" {
+				renderScreen()
+				break
+			}
+			value := tailstream.LogEntry(allEntries[currentIdx]).FieldString(field)
+			if value == "" {
+				status = fmt.Sprintf("Entry has no %s", field)
+				renderScreen()
+				break
+			}
+			searchHistory = addHistory(searchHistory, value)
+			appendSearchHistory(value)
+			performSearch(value, false)
+
+		case input[0] == 'i':
+			// Toggle byte-size stats in the footer
+			showStats = !showStats
+			renderScreen()
+
+		case input[0] == 't':
+			// Toggle the time gap between consecutive entries
+			showDeltas = !showDeltas
+			renderScreen()
+
+		case (input[0] == 'n' || input[0] == 'N') && expanded[currentIdx] && entrySearchTerm != "":
+			// Next/previous match within the expanded entry
+			jsonBytes, _ := json.MarshalIndent(allEntries[currentIdx], "  ", "  ")
+			jsonLines := strings.Split(string(jsonBytes), "\n")
+			forward := input[0] == 'n'
+			from := expandedScrollOffset[currentIdx] + 1
+			if !forward {
+				from = expandedScrollOffset[currentIdx] - 1
+			}
+			if idx := findLineMatch(jsonLines, entrySearchTerm, from, forward); idx >= 0 {
+				expandedScrollOffset[currentIdx] = idx
+				status = fmt.Sprintf("Found '%s' at line %d", entrySearchTerm, idx+1)
+			} else {
+				status = fmt.Sprintf("'%s' not found in entry", entrySearchTerm)
+			}
+			renderScreen()
 
 		case input[0] == 'n':
 			// Next entry (when filtered, just go down)
@@ -779,9 +1705,7 @@ func runInteractiveMode(entries []map[string]any, withColor bool, hasMore bool,
 						horizontalScrollOffset[currentIdx] = 0
 					}
 					delete(horizontalScrollOffset, oldIdx) // Clean up old entry to save memory
-					if currentIdx >= len(allEntries)-5 && hasNextPage && !loading {
-						loadNextPage()
-					}
+					prefetch()
 					renderScreen()
 				}
 			} else {
@@ -795,10 +1719,8 @@ func runInteractiveMode(entries []map[string]any, withColor bool, hasMore bool,
 					}
 					delete(horizontalScrollOffset, oldIdx) // Clean up old entry to save memory
 
-					// Auto-load next page when near the end (within 5 entries)
-					if currentIdx >= len(allEntries)-5 && hasNextPage && !loading {
-						loadNextPage()
-					}
+					// Auto-load the next page when near the end
+					prefetch()
 
 					renderScreen()
 				}
@@ -833,47 +1755,76 @@ func runInteractiveMode(entries []map[string]any, withColor bool, hasMore bool,
 					}
 					delete(horizontalScrollOffset, oldIdx) // Clean up old entry to save memory
 					renderScreen()
+				} else if searchActive {
+					// The API only returns forward cursors, and search always
+					// starts at the first match, so there is nothing earlier to load
+					first := "newest"
+					if ctx.SortDir == "asc" {
+						first = "oldest"
+					}
+					status = fmt.Sprintf("Top of search results (search starts at the %s match)", first)
+					renderScreen()
 				}
 			}
 
 		case n == 3 && input[0] == 27 && input[1] == 91 && input[2] == 67:
 			// Right arrow - scroll right horizontally
 			// Get the actual line content to calculate max offset
-			var lineContent string
-			if expanded[currentIdx] {
-				jsonBytes, _ := json.MarshalIndent(allEntries[currentIdx], "  ", "  ")
-				jsonLines := strings.Split(string(jsonBytes), "\n")
-				if len(jsonLines) > 0 {
-					// Use the longest line in expanded view
-					for _, jsonLine := range jsonLines {
-						if len(jsonLine) > len(lineContent) {
-							lineContent = jsonLine
-						}
-					}
+			var lines []string
+			if globalHScroll {
+				// Every entry on screen scrolls, so the longest of them sets the limit
+				for i := max(0, currentIdx-viewportHeight); i < min(len(allEntries), currentIdx+viewportHeight); i++ {
+					lines = append(lines, style("▶ ", "36", withColor)+collapsedLine(i))
 				}
+			} else if expanded[currentIdx] {
+				jsonBytes, _ := json.MarshalIndent(allEntries[currentIdx], "  ", "  ")
+				lines = strings.Split(string(jsonBytes), "\n")
 			} else {
-				lineContent = fmt.Sprintf("%s%s", style("▶ ", "36", withColor), formatEntry(allEntries[currentIdx], withColor))
-			}
-
-			// Calculate max offset
-			maxOffset := len(lineContent) - termWidth
-			if maxOffset < 0 {
-				maxOffset = 0
+				lines = []string{fmt.Sprintf("%s%s", style("▶ ", "36", withColor), collapsedLine(currentIdx))}
 			}
 
 			// Only scroll if we haven't reached the end
-			newOffset := horizontalScrollOffset[currentIdx] + 10
-			if newOffset > maxOffset {
-				newOffset = maxOffset
+			maxOffset := maxHorizontalOffset(lines, termWidth)
+			if globalHScroll {
+				globalHOffset = min(globalHOffset+10, maxOffset)
+			} else {
+				horizontalScrollOffset[currentIdx] = min(horizontalScrollOffset[currentIdx]+10, maxOffset)
 			}
-			horizontalScrollOffset[currentIdx] = newOffset
 			renderScreen()
 
 		case n == 3 && input[0] == 27 && input[1] == 91 && input[2] == 68:
 			// Left arrow - scroll left horizontally
-			horizontalScrollOffset[currentIdx] -= 10
-			if horizontalScrollOffset[currentIdx] < 0 {
-				horizontalScrollOffset[currentIdx] = 0
+			if globalHScroll {
+				globalHOffset = max(globalHOffset-10, 0)
+			} else {
+				horizontalScrollOffset[currentIdx] = max(horizontalScrollOffset[currentIdx]-10, 0)
+			}
+			renderScreen()
+
+		case input[0] == '+' || input[0] == '-':
+			// Raise or lower the auto-load threshold, starting from the
+			// one currently in effect
+			ahead := prefetchAhead(ctx.PrefetchThreshold, ctx.Prefetch, ctx.PerPage, viewportHeight)
+			if input[0] == '+' {
+				ahead += prefetchStep
+			} else {
+				ahead = max(ahead-prefetchStep, 1)
+			}
+			ctx.PrefetchThreshold = ahead
+			status = fmt.Sprintf("Loading the next page with %d entries left", ahead)
+			prefetch()
+			renderScreen()
+
+		case input[0] == 'H':
+			// Toggle horizontal scrolling of all entries together, starting
+			// from wherever the current entry is scrolled to
+			globalHScroll = !globalHScroll
+			if globalHScroll {
+				globalHOffset = horizontalScrollOffset[currentIdx]
+				status = "Horizontal scroll applies to all entries"
+			} else {
+				horizontalScrollOffset[currentIdx] = globalHOffset
+				status = "Horizontal scroll per entry"
 			}
 			renderScreen()
 
@@ -887,16 +1838,8 @@ func runInteractiveMode(entries []map[string]any, withColor bool, hasMore bool,
 				currentIdx = newIdx
 				renderScreen()
 
-				// Auto-load next page when near the end
-				if searchActive {
-					if currentIdx >= len(allEntries)-viewportHeight && searchHasMore && !loading {
-						loadNextPage()
-					}
-				} else {
-					if currentIdx >= len(allEntries)-viewportHeight && hasNextPage && !loading {
-						loadNextPage()
-					}
-				}
+				// Auto-load the next page when near the end
+				prefetch()
 			}
 
 		case input[0] == 'u' || input[0] == 'U':
@@ -941,16 +1884,8 @@ func runInteractiveMode(entries []map[string]any, withColor bool, hasMore bool,
 					currentIdx = newIdx
 					renderScreen()
 
-					// Auto-load next page when near the end
-					if searchActive {
-						if currentIdx >= len(allEntries)-viewportHeight && searchHasMore && !loading {
-							loadNextPage()
-						}
-					} else {
-						if currentIdx >= len(allEntries)-viewportHeight && hasNextPage && !loading {
-							loadNextPage()
-						}
-					}
+					// Auto-load the next page when near the end
+					prefetch()
 				}
 
 			case input[2] == 72: // Home
@@ -962,9 +1897,27 @@ func runInteractiveMode(entries []map[string]any, withColor bool, hasMore bool,
 				renderScreen()
 			}
 
+		case input[0] == 'E':
+			// Expand every loaded entry
+			for i := range allEntries {
+				expanded[i] = true
+				expandedScrollOffset[i] = 0
+			}
+			compact = make(map[int]bool)
+			status = fmt.Sprintf("Expanded %d entries (C to collapse all)", len(allEntries))
+			renderScreen()
+
+		case input[0] == 'C':
+			// Collapse every entry
+			expanded = make(map[int]bool)
+			expandedScrollOffset = make(map[int]int)
+			status = "Collapsed all entries"
+			renderScreen()
+
 		case input[0] == 13 || input[0] == 10 || input[0] == 32:
 			// Enter or Space - toggle expanded
 			expanded[currentIdx] = !expanded[currentIdx]
+			delete(compact, currentIdx)
 			// Reset scroll offset when toggling
 			if !expanded[currentIdx] {
 				delete(expandedScrollOffset, currentIdx)
@@ -975,3 +1928,528 @@ func runInteractiveMode(entries []map[string]any, withColor bool, hasMore bool,
 		}
 	}
 }
+
+// fetchLogs requests the first page of logs for params, as a reload from
+// the interactive viewer does. Errors are worded for the status line.
+func fetchLogs(ctx *InteractiveContext, params url.Values) (*tailstream.LogResponse, *url.URL, error) {
+	req, err := ctx.API.NewLogsRequest(context.Background(), ctx.StreamID, params, "")
+	if err != nil {
+		return nil, nil, fmt.Errorf("Request error: %v", err)
+	}
+	resp, err := ctx.API.Do(req)
+	var statusErr *tailstream.StatusError
+	if errors.As(err, &statusErr) {
+		return nil, nil, fmt.Errorf("Request failed: %s", statusErr.Status)
+	} else if err != nil {
+		return nil, nil, fmt.Errorf("Request error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := tailstream.DecodeBody(resp)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Decode error: %v", err)
+	}
+
+	var payload tailstream.LogResponse
+	decoder := json.NewDecoder(body)
+	decoder.UseNumber() // Keep large IDs exact
+	if err := decoder.Decode(&payload); err != nil {
+		return nil, nil, fmt.Errorf("Parse error: %v", err)
+	}
+	return &payload, req.URL, nil
+}
+
+// maxHistory is how many entries a prompt history keeps
+const maxHistory = 100
+
+// addHistory appends a prompt input to history, skipping blanks and
+// immediate repeats and dropping the oldest entries past maxHistory
+func addHistory(history []string, entry string) []string {
+	if strings.TrimSpace(entry) == "" || (len(history) > 0 && history[len(history)-1] == entry) {
+		return history
+	}
+	history = append(history, entry)
+	if len(history) > maxHistory {
+		history = history[len(history)-maxHistory:]
+	}
+	return history
+}
+
+// readLine reads a line of input in raw mode, echoing it to out after prompt.
+// ↑/↓ step through history (most recent first), Backspace deletes and Ctrl+U
+// clears the line. It returns false when the prompt is cancelled with Esc or
+// input ends.
+func readLine(in io.Reader, out io.Writer, prompt string, history []string) (string, bool) {
+	return readLineLive(in, out, prompt, history, nil)
+}
+
+// readLineLive is readLine with onChange called with the current line before
+// the prompt is drawn, including once up front, so callers can update a
+// preview as the user types. onChange must leave the cursor on the prompt row.
+func readLineLive(in io.Reader, out io.Writer, prompt string, history []string, onChange func(line string)) (string, bool) {
+	var line []byte
+	draft := ""         // Line being typed before browsing history
+	pos := len(history) // History position; len(history) = the draft
+	redraw := func() {
+		if onChange != nil {
+			onChange(string(line))
+		}
+		fmt.Fprintf(out, "\r\033[K%s%s", prompt, line)
+	}
+	recall := func(to int) {
+		if to < 0 || to > len(history) {
+			return
+		}
+		if pos == len(history) {
+			draft = string(line)
+		}
+		pos = to
+		if pos == len(history) {
+			line = []byte(draft)
+		} else {
+			line = []byte(history[pos])
+		}
+		redraw()
+	}
+
+	redraw()
+	buf := make([]byte, 64)
+	for {
+		n, err := in.Read(buf)
+		if n == 0 && err != nil {
+			return "", false
+		}
+		chunk := buf[:n]
+		if n == 1 && chunk[0] == 27 {
+			// A lone Escape (not an arrow key sequence) cancels
+			fmt.Fprint(out, "\r\n")
+			return "", false
+		}
+		for i := 0; i < len(chunk); i++ {
+			switch c := chunk[i]; {
+			case c == '\r' || c == '\n':
+				fmt.Fprint(out, "\r\n")
+				return string(line), true
+			case c == 27 && i+2 < len(chunk) && chunk[i+1] == '[':
+				switch chunk[i+2] {
+				case 'A':
+					recall(pos - 1)
+				case 'B':
+					recall(pos + 1)
+				}
+				i += 2
+			case c == 127 || c == 8:
+				if len(line) > 0 {
+					_, size := utf8.DecodeLastRune(line)
+					line = line[:len(line)-size]
+					redraw()
+				}
+			case c == 21: // Ctrl+U
+				line = line[:0]
+				redraw()
+			case c >= 32:
+				line = append(line, c)
+				redraw()
+			}
+		}
+	}
+}
+
+// localMatches returns the indices of the loaded entries containing query
+// (case-insensitive), for the live preview while typing a search. texts are
+// the entries' searchTexts, computed once per prompt rather than per
+// keystroke. An empty query matches everything.
+func localMatches(texts []string, query string) []int {
+	terms := normalizeQueries([]string{query})
+	matches := make([]int, 0, len(texts))
+	for i, text := range texts {
+		if textMatches(text, terms) {
+			matches = append(matches, i)
+		}
+	}
+	return matches
+}
+
+// totalInfo describes how the loaded entries relate to the server's total,
+// as a suffix for "N loaded". A nil total means the server didn't count the
+// matches (tail mode, i.e. no time range), which is said outright rather than
+// implied; a total that contradicts what's already loaded is treated as
+// unknown instead of displayed.
+func totalInfo(loaded int, total *int, more bool) string {
+	switch {
+	case total == nil && more:
+		return ", tail mode (no total), more available"
+	case total == nil:
+		return ", tail mode (no total)"
+	case *total >= loaded && (*total > loaded || !more):
+		return fmt.Sprintf(" of %d total", *total)
+	case more:
+		return ", more available"
+	default:
+		return ""
+	}
+}
+
+// collapseLines keeps the first line of a multi-line rendered entry,
+// followed by a dim count of the lines left out
+func collapseLines(line string, withColor bool) string {
+	first, rest, found := strings.Cut(line, "\n")
+	if !found {
+		return line
+	}
+	return first + style(fmt.Sprintf(" [+%d lines]", strings.Count(rest, "\n")+1), "90", withColor)
+}
+
+// uiChromeLines returns how many terminal rows the interactive UI reserves
+// around the entries: header, status, two separators, and footer. Short
+// terminals (or compactUI) get the compact layout with only header and footer.
+func uiChromeLines(termHeight int, compactUI bool) int {
+	if compactUI || termHeight < compactUIHeight {
+		return 2
+	}
+	return 5
+}
+
+// defaultEntryURL is the web UI deep-link pattern for a single log entry
+const defaultEntryURL = "{base_url}/streams/{stream_id}/logs/{id}"
+
+// buildEntryURL fills an entry_url pattern with the base URL, stream ID, and
+// the entry's id. The pattern falls back to defaultEntryURL when empty.
+func buildEntryURL(pattern, baseURL, streamID string, entry map[string]any) (string, error) {
+	id := tailstream.LogEntry(entry).String("id")
+	if id == "" {
+		return "", fmt.Errorf("entry has no id")
+	}
+	if pattern == "" {
+		pattern = defaultEntryURL
+	}
+	return strings.NewReplacer(
+		"{base_url}", strings.TrimRight(baseURL, "/"),
+		"{stream_id}", url.PathEscape(streamID),
+		"{id}", url.PathEscape(id),
+	).Replace(pattern), nil
+}
+
+// findLineMatch returns the index of the first line containing term
+// (case-insensitive), searching from the given line and wrapping around.
+// Returns -1 if no line matches.
+func findLineMatch(lines []string, term string, from int, forward bool) int {
+	if len(lines) == 0 || term == "" {
+		return -1
+	}
+	term = strings.ToLower(term)
+	for i := 0; i < len(lines); i++ {
+		idx := from + i
+		if !forward {
+			idx = from - i
+		}
+		idx = ((idx % len(lines)) + len(lines)) % len(lines)
+		if strings.Contains(strings.ToLower(lines[idx]), term) {
+			return idx
+		}
+	}
+	return -1
+}
+
+// shiftIndexKeys returns a copy of an index-keyed map adjusted for an entry
+// removed at (delta -1) or inserted at (delta +1) the given index
+func shiftIndexKeys[V any](m map[int]V, at int, delta int) map[int]V {
+	shifted := make(map[int]V, len(m))
+	for idx, v := range m {
+		switch {
+		case delta < 0 && idx == at:
+			continue // Removed entry
+		case idx > at || (delta > 0 && idx == at):
+			shifted[idx+delta] = v
+		default:
+			shifted[idx] = v
+		}
+	}
+	return shifted
+}
+
+// prefetchStep is how much the + and - keys change the auto-load threshold
+const prefetchStep = 10
+
+// nearEndEntries is how close to the last loaded entry the cursor gets
+// before the next page loads, without --prefetch or a threshold
+const nearEndEntries = 5
+
+// prefetchAhead returns how many entries below the cursor are kept loaded:
+// the explicit threshold when set, otherwise the given number of pages (at
+// least a screenful), otherwise just the last few entries
+func prefetchAhead(threshold, pages, perPage, viewport int) int {
+	if threshold > 0 {
+		return threshold
+	}
+	if pages > 0 {
+		return max(pages*perPage, viewport)
+	}
+	return nearEndEntries
+}
+
+// maxHorizontalOffset returns how far lines can scroll right before the
+// longest of them ends at the terminal's right edge
+func maxHorizontalOffset(lines []string, width int) int {
+	longest := 0
+	for _, line := range lines {
+		longest = max(longest, len(line))
+	}
+	return max(longest-width, 0)
+}
+
+// permuteIndexKeys returns a copy of an index-keyed map after the entries were
+// reordered so that new index i holds the entry previously at order[i]
+func permuteIndexKeys[V any](m map[int]V, order []int) map[int]V {
+	permuted := make(map[int]V, len(m))
+	for i, j := range order {
+		if v, ok := m[j]; ok {
+			permuted[i] = v
+		}
+	}
+	return permuted
+}
+
+// movedIndices inverts a reordering like permuteIndexKeys takes: for each
+// old index, the new index of the entry that was there
+func movedIndices(order []int) []int {
+	moved := make([]int, len(order))
+	for i, j := range order {
+		moved[j] = i
+	}
+	return moved
+}
+
+// entryAction is an item of the entry actions menu (. key), run by handling
+// its key. Key 0 marks an action only available from the menu.
+type entryAction struct {
+	key   byte
+	label string
+}
+
+// entryActions lists what can be done with the current entry, in menu order
+var entryActions = []entryAction{
+	{' ', "Expand / collapse"},
+	{'Y', "Copy JSON to clipboard"},
+	{0, "Copy curl command for its context"},
+	{'o', "Open in web UI"},
+	{'c', "Show entries around it"},
+	{'*', "Search for a field's value"},
+	{'m', "Mark / unmark"},
+	{'J', "Toggle one-line JSON"},
+	{'x', "Hide"},
+	{'s', "Save loaded entries"},
+}
+
+// actionsMenu renders the entry actions menu as the lines of a box, each of
+// the same width, with the selected action highlighted
+func actionsMenu(selected int, withColor bool) []string {
+	width := 0
+	for _, a := range entryActions {
+		width = max(width, utf8.RuneCountInString(a.label))
+	}
+	inner := width + 8 // Cursor, label, and a right-aligned key
+	const title = "─ Actions "
+	lines := []string{"┌" + title + strings.Repeat("─", inner+2-utf8.RuneCountInString(title)) + "┐"}
+	for i, a := range entryActions {
+		key := string(a.key)
+		switch a.key {
+		case 0:
+			key = ""
+		case ' ':
+			key = "Space"
+		}
+		item := fmt.Sprintf("%-*s %5s", width, a.label, key)
+		if i == selected {
+			item = style("▶ "+item, "36", withColor)
+		} else {
+			item = "  " + item
+		}
+		lines = append(lines, "│ "+item+" │")
+	}
+	hint := style(fmt.Sprintf("%-*s", inner, "j/k, Enter, Esc"), "90", withColor)
+	lines = append(lines, "│ "+hint+" │", "└"+strings.Repeat("─", inner+2)+"┘")
+	return lines
+}
+
+// osc52 returns the escape sequence asking the terminal to put text on the
+// system clipboard. It needs no clipboard tool and works over SSH; terminals
+// without OSC 52 support ignore it.
+func osc52(text string) string {
+	return "\033]52;c;" + base64.StdEncoding.EncodeToString([]byte(text)) + "\a"
+}
+
+// contextCurl returns a curl command fetching the entries within window of
+// t, with the view's other query params. The token is left as a variable so
+// it never ends up on the clipboard.
+func contextCurl(endpoint string, query url.Values, t time.Time, window time.Duration) string {
+	q := maps.Clone(query)
+	if q == nil {
+		q = url.Values{}
+	}
+	q.Set("start_time", strconv.FormatInt(t.Add(-window).UnixMilli(), 10))
+	q.Set("end_time", strconv.FormatInt(t.Add(window).UnixMilli(), 10))
+	return `curl -H "Authorization: Bearer $TAILSTREAM_TOKEN" ` + shellQuote(endpoint+"?"+q.Encode())
+}
+
+// toggleFields applies the v key's input to the shown fields: each word is a
+// 1-based number in available or a field name, added at the end when not yet
+// shown and removed when it is. Names needn't be in available, since the
+// loaded entries may not have every field yet. Words that are out-of-range
+// numbers are reported after the others are applied.
+func toggleFields(shown, available []string, input string) ([]string, error) {
+	var invalid []string
+	for _, word := range strings.Fields(input) {
+		name := word
+		if n, err := strconv.Atoi(word); err == nil {
+			if n < 1 || n > len(available) {
+				invalid = append(invalid, word)
+				continue
+			}
+			name = available[n-1]
+		}
+		if i := slices.Index(shown, name); i >= 0 {
+			shown = slices.Delete(shown, i, i+1)
+		} else {
+			shown = append(shown, name)
+		}
+	}
+	if len(invalid) > 0 {
+		return shown, fmt.Errorf("no field numbered %s (1-%d)", strings.Join(invalid, ", "), len(available))
+	}
+	return shown, nil
+}
+
+// sortText describes a client-side sort for the header and status line
+func sortText(field string, desc bool) string {
+	if desc {
+		return field + " ↓"
+	}
+	return field + " ↑"
+}
+
+// wrapLine splits line into rows of at most width visible characters.
+// ANSI escape sequences are kept intact and don't count towards the width.
+// Continuation rows start with indent.
+func wrapLine(line string, width int, indent string) []string {
+	if width <= len(indent) {
+		return []string{line}
+	}
+
+	var rows []string
+	var row strings.Builder
+	visible := 0
+	runes := []rune(line)
+	for i := 0; i < len(runes); i++ {
+		// Copy escape sequences through without counting them
+		if runes[i] == 27 && i+1 < len(runes) && runes[i+1] == '[' {
+			j := i + 2
+			for j < len(runes) && (runes[j] < '@' || runes[j] > '~') {
+				j++
+			}
+			if j >= len(runes) {
+				j = len(runes) - 1
+			}
+			row.WriteString(string(runes[i : j+1]))
+			i = j
+			continue
+		}
+		if visible == width {
+			rows = append(rows, row.String())
+			row.Reset()
+			row.WriteString(indent)
+			visible = len(indent)
+		}
+		row.WriteRune(runes[i])
+		visible++
+	}
+	return append(rows, row.String())
+}
+
+// entryDiff describes one differing field between two entries
+type entryDiff struct {
+	Path string // Dotted path to the field
+	Kind byte   // '-' only in the first entry, '+' only in the second, '~' changed
+	Old  any
+	New  any
+}
+
+// diffEntries recursively compares two entries and returns their differing
+// fields sorted by path. Nested objects are descended into; other values
+// (including arrays) are compared as a whole.
+func diffEntries(a, b map[string]any, prefix string) []entryDiff {
+	keys := make(map[string]bool, len(a)+len(b))
+	for k := range a {
+		keys[k] = true
+	}
+	for k := range b {
+		keys[k] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	var diffs []entryDiff
+	for _, k := range sorted {
+		path := prefix + k
+		av, inA := a[k]
+		bv, inB := b[k]
+		switch {
+		case !inB:
+			diffs = append(diffs, entryDiff{Path: path, Kind: '-', Old: av})
+		case !inA:
+			diffs = append(diffs, entryDiff{Path: path, Kind: '+', New: bv})
+		default:
+			am, aIsMap := av.(map[string]any)
+			bm, bIsMap := bv.(map[string]any)
+			if aIsMap && bIsMap {
+				diffs = append(diffs, diffEntries(am, bm, path+".")...)
+			} else if !reflect.DeepEqual(av, bv) {
+				diffs = append(diffs, entryDiff{Path: path, Kind: '~', Old: av, New: bv})
+			}
+		}
+	}
+	return diffs
+}
+
+// formatDiffLine renders a single diff as a colored line
+func formatDiffLine(d entryDiff, withColor bool) string {
+	switch d.Kind {
+	case '-':
+		return style(fmt.Sprintf("- %s: %s", d.Path, tailstream.Stringify(d.Old)), "31", withColor)
+	case '+':
+		return style(fmt.Sprintf("+ %s: %s", d.Path, tailstream.Stringify(d.New)), "32", withColor)
+	default:
+		return style(fmt.Sprintf("~ %s: %s → %s", d.Path, tailstream.Stringify(d.Old), tailstream.Stringify(d.New)), "33", withColor)
+	}
+}
+
+const (
+	deltaWidth     = 8           // Column width of the t-key time gaps, e.g. "  +0.23s"
+	deltaHighlight = time.Second // Gaps at least this long are highlighted
+)
+
+// entryDelta returns the time between two consecutive entries, regardless of
+// sort direction. It is false when either entry has no timestamp.
+func entryDelta(prev, cur map[string]any) (time.Duration, bool) {
+	a, ok := tailstream.LogEntry(prev).Timestamp()
+	if !ok {
+		return 0, false
+	}
+	b, ok := tailstream.LogEntry(cur).Timestamp()
+	if !ok {
+		return 0, false
+	}
+	return b.Sub(a).Abs(), true
+}
+
+// formatDelta renders a gap between entries: hundredths of a second below a
+// minute (e.g. "+0.23s"), whole seconds above (e.g. "+2m5s")
+func formatDelta(d time.Duration) string {
+	if d < time.Minute {
+		return fmt.Sprintf("+%.2fs", d.Seconds())
+	}
+	return "+" + d.Round(time.Second).String()
+}
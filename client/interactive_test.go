@@ -1,14 +1,25 @@
 package main
 
 import (
+	"bytes"
+	"io"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
+	"unicode/utf8"
+
+	"tailstream/client/pkg/tailstream"
 )
 
 // TestInteractiveContext verifies the InteractiveContext structure
 func TestInteractiveContext(t *testing.T) {
 	ctx := &InteractiveContext{
 		BaseURL:  "https://test.example.com",
-		Token:    "test-token",
+		API:      tailstream.NewClient("https://test.example.com", "test-token"),
 		StreamID: "test-stream-id",
 		PerPage:  200,
 		SortDir:  "desc",
@@ -17,8 +28,8 @@ func TestInteractiveContext(t *testing.T) {
 	if ctx.BaseURL != "https://test.example.com" {
 		t.Errorf("unexpected BaseURL: %s", ctx.BaseURL)
 	}
-	if ctx.Token != "test-token" {
-		t.Errorf("unexpected Token: %s", ctx.Token)
+	if ctx.API.Token != "test-token" {
+		t.Errorf("unexpected Token: %s", ctx.API.Token)
 	}
 	if ctx.StreamID != "test-stream-id" {
 		t.Errorf("unexpected StreamID: %s", ctx.StreamID)
@@ -35,3 +46,457 @@ func TestInteractiveContext(t *testing.T) {
 // and is better suited for manual testing or end-to-end tests with terminal emulation.
 // The core logic is tested through the other component tests (display, api, etc.)
 
+
+func TestFindLineMatch(t *testing.T) {
+	lines := []string{"{", `  "level": "error",`, `  "message": "Timeout",`, `  "level_code": 3`, "}"}
+
+	tests := []struct {
+		name     string
+		term     string
+		from     int
+		forward  bool
+		expected int
+	}{
+		{"first match forward", "level", 0, true, 1},
+		{"next match forward", "level", 2, true, 3},
+		{"wraps forward", "level", 4, true, 1},
+		{"case insensitive", "TIMEOUT", 0, true, 2},
+		{"backward", "level", 2, false, 1},
+		{"wraps backward", "level", 0, false, 3},
+		{"no match", "missing", 0, true, -1},
+		{"empty term", "", 0, true, -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := findLineMatch(lines, tt.term, tt.from, tt.forward); got != tt.expected {
+				t.Errorf("expected %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestIsTerminal(t *testing.T) {
+	// A regular file is never a terminal
+	f, err := os.CreateTemp(t.TempDir(), "not-a-tty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if isTerminal(f) {
+		t.Error("expected regular file not to be a terminal")
+	}
+}
+
+func TestOpenKeyboardPipedStdin(t *testing.T) {
+	// With stdin piped, keystrokes must come from /dev/tty, or there is no
+	// keyboard at all (as under `go test` without a controlling terminal)
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+	stdin := os.Stdin
+	os.Stdin = r
+	defer func() { os.Stdin = stdin }()
+
+	keyboard, err := openKeyboard()
+	if err != nil {
+		return
+	}
+	defer keyboard.Close()
+	if keyboard == r || !isTerminal(keyboard) {
+		t.Errorf("expected the controlling terminal, got %s", keyboard.Name())
+	}
+}
+
+func TestShiftIndexKeys(t *testing.T) {
+	m := map[int]bool{0: true, 2: true, 5: true}
+
+	// Removing index 2 drops it and shifts later keys down
+	removed := shiftIndexKeys(m, 2, -1)
+	if len(removed) != 2 || !removed[0] || !removed[4] {
+		t.Errorf("unexpected map after removal: %v", removed)
+	}
+
+	// Inserting at index 2 shifts it and later keys up
+	inserted := shiftIndexKeys(m, 2, 1)
+	if len(inserted) != 3 || !inserted[0] || !inserted[3] || !inserted[6] {
+		t.Errorf("unexpected map after insertion: %v", inserted)
+	}
+}
+
+func TestToggleFields(t *testing.T) {
+	available := []string{"level", "path", "status"}
+
+	shown, err := toggleFields(nil, available, "3 path")
+	if err != nil || !reflect.DeepEqual(shown, []string{"status", "path"}) {
+		t.Errorf("expected status and path in selection order, got %v (%v)", shown, err)
+	}
+
+	// Toggling again removes; unknown names are allowed
+	shown, err = toggleFields(shown, available, "status user_id")
+	if err != nil || !reflect.DeepEqual(shown, []string{"path", "user_id"}) {
+		t.Errorf("unexpected fields after toggling: %v (%v)", shown, err)
+	}
+
+	// Out-of-range numbers are reported, the rest still apply
+	shown, err = toggleFields(shown, available, "0 1 9")
+	if err == nil || !strings.Contains(err.Error(), "0, 9") || !reflect.DeepEqual(shown, []string{"path", "user_id", "level"}) {
+		t.Errorf("unexpected result for invalid numbers: %v (%v)", shown, err)
+	}
+}
+
+func TestActionsMenu(t *testing.T) {
+	lines := actionsMenu(2, false)
+	if len(lines) != len(entryActions)+3 {
+		t.Fatalf("expected a border, %d actions, a hint, and a border; got %d lines", len(entryActions), len(lines))
+	}
+	width := utf8.RuneCountInString(lines[0])
+	for _, line := range lines {
+		if w := utf8.RuneCountInString(line); w != width {
+			t.Errorf("line %q is %d wide, want %d", line, w, width)
+		}
+	}
+	if !strings.Contains(lines[3], "▶ Copy curl command") || !strings.HasSuffix(lines[1], "Space │") {
+		t.Errorf("unexpected menu:\n%s", strings.Join(lines, "\n"))
+	}
+}
+
+func TestOSC52(t *testing.T) {
+	if got := osc52(`{"a":1}`); got != "\033]52;c;eyJhIjoxfQ==\a" {
+		t.Errorf("unexpected sequence: %q", got)
+	}
+}
+
+func TestContextCurl(t *testing.T) {
+	at := time.UnixMilli(1704067200000)
+	got := contextCurl("https://app.tailstream.io/api/streams/s1/logs", url.Values{"limit": {"200"}}, at, time.Minute)
+	want := `curl -H "Authorization: Bearer $TAILSTREAM_TOKEN" 'https://app.tailstream.io/api/streams/s1/logs?end_time=1704067260000&limit=200&start_time=1704067140000'`
+	if got != want {
+		t.Errorf("contextCurl =\n %s\nwant\n %s", got, want)
+	}
+}
+
+func TestKeyStream(t *testing.T) {
+	keys := newKeyStream(strings.NewReader("jk\x1b[B"))
+	buf := make([]byte, 2)
+	var got []string
+	for {
+		n, err := keys.Read(buf)
+		if err != nil {
+			if err != io.EOF {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			break
+		}
+		got = append(got, string(buf[:n]))
+	}
+	if strings.Join(got, "") != "jk\x1b[B" {
+		t.Errorf("expected every key in order, got %q", got)
+	}
+	if keys.buffered() {
+		t.Error("expected nothing left over at EOF")
+	}
+}
+
+func TestPrefetchAhead(t *testing.T) {
+	tests := []struct {
+		threshold, pages, perPage, viewport int
+		expected                            int
+	}{
+		{0, 0, 50, 20, 5},   // Only near the end by default
+		{0, 1, 50, 20, 50},  // One page ahead
+		{0, 1, 10, 20, 20},  // At least a screenful
+		{0, 2, 50, 20, 100}, // More pages for fast scrolling
+		{7, 2, 50, 20, 7},   // An explicit threshold wins
+	}
+	for _, tt := range tests {
+		if got := prefetchAhead(tt.threshold, tt.pages, tt.perPage, tt.viewport); got != tt.expected {
+			t.Errorf("prefetchAhead(%d, %d, %d, %d) = %d, expected %d", tt.threshold, tt.pages, tt.perPage, tt.viewport, got, tt.expected)
+		}
+	}
+}
+
+func TestMaxHorizontalOffset(t *testing.T) {
+	lines := []string{strings.Repeat("a", 30), strings.Repeat("b", 120), ""}
+	if got := maxHorizontalOffset(lines, 80); got != 40 {
+		t.Errorf("expected the longest line to set the offset, got %d", got)
+	}
+	if got := maxHorizontalOffset(lines[:1], 80); got != 0 {
+		t.Errorf("expected no scrolling for lines that fit, got %d", got)
+	}
+	if got := maxHorizontalOffset(nil, 80); got != 0 {
+		t.Errorf("expected no scrolling without lines, got %d", got)
+	}
+}
+
+func TestPermuteIndexKeys(t *testing.T) {
+	// The entry at 2 moves to the front, the others shift back
+	m := map[int]bool{0: true, 2: true}
+	permuted := permuteIndexKeys(m, []int{2, 0, 1})
+	if len(permuted) != 2 || !permuted[0] || !permuted[1] {
+		t.Errorf("unexpected map after permutation: %v", permuted)
+	}
+	// Hidden entries and the cursor follow their entries the same way
+	if moved := movedIndices([]int{2, 0, 1}); !reflect.DeepEqual(moved, []int{1, 2, 0}) {
+		t.Errorf("movedIndices = %v, want [1 2 0]", moved)
+	}
+}
+
+func TestDiffEntries(t *testing.T) {
+	a := map[string]any{
+		"level":   "INFO",
+		"message": "ok",
+		"request": map[string]any{"status": float64(200), "path": "/api"},
+		"trace":   "abc",
+	}
+	b := map[string]any{
+		"level":   "ERROR",
+		"message": "ok",
+		"request": map[string]any{"status": float64(500), "path": "/api"},
+		"error":   "boom",
+	}
+
+	diffs := diffEntries(a, b, "")
+	want := []entryDiff{
+		{Path: "error", Kind: '+', New: "boom"},
+		{Path: "level", Kind: '~', Old: "INFO", New: "ERROR"},
+		{Path: "request.status", Kind: '~', Old: float64(200), New: float64(500)},
+		{Path: "trace", Kind: '-', Old: "abc"},
+	}
+	if len(diffs) != len(want) {
+		t.Fatalf("expected %d diffs, got %d: %v", len(want), len(diffs), diffs)
+	}
+	for i := range want {
+		if diffs[i] != want[i] {
+			t.Errorf("diff %d: expected %+v, got %+v", i, want[i], diffs[i])
+		}
+	}
+
+	if diffs := diffEntries(a, a, ""); len(diffs) != 0 {
+		t.Errorf("expected identical entries to have no diffs, got %v", diffs)
+	}
+}
+
+func TestFormatDiffLine(t *testing.T) {
+	tests := []struct {
+		diff entryDiff
+		want string
+	}{
+		{entryDiff{Path: "a", Kind: '-', Old: "x"}, "- a: x"},
+		{entryDiff{Path: "b", Kind: '+', New: float64(1)}, "+ b: 1"},
+		{entryDiff{Path: "c.d", Kind: '~', Old: "x", New: "y"}, "~ c.d: x → y"},
+	}
+	for _, tt := range tests {
+		if got := formatDiffLine(tt.diff, false); got != tt.want {
+			t.Errorf("formatDiffLine(%+v) = %q, want %q", tt.diff, got, tt.want)
+		}
+	}
+}
+
+func TestWrapLine(t *testing.T) {
+	// Short lines are left alone
+	if rows := wrapLine("hello", 10, "  "); len(rows) != 1 || rows[0] != "hello" {
+		t.Errorf("unexpected rows for short line: %q", rows)
+	}
+
+	// Long lines wrap with indented continuation rows
+	rows := wrapLine("abcdefghij", 4, "  ")
+	want := []string{"abcd", "  ef", "  gh", "  ij"}
+	if len(rows) != len(want) {
+		t.Fatalf("expected %q, got %q", want, rows)
+	}
+	for i := range want {
+		if rows[i] != want[i] {
+			t.Errorf("row %d: expected %q, got %q", i, want[i], rows[i])
+		}
+	}
+
+	// Escape sequences don't count towards the width and are never split
+	rows = wrapLine("\033[31mabcdef\033[0m", 4, "")
+	if len(rows) != 2 || rows[0] != "\033[31mabcd" || rows[1] != "ef\033[0m" {
+		t.Errorf("unexpected rows for colored line: %q", rows)
+	}
+}
+
+func TestBuildEntryURL(t *testing.T) {
+	entry := map[string]any{"id": float64(42)}
+
+	got, err := buildEntryURL("", "https://app.tailstream.io/", "my stream", entry)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "https://app.tailstream.io/streams/my%20stream/logs/42" {
+		t.Errorf("unexpected default URL: %s", got)
+	}
+
+	got, _ = buildEntryURL("{base_url}/s/{stream_id}?entry={id}", "https://logs.example.com", "s1", entry)
+	if got != "https://logs.example.com/s/s1?entry=42" {
+		t.Errorf("unexpected custom URL: %s", got)
+	}
+
+	if _, err := buildEntryURL("", "https://x", "s", map[string]any{"message": "no id"}); err == nil {
+		t.Error("expected error for entry without id")
+	}
+}
+
+func TestUIChromeLines(t *testing.T) {
+	tests := []struct {
+		height  int
+		compact bool
+		want    int
+	}{
+		{40, false, 5},
+		{compactUIHeight, false, 5},
+		{compactUIHeight - 1, false, 2}, // Small pane switches to the compact layout
+		{8, false, 2},
+		{40, true, 2}, // --compact-ui
+	}
+	for _, tt := range tests {
+		if got := uiChromeLines(tt.height, tt.compact); got != tt.want {
+			t.Errorf("uiChromeLines(%d, %v) = %d, want %d", tt.height, tt.compact, got, tt.want)
+		}
+	}
+}
+
+func TestAddHistory(t *testing.T) {
+	var history []string
+	for _, entry := range []string{"error", "", "  ", "error", "timeout", "error"} {
+		history = addHistory(history, entry)
+	}
+	if want := []string{"error", "timeout", "error"}; !reflect.DeepEqual(history, want) {
+		t.Errorf("history = %q, want %q", history, want)
+	}
+
+	for i := 0; i < maxHistory+5; i++ {
+		history = addHistory(history, strconv.Itoa(i))
+	}
+	if len(history) != maxHistory || history[len(history)-1] != strconv.Itoa(maxHistory+4) {
+		t.Errorf("history not capped to the most recent %d entries (len %d)", maxHistory, len(history))
+	}
+}
+
+func TestReadLine(t *testing.T) {
+	history := []string{"first", "second"}
+	tests := []struct {
+		name  string
+		input string
+		want  string
+		ok    bool
+	}{
+		{"typed", "abc\n", "abc", true},
+		{"backspace", "abx\x7fc\r", "abc", true},
+		{"ctrl-u", "junk\x15ok\n", "ok", true},
+		{"up recalls latest", "\x1b[A\n", "second", true},
+		{"up twice", "\x1b[A\x1b[A\n", "first", true},
+		{"up stops at oldest", "\x1b[A\x1b[A\x1b[A\n", "first", true},
+		{"down restores draft", "dr\x1b[A\x1b[Baft\n", "draft", true},
+		{"multibyte backspace", "né\x7f\n", "n", true},
+		{"escape cancels", "\x1b", "", false},
+		{"end of input", "abc", "", false},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		got, ok := readLine(strings.NewReader(tt.input), &out, "Search: ", history)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("%s: readLine = %q, %v; want %q, %v", tt.name, got, ok, tt.want, tt.ok)
+		}
+		if !strings.Contains(out.String(), "Search: ") {
+			t.Errorf("%s: prompt not echoed", tt.name)
+		}
+	}
+}
+
+func TestEntryDelta(t *testing.T) {
+	a := map[string]any{"timestamp": "2024-01-02T15:04:05Z"}
+	b := map[string]any{"timestamp": "2024-01-02T15:04:05.230Z"}
+	if d, ok := entryDelta(a, b); !ok || d != 230*time.Millisecond {
+		t.Errorf("expected 230ms, got %v %v", d, ok)
+	}
+	// Newest-first lists give the same gap
+	if d, ok := entryDelta(b, a); !ok || d != 230*time.Millisecond {
+		t.Errorf("expected 230ms in descending order, got %v %v", d, ok)
+	}
+	if _, ok := entryDelta(a, map[string]any{"message": "no time"}); ok {
+		t.Error("expected no delta without a timestamp")
+	}
+}
+
+func TestFormatDelta(t *testing.T) {
+	tests := map[time.Duration]string{
+		230 * time.Millisecond:                "+0.23s",
+		0:                                     "+0.00s",
+		42 * time.Second:                      "+42.00s",
+		2*time.Minute + 5400*time.Millisecond: "+2m5s",
+	}
+	for d, want := range tests {
+		if got := formatDelta(d); got != want {
+			t.Errorf("formatDelta(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
+
+func TestReadLineLive(t *testing.T) {
+	var seen []string
+	got, ok := readLineLive(strings.NewReader("er\x7fr\n"), io.Discard, "Search: ", nil, func(line string) {
+		seen = append(seen, line)
+	})
+	if got != "er" || !ok {
+		t.Fatalf("readLineLive = %q, %v; want \"er\", true", got, ok)
+	}
+	// Once up front, then after every edit
+	if want := []string{"", "e", "er", "e", "er"}; !reflect.DeepEqual(seen, want) {
+		t.Errorf("onChange saw %q, want %q", seen, want)
+	}
+}
+
+func TestLocalMatches(t *testing.T) {
+	entries := []map[string]any{
+		{"message": "Connection refused"},
+		{"message": "request ok"},
+		{"message": "connection reset"},
+	}
+	if got := localMatches(searchTexts(entries), "CONNECTION"); !reflect.DeepEqual(got, []int{0, 2}) {
+		t.Errorf("expected case-insensitive matches [0 2], got %v", got)
+	}
+	if got := localMatches(searchTexts(entries), "  "); len(got) != 3 {
+		t.Errorf("expected an empty query to match everything, got %v", got)
+	}
+	if got := localMatches(searchTexts(entries), "timeout"); len(got) != 0 {
+		t.Errorf("expected no matches, got %v", got)
+	}
+}
+
+func TestTotalInfo(t *testing.T) {
+	n := func(v int) *int { return &v }
+	tests := []struct {
+		loaded int
+		total  *int
+		more   bool
+		want   string
+	}{
+		{50, n(200), true, " of 200 total"},
+		{200, n(200), false, " of 200 total"},
+		{50, nil, true, ", tail mode (no total), more available"},
+		{50, nil, false, ", tail mode (no total)"},
+		{50, n(0), true, ", more available"},  // contradicts what's loaded
+		{50, n(50), true, ", more available"}, // can't have more than the total
+		{50, n(10), false, ""},
+	}
+	for _, tt := range tests {
+		if got := totalInfo(tt.loaded, tt.total, tt.more); got != tt.want {
+			t.Errorf("totalInfo(%d, %v, %v) = %q, want %q", tt.loaded, tt.total, tt.more, got, tt.want)
+		}
+	}
+}
+
+func TestCollapseLines(t *testing.T) {
+	if got := collapseLines("single line", false); got != "single line" {
+		t.Errorf("single line changed: %q", got)
+	}
+	if got := collapseLines("panic: boom\n\tat a\n\tat b", false); got != "panic: boom [+2 lines]" {
+		t.Errorf("got %q", got)
+	}
+}
@@ -0,0 +1,175 @@
+// Package main - keychain.go
+//
+// Optional storage of OAuth tokens in the operating system's secret store.
+//
+// With --use-keychain at login, the access and refresh tokens are written to
+// the OS secret store and the config file only keeps a reference to them
+// (the `keychain` field). Supported stores:
+// - macOS Keychain (security)
+// - Secret Service on Linux (secret-tool, from libsecret)
+// - Windows Credential Manager (advapi32, see keychain_windows.go)
+//
+// Secrets are never passed as command-line arguments, where other users
+// could see them in the process list.
+//
+// When no store is available the client falls back to the config file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// keychainService is the service name tokens are stored under
+const keychainService = "tailstream-client"
+
+// secretStore stores secrets by account name
+type secretStore interface {
+	Set(account, secret string) error
+	Get(account string) (string, error)
+	Delete(account string) error
+}
+
+// openSecretStore returns the OS secret store, or false when none is
+// available. It is a variable so tests can substitute an in-memory store.
+var openSecretStore = func() (secretStore, bool) {
+	switch runtime.GOOS {
+	case "darwin":
+		if _, err := exec.LookPath("security"); err == nil {
+			return macKeychain{}, true
+		}
+	case "linux", "freebsd", "openbsd":
+		if _, err := exec.LookPath("secret-tool"); err == nil {
+			return secretService{}, true
+		}
+	case "windows":
+		return platformSecretStore()
+	}
+	return nil, false
+}
+
+// keychainAccounts returns the account names for a config's tokens
+func keychainAccounts(ref string) (access, refresh string) {
+	return ref + "/access_token", ref + "/refresh_token"
+}
+
+// storeTokensInKeychain moves the config's tokens into the OS secret store,
+// leaving only the ref in the config
+func storeTokensInKeychain(config *ClientConfig, ref string) error {
+	store, ok := openSecretStore()
+	if !ok {
+		return fmt.Errorf("no OS keychain available")
+	}
+	accessAccount, refreshAccount := keychainAccounts(ref)
+	if err := store.Set(accessAccount, config.AccessToken); err != nil {
+		return err
+	}
+	if config.RefreshToken != "" {
+		if err := store.Set(refreshAccount, config.RefreshToken); err != nil {
+			return err
+		}
+	}
+	config.AccessToken = ""
+	config.RefreshToken = ""
+	config.Keychain = ref
+	return nil
+}
+
+// keychainToken reads the access token referenced by the config
+func keychainToken(ref string) (string, error) {
+	store, ok := openSecretStore()
+	if !ok {
+		return "", fmt.Errorf("config stores tokens in the OS keychain, but none is available")
+	}
+	accessAccount, _ := keychainAccounts(ref)
+	token, err := store.Get(accessAccount)
+	if err != nil {
+		return "", fmt.Errorf("failed to read token from keychain: %v", err)
+	}
+	return token, nil
+}
+
+// deleteKeychainTokens removes the tokens referenced by the config, ignoring
+// entries that are already gone
+func deleteKeychainTokens(ref string) {
+	store, ok := openSecretStore()
+	if !ok {
+		return
+	}
+	accessAccount, refreshAccount := keychainAccounts(ref)
+	store.Delete(accessAccount)
+	store.Delete(refreshAccount)
+}
+
+// runSecretCommand runs a secret store CLI, optionally feeding stdin, and
+// returns its trimmed stdout
+func runSecretCommand(stdin string, name string, args ...string) (string, error) {
+	cmd := exec.Command(name, args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if stdin != "" {
+		cmd.Stdin = strings.NewReader(stdin)
+	}
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s: %v: %s", name, err, msg)
+		}
+		return "", fmt.Errorf("%s: %v", name, err)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// macKeychain stores secrets as generic passwords in the login keychain
+type macKeychain struct{}
+
+// Set runs add-generic-password through `security -i`, which reads the
+// command from stdin, so the secret never appears in argv. Interactive mode
+// doesn't report a failed command in its exit status, so the stored value
+// is read back to confirm it.
+func (m macKeychain) Set(account, secret string) error {
+	command := "add-generic-password -U -s " + securityQuote(keychainService) + " -a " + securityQuote(account) + " -w " + securityQuote(secret) + "\n"
+	if _, err := runSecretCommand(command, "security", "-i"); err != nil {
+		return err
+	}
+	if stored, err := m.Get(account); err != nil || stored != secret {
+		return fmt.Errorf("security: failed to store the secret for %s", account)
+	}
+	return nil
+}
+
+// securityQuote quotes an argument for a `security -i` command line
+func securityQuote(arg string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(arg) + `"`
+}
+
+func (macKeychain) Get(account string) (string, error) {
+	return runSecretCommand("", "security", "find-generic-password", "-s", keychainService, "-a", account, "-w")
+}
+
+func (macKeychain) Delete(account string) error {
+	_, err := runSecretCommand("", "security", "delete-generic-password", "-s", keychainService, "-a", account)
+	return err
+}
+
+// secretService stores secrets via the freedesktop Secret Service (GNOME
+// Keyring, KWallet). The secret is passed on stdin, never as an argument.
+type secretService struct{}
+
+func (secretService) Set(account, secret string) error {
+	_, err := runSecretCommand(secret, "secret-tool", "store", "--label=Tailstream client ("+account+")", "service", keychainService, "account", account)
+	return err
+}
+
+func (secretService) Get(account string) (string, error) {
+	return runSecretCommand("", "secret-tool", "lookup", "service", keychainService, "account", account)
+}
+
+func (secretService) Delete(account string) error {
+	_, err := runSecretCommand("", "secret-tool", "clear", "service", keychainService, "account", account)
+	return err
+}
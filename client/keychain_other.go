@@ -0,0 +1,9 @@
+//go:build !windows
+
+package main
+
+// platformSecretStore reports no built-in secret store; the CLI-driven
+// stores in keychain.go cover the other platforms
+func platformSecretStore() (secretStore, bool) {
+	return nil, false
+}
@@ -0,0 +1,85 @@
+package main
+
+import "testing"
+
+// memorySecretStore is an in-memory secretStore for tests
+type memorySecretStore map[string]string
+
+func (m memorySecretStore) Set(account, secret string) error {
+	m[account] = secret
+	return nil
+}
+
+func (m memorySecretStore) Get(account string) (string, error) {
+	return m[account], nil
+}
+
+func (m memorySecretStore) Delete(account string) error {
+	delete(m, account)
+	return nil
+}
+
+// useSecretStore swaps the OS secret store for the duration of a test
+func useSecretStore(t *testing.T, store secretStore) {
+	original := openSecretStore
+	openSecretStore = func() (secretStore, bool) { return store, store != nil }
+	t.Cleanup(func() { openSecretStore = original })
+}
+
+func TestStoreTokensInKeychain(t *testing.T) {
+	store := memorySecretStore{}
+	useSecretStore(t, store)
+
+	config := &ClientConfig{AccessToken: "access", RefreshToken: "refresh"}
+	if err := storeTokensInKeychain(config, "https://app.tailstream.io"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Only the reference stays in the config
+	if config.AccessToken != "" || config.RefreshToken != "" || config.Keychain != "https://app.tailstream.io" {
+		t.Errorf("unexpected config after storing: %+v", config)
+	}
+	if store["https://app.tailstream.io/access_token"] != "access" || store["https://app.tailstream.io/refresh_token"] != "refresh" {
+		t.Errorf("unexpected keychain contents: %v", store)
+	}
+
+	// Token resolution reads it back
+	if got, err := determineToken("", config); err != nil || got != "access" {
+		t.Errorf("expected keychain token, got %q (%v)", got, err)
+	}
+
+	deleteKeychainTokens(config.Keychain)
+	if len(store) != 0 {
+		t.Errorf("expected tokens to be deleted, got %v", store)
+	}
+}
+
+func TestStoreTokensWithoutKeychain(t *testing.T) {
+	useSecretStore(t, nil)
+
+	config := &ClientConfig{AccessToken: "access"}
+	if err := storeTokensInKeychain(config, "ref"); err == nil {
+		t.Fatal("expected error without a keychain")
+	}
+	// The config is left intact so the caller can fall back to the file
+	if config.AccessToken != "access" || config.Keychain != "" {
+		t.Errorf("expected config to be unchanged, got %+v", config)
+	}
+
+	if _, err := determineToken("", &ClientConfig{Keychain: "ref"}); err == nil {
+		t.Error("expected error reading a keychain reference without a keychain")
+	}
+}
+
+func TestSecurityQuote(t *testing.T) {
+	tests := map[string]string{
+		"plain":      `"plain"`,
+		`with space`: `"with space"`,
+		`a"b\c`:      `"a\"b\\c"`,
+	}
+	for in, expected := range tests {
+		if got := securityQuote(in); got != expected {
+			t.Errorf("securityQuote(%q) = %s, expected %s", in, got, expected)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+// Package main - keychain_windows.go
+//
+// Windows Credential Manager backend for --use-keychain, called through
+// advapi32 directly since no bundled CLI can read a stored secret back.
+
+package main
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	advapi32       = syscall.NewLazyDLL("advapi32.dll")
+	procCredWrite  = advapi32.NewProc("CredWriteW")
+	procCredRead   = advapi32.NewProc("CredReadW")
+	procCredDelete = advapi32.NewProc("CredDeleteW")
+	procCredFree   = advapi32.NewProc("CredFree")
+)
+
+const (
+	credTypeGeneric         = 1 // CRED_TYPE_GENERIC
+	credPersistLocalMachine = 2 // CRED_PERSIST_LOCAL_MACHINE
+)
+
+// winCredential mirrors the CREDENTIALW struct
+type winCredential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        syscall.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+// platformSecretStore returns the Windows Credential Manager
+func platformSecretStore() (secretStore, bool) {
+	if advapi32.Load() != nil {
+		return nil, false
+	}
+	return windowsCredentials{}, true
+}
+
+// windowsCredentials stores secrets as generic credentials named
+// "tailstream-client/<account>"
+type windowsCredentials struct{}
+
+func credentialTarget(account string) (*uint16, error) {
+	return syscall.UTF16PtrFromString(keychainService + "/" + account)
+}
+
+func (windowsCredentials) Set(account, secret string) error {
+	target, err := credentialTarget(account)
+	if err != nil {
+		return err
+	}
+	user, err := syscall.UTF16PtrFromString(account)
+	if err != nil {
+		return err
+	}
+	blob := []byte(secret)
+	cred := winCredential{
+		Type:               credTypeGeneric,
+		TargetName:         target,
+		CredentialBlobSize: uint32(len(blob)),
+		Persist:            credPersistLocalMachine,
+		UserName:           user,
+	}
+	if len(blob) > 0 {
+		cred.CredentialBlob = &blob[0]
+	}
+	if r, _, err := procCredWrite.Call(uintptr(unsafe.Pointer(&cred)), 0); r == 0 {
+		return fmt.Errorf("CredWrite: %v", err)
+	}
+	return nil
+}
+
+func (windowsCredentials) Get(account string) (string, error) {
+	target, err := credentialTarget(account)
+	if err != nil {
+		return "", err
+	}
+	var cred *winCredential
+	if r, _, err := procCredRead.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0, uintptr(unsafe.Pointer(&cred))); r == 0 {
+		return "", fmt.Errorf("CredRead: %v", err)
+	}
+	defer procCredFree.Call(uintptr(unsafe.Pointer(cred)))
+	if cred.CredentialBlobSize == 0 {
+		return "", nil
+	}
+	return string(unsafe.Slice(cred.CredentialBlob, cred.CredentialBlobSize)), nil
+}
+
+func (windowsCredentials) Delete(account string) error {
+	target, err := credentialTarget(account)
+	if err != nil {
+		return err
+	}
+	if r, _, err := procCredDelete.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0); r == 0 {
+		return fmt.Errorf("CredDelete: %v", err)
+	}
+	return nil
+}
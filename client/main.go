@@ -15,7 +15,11 @@
 // - config.go: Configuration file management
 // - oauth.go: OAuth device flow and stream selection
 // - api.go: HTTP client and API interactions
+// - pkg/tailstream: Importable API client (streams, logs, entry accessors, OAuth) used by the CLI
+// - cache.go: Optional on-disk page cache
+// - keychain.go: Optional OS keychain storage for tokens
 // - time.go: Time parsing utilities
+// - entry.go: Entry search text and field discovery
 // - display.go: Log formatting and styling
 // - interactive.go: Interactive terminal UI
 //
@@ -28,17 +32,29 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"cmp"
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"maps"
+	"math"
 	"net/http"
 	"net/url"
 	"os"
+	"os/signal"
+	"slices"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
+
+	"tailstream/client/pkg/tailstream"
 )
 
 var (
@@ -49,8 +65,21 @@ var (
 
 	defaultBaseURL     = "https://app.tailstream.io"
 	insecureSkipTLSStr = "false" // Set to "true" for local testing with self-signed certs
+
+	// Latest-release endpoint for `version --check`; TAILSTREAM_UPDATE_URL
+	// overrides it (e.g. for a mirror)
+	updateCheckURL = "https://api.github.com/repos/tailstream-io/tailstream-client/releases/latest"
 )
 
+// updateCheckTimeout bounds `version --check` so it never hangs
+const updateCheckTimeout = 5 * time.Second
+
+// maxClockSkew is the local/server clock difference above which a warning is shown
+const maxClockSkew = 2 * time.Minute
+
+// defaultFollowInterval is how often --follow polls for new entries
+const defaultFollowInterval = 2 * time.Second
+
 type stringSliceFlag []string
 
 func (s *stringSliceFlag) String() string {
@@ -62,54 +91,406 @@ func (s *stringSliceFlag) Set(value string) error {
 	return nil
 }
 
+// listFlag is a repeatable flag whose values may also be comma-separated,
+// so "--level ERROR,WARN --level FATAL" gives three values. Empty items are
+// skipped.
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
+
+// flagWasSet reports whether the named flag was given on the command line,
+// rather than left at its default
+func flagWasSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		set = set || f.Name == name
+	})
+	return set
+}
+
+// configureTLS applies --insecure (or TAILSTREAM_INSECURE), --ca-cert, and
+// --client-cert/--client-key to the HTTP clients created from then on
+func configureTLS(insecure bool, caCert, clientCert, clientKey string) error {
+	// Skipping TLS verification at runtime saves rebuilding with
+	// insecureSkipTLSStr for local and self-hosted testing
+	if envInsecure, _ := strconv.ParseBool(os.Getenv("TAILSTREAM_INSECURE")); insecure || envInsecure {
+		insecureTLS = true
+	}
+	if tlsVerifyDisabled() {
+		fmt.Fprintln(os.Stderr, "⚠️  WARNING: TLS certificate verification is disabled; connections can be intercepted")
+	}
+	if caCert != "" {
+		pool, err := loadCACert(caCert)
+		if err != nil {
+			return err
+		}
+		customRootCAs = pool
+	}
+	if clientCert != "" || clientKey != "" {
+		cert, err := loadClientCert(clientCert, clientKey)
+		if err != nil {
+			return err
+		}
+		clientCertificates = []tls.Certificate{cert}
+	}
+	return nil
+}
+
 func main() {
+	// Handle the config doctor command
+	if len(os.Args) > 2 && os.Args[1] == "config" && os.Args[2] == "doctor" {
+		// The server check connects like any other command, so it takes the
+		// same TLS flags
+		doctorFlags := flag.NewFlagSet("config doctor", flag.ExitOnError)
+		insecure := doctorFlags.Bool("insecure", false, "Skip TLS certificate verification (self-signed test servers only)")
+		caCert := doctorFlags.String("ca-cert", "", "PEM file with extra CA certificates to trust (e.g. a private CA)")
+		clientCert := doctorFlags.String("client-cert", "", "PEM client certificate for servers requiring mutual TLS (with --client-key)")
+		clientKey := doctorFlags.String("client-key", "", "PEM private key for --client-cert")
+		doctorFlags.Parse(os.Args[3:])
+		if err := configureTLS(*insecure, *caCert, *clientCert, *clientKey); err != nil {
+			fatal(err)
+		}
+		config, err := loadConfig()
+		if !runConfigDoctor(os.Stdout, config, err) {
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Handle version command
 	if len(os.Args) > 1 && (os.Args[1] == "--version" || os.Args[1] == "-v" || os.Args[1] == "version") {
 		fmt.Printf("tailstream-client %s\n", Version)
 		fmt.Printf("Build date: %s\n", BuildDate)
 		fmt.Printf("Git commit: %s\n", GitCommit)
+		if len(os.Args) > 2 && os.Args[2] == "--check" {
+			if err := runUpdateCheck(os.Stdout); err != nil {
+				fatal(err)
+			}
+		}
 		return
 	}
 
+	// The streams command lists the account's streams, numbered for
+	// --stream-index. It takes the usual flags (--base-url, --token, --json).
+	listStreams := len(os.Args) > 1 && os.Args[1] == "streams"
+	if listStreams {
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+	}
+
 	var (
-		baseURL       = flag.String("base-url", "", "Tailstream API host (overrides config)")
-		token         = flag.String("token", "", "API token for Authorization header (overrides config)")
-		streamID      = flag.String("stream-id", "", "Stream ID (overrides config default)")
-		from          = flag.String("from", "", "Start date/time (RFC3339, YYYY-MM-DD, or relative like -1h)")
-		to            = flag.String("to", "", "End date/time (RFC3339, YYYY-MM-DD, or relative like -5m)")
-		limit         = flag.Int("limit", 200, "Maximum number of log entries to display")
-		perPage       = flag.Int("per-page", 200, "Number of results per page (uses 'limit' parameter)")
-		sortDir       = flag.String("sort", "desc", "Sort direction: asc or desc (uses 'direction' parameter)")
-		timeout       = flag.Duration("timeout", 15*time.Second, "HTTP request timeout")
-		rawJSON       = flag.Bool("json", false, "Output raw JSON response")
-		noColor       = flag.Bool("no-color", false, "Disable ANSI color output")
-		quiet         = flag.Bool("quiet", false, "Disable progress indicator")
-		login         = flag.Bool("login", false, "Run OAuth login flow")
-		logout        = flag.Bool("logout", false, "Remove stored credentials")
-		interactive   = flag.Bool("interactive", true, "Interactive mode with navigation (use --interactive=false to disable)")
-		noInteractive = flag.Bool("no-interactive", false, "Disable interactive mode and output directly")
+		baseURL        = flag.String("base-url", "", "Tailstream API host (overrides config)")
+		token          = flag.String("token", "", "API token for Authorization header (overrides config)")
+		tokenFile      = flag.String("token-file", "", "Read the API token from a file instead of --token (keeps it out of ps and shell history)")
+		public         = flag.Bool("public", false, "Query a public stream without authenticating (needs --stream-id)")
+		streamID       = flag.String("stream-id", "", "Stream ID (overrides config default)")
+		streamName     = flag.String("stream", "", "Stream name, resolved to its stream ID (e.g. \"Production API\")")
+		streamIndex    = flag.Int("stream-index", 0, "Stream by its number in the last \"streams\" listing (e.g. 2)")
+		from           = flag.String("from", "", "Start date/time (RFC3339, YYYY-MM-DD, YYYY-MM, YYYY-Www, epoch seconds/millis, or relative like -1h)")
+		to             = flag.String("to", "", "End date/time (RFC3339, YYYY-MM-DD/YYYY-MM/YYYY-Www for the end of that period, epoch seconds/millis, or relative like -5m)")
+		afterID        = flag.String("after-id", "", "Only fetch entries after this entry ID (pair with --sort asc to resume)")
+		beforeID       = flag.String("before-id", "", "Only fetch entries before this entry ID (pair with --sort desc to page back)")
+		limit          = flag.Int("limit", 200, "Maximum number of log entries to display (0 or negative = fetch everything)")
+		head           = flag.Int("head", 0, "Print the first N entries in fetch order, then stop without fetching more pages (overrides --limit, implies --no-interactive)")
+		maxPages       = flag.Int("max-pages", 0, "Stop after fetching this many pages, including the first (0 = unlimited)")
+		strict         = flag.Bool("strict", false, "Exit with an error when a later page fails to fetch instead of warning and printing what was fetched")
+		prefetch       = flag.Int("prefetch", 0, "Interactive mode: pages to keep loaded ahead of the cursor while scrolling (0 = load only near the end)")
+		prefetchAt     = flag.Int("prefetch-threshold", 0, "Interactive mode: load the next page once this many entries are left below the cursor (0 = from --prefetch)")
+		perPage        = flag.Int("per-page", 200, "Number of results per page (uses 'limit' parameter)")
+		sortDir        = flag.String("sort", "desc", "Sort direction: asc or desc (uses 'direction' parameter)")
+		sortBy         = flag.String("sort-by", "", "Sort fetched entries client-side by a field, e.g. duration_ms:desc")
+		displayOrder   = flag.String("display-order", "", "Show entries oldest-first or newest-first, whatever --sort fetched (default: as fetched)")
+		sampleRate     = flag.Float64("sample-rate", 0, "Keep only a fraction of entries while paging, e.g. 0.1 for every 10th (0 = keep all)")
+		distinctField  = flag.String("distinct", "", "Print only the unique values of this field across the results, sorted (an array with --json); reads every page unless --limit is given")
+		caCert         = flag.String("ca-cert", "", "PEM file with extra CA certificates to trust (e.g. a private CA)")
+		clientCert     = flag.String("client-cert", "", "PEM client certificate for servers requiring mutual TLS (with --client-key)")
+		clientKey      = flag.String("client-key", "", "PEM private key for --client-cert")
+		insecure       = flag.Bool("insecure", false, "Skip TLS certificate verification (self-signed test servers only)")
+		timeout        = flag.Duration("timeout", defaultRequestTimeout, "Timeout for each HTTP request")
+		postQuery      = flag.Bool("post", false, "Send log queries as POST bodies (done automatically for very long filter sets)")
+		overallTimeout = flag.Duration("overall-timeout", 0, "Limit on the total time spent fetching pages (0 = no limit)")
+		rateLimit      = flag.Float64("rate-limit", 0, "Maximum requests per second when paginating (0 = unlimited)")
+		useCache       = flag.Bool("cache", false, "Cache fetched pages on disk and reuse them for identical queries")
+		noCache        = flag.Bool("no-cache", false, "Disable the page cache even if enabled in config")
+		cacheTTL       = flag.Duration("cache-ttl", defaultCacheTTL, "How long cached pages stay valid")
+		clearCacheFlag = flag.Bool("clear-cache", false, "Remove all cached pages and exit")
+		rawJSON        = flag.Bool("json", false, "Output raw JSON response")
+		share          = flag.Bool("share", false, "Upload the output to the paste service in share_url (config) and print the link")
+		noColor        = flag.Bool("no-color", false, "Disable ANSI color output")
+		safeRenderFlag = flag.Bool("safe-render", false, "Escape control characters and invalid UTF-8 in displayed entries")
+		inferLevelFlag = flag.Bool("infer-level", false, "Color raw messages by a level found in their text (e.g. [ERROR], level=warn) when there is no level field")
+		outputFormat   = flag.String("format", "", "Output preset: default, short, combined, json-array, or ndjson (default: output_format from config, else default)")
+		tsOnly         = flag.Bool("timestamps-only", false, "Print only each entry's timestamp, one per line (see --timestamp-format)")
+		tsFormat       = flag.String("timestamp-format", "rfc3339", "Timestamp format for --timestamps-only: rfc3339, unix, unix-ms, or a Go time layout (UTC)")
+		outputTemplate = flag.String("template", "", "Go template for each entry, e.g. '{{time}} {{field \"status\"}}' (overrides --format)")
+		truncate       = flag.Int("truncate", 0, "Cut each printed line to this many characters, with an ellipsis (0 = full lines)")
+		noTruncate     = flag.Bool("no-truncate", false, "Print full lines, overriding --truncate")
+		quiet          = flag.Bool("quiet", false, "Disable progress indicator")
+		login          = flag.Bool("login", false, "Run OAuth login flow")
+		oauthClientID  = flag.String("client-id", "", "OAuth client ID for --login (overrides config)")
+		useKeychain    = flag.Bool("use-keychain", false, "With --login: store tokens in the OS keychain instead of the config file")
+		loginPoll      = flag.String("poll", "", "With --login: wait for this device code to be authorized and print the token as JSON")
+		oauthScope     = flag.String("scope", "", "OAuth scope for --login (overrides config)")
+		logout         = flag.Bool("logout", false, "Remove stored credentials")
+		compactUI      = flag.Bool("compact-ui", false, "Interactive mode: drop the status and separator lines to fit small terminals")
+		contextWindow  = flag.Duration("context-window", defaultContextWindow, "Range on each side of an entry for the interactive c key")
+		interactive    = flag.Bool("interactive", true, "Interactive mode with navigation (use --interactive=false to disable)")
+		noInteractive  = flag.Bool("no-interactive", false, "Disable interactive mode and output directly")
+		noDefaultRange = flag.Bool("no-default-range", false, "Ignore default_range from config (query without a time bound)")
+		allowInverted  = flag.Bool("allow-inverted", false, "Allow --from to be later than --to")
+		printQuery     = flag.Bool("print-query", false, "Print a reproducible command line for this query and exit")
+		explain        = flag.Bool("explain", false, "Describe how the query will be interpreted and exit")
+		searchStdin    = flag.Bool("search-stdin", false, "Run one search per line read from stdin, prefixing output with the line")
+		queryFilePath  = flag.String("query-file", "", "YAML file with levels, methods, search terms, and filters to apply")
+		watchQuery     = flag.Bool("watch-query-file", false, "With --follow: reload --query-file whenever it changes")
+		follow         = flag.Bool("follow", false, "Keep polling for new entries after printing the initial results")
+		followOnlyNew  = flag.Bool("follow-only-new", false, "Follow from now on without loading any history (implies --follow)")
+		followInterval = flag.Duration("follow-interval", defaultFollowInterval, "How often --follow polls for new entries")
+		sse            = flag.Bool("sse", false, "Follow over the server's live event stream instead of polling, when it has one (implies --follow)")
+		guided         = flag.Bool("select", false, "Guided setup: pick stream, time range, and level from menus")
+		serverTime     = flag.Bool("server-time", false, "Resolve relative times against the server clock instead of the local clock")
 	)
 
-	var levels stringSliceFlag
-	var methods stringSliceFlag
+	var levels listFlag
+	var methods listFlag
 	var searches stringSliceFlag
-	flag.Var(&levels, "level", "Log level filter (repeatable, e.g., ERROR, WARN, INFO)")
-	flag.Var(&methods, "method", "HTTP method filter (repeatable, e.g., GET, POST)")
+	flag.Var(&levels, "level", "Log level filter (comma-separated or repeatable, e.g., ERROR,WARN)")
+	minLevel := flag.String("min-level", "", "Minimum log level; matches this level and anything more severe (e.g., WARN)")
+	flag.Var(&methods, "method", "HTTP method filter (comma-separated or repeatable, e.g., GET,POST)")
 	flag.Var(&searches, "search", "Search query (repeatable, case-insensitive)")
+	matchModeFlag := flag.String("match-mode", "substring", "How --search terms match: substring, word (whole words), or value (field values only)")
+	var filterExprs stringSliceFlag
+	flag.Var(&filterExprs, "filter", "Field filter like status>=500 or fields.http.status=404 (repeatable)")
+	filtersJSON := flag.String("filters-json", "", "Raw JSON array of API filters, added after those from --level, --method, and --filter")
+	var redactFields stringSliceFlag
+	var redactPatterns stringSliceFlag
+	flag.Var(&redactFields, "redact", "Mask values of these fields in output (comma-separated or repeatable)")
+	flag.Var(&redactPatterns, "redact-pattern", "Mask text matching this regex in any string value (repeatable)")
 
 	flag.Parse()
+	safeRender = *safeRenderFlag
+	inferLevel = *inferLevelFlag
+
+	if err := configureTLS(*insecure, *caCert, *clientCert, *clientKey); err != nil {
+		fatal(err)
+	}
+
+	if *head < 0 {
+		fatal(fmt.Errorf("--head must be a positive number of entries"))
+	}
+	// --head is --limit for direct output only
+	if *head > 0 {
+		*limit = *head
+	}
+	// --distinct is only complete over every page, so the default --limit
+	// doesn't apply to it
+	if *distinctField != "" && *head == 0 && !flagWasSet("limit") {
+		*limit = 0
+	}
+
+	sortField, sortDesc, err := parseSortSpec(*sortBy)
+	if err != nil {
+		fatal(err)
+	}
+	if *displayOrder != "" {
+		if sortField != "" {
+			fatal(fmt.Errorf("--display-order and --sort-by can't be combined"))
+		}
+		// Display order reuses the client-side sort, by timestamp
+		if sortField, sortDesc, err = parseDisplayOrder(*displayOrder, *sortDir); err != nil {
+			fatal(err)
+		}
+	}
+	redact, err := newRedactor(redactFields, redactPatterns)
+	if err != nil {
+		fatal(err)
+	}
+	// Shared output is read in a browser, where ANSI colors are noise
+	if *share {
+		*noColor = true
+	}
+	// --format overrides output_format from the config file, which is
+	// loaded properly (with error reporting) once the flags are checked
+	formatFlag := *outputFormat
+	if formatFlag == "" {
+		configured, _ := loadConfig()
+		*outputFormat = determineOutputFormat(formatFlag, configured)
+	}
+	if *tsOnly && ((formatFlag != "" && formatFlag != "default") || *outputTemplate != "" || *rawJSON) {
+		fatal(fmt.Errorf("--timestamps-only can't be combined with --format, --template, or --json"))
+	}
+	// The formatter is built again once per-stream defaults are known
+	var format entryFormatter
+	var lineFields []string // Fields added to default-format lines (stream_defaults)
+	buildFormatter := func() {
+		f, err := newEntryFormatter(*outputFormat, *outputTemplate, !*noColor)
+		if err != nil {
+			fatal(err)
+		}
+		if *tsOnly {
+			*outputFormat = "default" // Plain lines, whatever output_format says
+			if f, err = newTimestampFormatter(*tsFormat); err != nil {
+				fatal(err)
+			}
+		} else if len(lineFields) > 0 && *outputFormat == "default" && *outputTemplate == "" {
+			base := f
+			f = func(entry map[string]any) string {
+				return withFields(base(entry), entry, lineFields, !*noColor)
+			}
+		}
+		// Truncation is for reading text in a terminal; JSON elements stay whole
+		if *truncate > 0 && !*noTruncate && ((*outputFormat != jsonArrayFormat && *outputFormat != ndjsonFormat) || *outputTemplate != "") {
+			f = truncatingFormatter(f, *truncate)
+		}
+		format = f
+	}
+	buildFormatter()
+	sample, err := newSampler(*sampleRate)
+	if err != nil {
+		fatal(err)
+	}
+	match, err := parseMatchMode(*matchModeFlag)
+	if err != nil {
+		fatal(err)
+	}
+	var fieldFilters []fieldFilter
+	for _, expr := range filterExprs {
+		f, err := parseFieldFilter(expr)
+		if err != nil {
+			fatal(err)
+		}
+		fieldFilters = append(fieldFilters, f)
+	}
+	var rawFilters []map[string]any
+	if *filtersJSON != "" {
+		if rawFilters, err = parseFiltersJSON(*filtersJSON); err != nil {
+			fatal(err)
+		}
+	}
+
+	// Query file settings add to the flags; the flag-only values are kept so
+	// --watch-query-file can re-merge a changed file
+	flagLevels, flagMethods, flagSearches, flagFilters := slices.Clone(levels), slices.Clone(methods), slices.Clone(searches), slices.Clone(fieldFilters)
+	if *queryFilePath != "" {
+		qf, err := loadQueryFile(*queryFilePath)
+		if err != nil {
+			fatal(err)
+		}
+		levels = append(levels, qf.Levels...)
+		methods = append(methods, qf.Methods...)
+		searches = append(searches, qf.Search...)
+		fieldFilters = append(fieldFilters, qf.fieldFilters...)
+	}
+	if *watchQuery && *sse {
+		fatal(fmt.Errorf("--watch-query-file can't be combined with --sse"))
+	}
+	if *watchQuery && (*queryFilePath == "" || !(*follow || *followOnlyNew)) {
+		fatal(fmt.Errorf("--watch-query-file needs --query-file and --follow"))
+	}
 
 	// Determine if we should use interactive mode
 	useInteractive := *interactive && !*noInteractive && !*rawJSON
 
 	// If filters or searches are provided, assume non-interactive output is desired
-	if len(levels) > 0 || len(methods) > 0 || len(searches) > 0 {
+	if len(levels) > 0 || len(methods) > 0 || len(searches) > 0 || len(fieldFilters) > 0 || len(rawFilters) > 0 || *minLevel != "" {
+		useInteractive = false
+	}
+
+	// Interactive reloads fetch outside the sampled pipeline
+	if sample != nil {
+		useInteractive = false
+	}
+
+	// Shared output is collected and uploaded instead of shown
+	if *share {
+		useInteractive = false
+	}
+
+	// Distinct values are printed once all pages are read
+	if *distinctField != "" {
+		useInteractive = false
+	}
+
+	// Batch searches read stdin, so it can't also drive the UI
+	if *searchStdin {
+		useInteractive = false
+	}
+
+	// --head prints and exits like head(1)
+	if *head > 0 {
+		if *follow || *followOnlyNew || *sse || *searchStdin {
+			fatal(fmt.Errorf("--head can't be combined with --follow or --search-stdin"))
+		}
+		useInteractive = false
+	}
+
+	// Following streams entries to stdout like `tail -f`; the interactive
+	// equivalent is auto-refresh (a key)
+	if *followOnlyNew || *sse {
+		*follow = true
+	}
+	if *follow && *displayOrder == newestFirst {
+		fatal(fmt.Errorf("--display-order newest-first can't be combined with --follow, which prints new entries last"))
+	}
+	// --follow prints new entries as they arrive, unless the viewer was asked
+	// for explicitly: then it opens following, reloading every
+	// --follow-interval. It can't open on no entries (--follow-only-new),
+	// read the event stream (--sse), or reload a query file (--watch-query-file).
+	if *follow && (!flagWasSet("interactive") || *followOnlyNew || *sse || *watchQuery) {
+		useInteractive = false
+	}
+
+	// Custom output formats are meant for exporting. A format from the config
+	// only applies once output isn't interactive anyway.
+	if *outputTemplate != "" || (formatFlag != "" && formatFlag != "default") || *tsOnly {
+		useInteractive = false
+	}
+
+	// Interactive mode needs a terminal for both keyboard input and screen
+	// control; keystrokes come from /dev/tty when stdin is piped
+	var keyboard *os.File
+	if useInteractive && isTerminal(os.Stdout) {
+		if tty, err := openKeyboard(); err == nil {
+			keyboard = tty
+		} else {
+			useInteractive = false
+		}
+	} else {
 		useInteractive = false
 	}
 
+	// Report a closed stdout (e.g. `| head`) as EPIPE instead of being killed
+	// by SIGPIPE, so writeOutput can stop early and exit cleanly
+	if !useInteractive {
+		signal.Ignore(syscall.SIGPIPE)
+	}
+
 	// Handle login command
 	if *login {
-		if err := runLogin(*baseURL); err != nil {
+		// Existing config may hold a custom OAuth client registration
+		existing, _ := loadConfig()
+		loginClientID, loginScope := determineOAuthClient(*oauthClientID, *oauthScope, existing)
+		// Keep using the keychain once tokens have been stored there
+		keychain := *useKeychain || (existing != nil && existing.Keychain != "")
+		switch {
+		case *loginPoll != "":
+			err = runLoginPoll(*baseURL, loginClientID, loginScope, *loginPoll, keychain, os.Stdout)
+		case *rawJSON:
+			err = runLoginJSON(*baseURL, loginClientID, loginScope, os.Stdout)
+		default:
+			err = runLogin(*baseURL, loginClientID, loginScope, keychain)
+		}
+		if err != nil {
 			fatal(err)
 		}
 		return
@@ -129,25 +510,109 @@ func main() {
 		fatal(fmt.Errorf("failed to load config: %v", err))
 	}
 
+	// Handle clear-cache command
+	if *clearCacheFlag {
+		dir, err := determineCacheDir(config)
+		if err != nil {
+			fatal(err)
+		}
+		if err := clearCache(dir); err != nil {
+			fatal(fmt.Errorf("failed to clear cache: %v", err))
+		}
+		fmt.Println("✅ Cache cleared.")
+		return
+	}
+
 	// Determine base URL (flag > config > default)
 	finalBaseURL := determineBaseURL(*baseURL, config)
 
-	// Determine token (flag > config)
-	finalToken := *token
-	if finalToken == "" && config != nil {
-		finalToken = config.AccessToken
+	// Determine token (flag or token file > credential helper > config).
+	// Public streams are read without one, even when logged in.
+	if *tokenFile != "" {
+		if *token != "" {
+			fatal(fmt.Errorf("--token and --token-file can't be combined"))
+		}
+		if *token, err = readTokenFile(*tokenFile); err != nil {
+			fatal(err)
+		}
+	}
+	var finalToken string
+	if *public {
+		if *token != "" {
+			fatal(fmt.Errorf("--public can't be combined with --token or --token-file"))
+		}
+		if *streamID == "" {
+			fatal(fmt.Errorf("--public needs --stream-id (listing streams requires logging in)"))
+		}
+	} else if finalToken, err = determineToken(*token, config); err != nil {
+		fatal(err)
 	}
 
 	// If no token available, prompt for login
-	if finalToken == "" {
+	if finalToken == "" && !*public {
 		fmt.Println("No authentication found. Please run:")
 		fmt.Println("  tailstream-client --login")
 		os.Exit(1)
 	}
 
+	if listStreams {
+		if err := runListStreams(os.Stdout, config, finalBaseURL, finalToken, *rawJSON); err != nil {
+			fatal(err)
+		}
+		return
+	}
+
 	// Determine stream ID
 	finalStreamID := *streamID
 
+	// Resolve a number from the last `streams` listing
+	if *streamIndex != 0 {
+		if finalStreamID != "" || *streamName != "" {
+			fatal(fmt.Errorf("use only one of --stream-index, --stream, or --stream-id"))
+		}
+		dir, err := determineCacheDir(config)
+		if err != nil {
+			fatal(err)
+		}
+		streams, err := loadStreamList(dir, finalBaseURL)
+		if err != nil {
+			fatal(err)
+		}
+		if finalStreamID, err = streamAtIndex(streams, *streamIndex); err != nil {
+			fatal(err)
+		}
+	}
+
+	// Resolve a human-readable stream name, using the cached resolution when available
+	if name := strings.TrimSpace(*streamName); name != "" {
+		if finalStreamID != "" {
+			fatal(fmt.Errorf("use either --stream or --stream-id, not both"))
+		}
+		if config != nil && config.StreamNames[name] != "" {
+			finalStreamID = config.StreamNames[name]
+		} else {
+			streams, err := fetchUserStreams(finalBaseURL, finalToken)
+			if err != nil {
+				fatal(fmt.Errorf("failed to resolve stream %q: %v", name, err))
+			}
+			stream, err := resolveStreamName(streams, name)
+			if err != nil {
+				fatal(err)
+			}
+			finalStreamID = stream.StreamID
+
+			if config != nil {
+				if config.StreamNames == nil {
+					config.StreamNames = make(map[string]string)
+				}
+				config.StreamNames[name] = finalStreamID
+				if err := saveConfig(config); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: could not cache stream name: %v\n", err)
+				}
+			}
+		}
+	}
+
 	// If no explicit stream ID was provided via flag, show interactive selector
 	if finalStreamID == "" {
 		selectedStream, err := selectStreamInteractive(finalBaseURL, finalToken, config)
@@ -167,8 +632,56 @@ func main() {
 		}
 	}
 
+	// Per-stream defaults from the config fill in whatever the flags and
+	// query file leave unset. The flag* copies stay without them, so a
+	// reloaded query file only gets them when it leaves those unset too.
+	defaults := streamDefaults(config, finalStreamID)
+	flagMinLevel := *minLevel
+	levels, *minLevel, fieldFilters, err = applyStreamDefaults(levels, *minLevel, fieldFilters, defaults)
+	if err != nil {
+		fatal(fmt.Errorf("stream_defaults for %s: %w", finalStreamID, err))
+	}
+	if formatFlag == "" && defaults.Format != "" {
+		*outputFormat = defaults.Format
+	}
+	if lineFields = defaults.Fields; formatFlag == "" && (defaults.Format != "" || len(lineFields) > 0) {
+		buildFormatter()
+	}
+
+	client := getHTTPClient(*timeout)
+
+	// Correct relative times for local clock drift
+	if *serverTime {
+		offset, err := measureClockOffset(client, finalBaseURL)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not determine server time, using local clock: %v\n", err)
+		} else {
+			clockOffset = offset
+		}
+	}
+
+	// Guided setup fills in the time range and level from menus
+	if *guided {
+		guidedFrom, guidedLevel, err := runGuidedSelect(os.Stdin, os.Stdout)
+		if err != nil {
+			fatal(err)
+		}
+		*from = guidedFrom
+		*minLevel = guidedLevel
+
+		args := []string{"tailstream-client", "--stream-id", shellQuote(finalStreamID), "--from", shellQuote(guidedFrom)}
+		if guidedLevel != "" {
+			args = append(args, "--min-level", guidedLevel)
+		}
+		fmt.Println("Equivalent command:")
+		fmt.Printf("  %s\n\n", strings.Join(args, " "))
+	}
+
 	query := url.Values{}
-	if v := strings.TrimSpace(*from); v != "" {
+	var startTime, endTime time.Time
+	// Apply the configured default range when no explicit time bounds were given
+	fromValue := determineStartTime(*from, *to, *noDefaultRange, config)
+	if v := fromValue; v != "" {
 		parsed, err := parseTimeArg(v)
 		if err != nil {
 			fatal(err)
@@ -178,10 +691,11 @@ func main() {
 		if err != nil {
 			fatal(fmt.Errorf("failed to parse from time: %w", err))
 		}
+		startTime = t
 		query.Set("start_time", strconv.FormatInt(t.UnixMilli(), 10))
 	}
 	if v := strings.TrimSpace(*to); v != "" {
-		parsed, err := parseTimeArg(v)
+		parsed, err := parseEndTimeArg(v)
 		if err != nil {
 			fatal(err)
 		}
@@ -190,29 +704,35 @@ func main() {
 		if err != nil {
 			fatal(fmt.Errorf("failed to parse to time: %w", err))
 		}
+		endTime = t
 		query.Set("end_time", strconv.FormatInt(t.UnixMilli(), 10))
 	}
-	// Build filters for levels and methods
-	if len(levels) > 0 || len(methods) > 0 {
-		filters := make([]map[string]any, 0, len(levels)+len(methods))
-		for _, level := range levels {
-			filters = append(filters, map[string]any{
-				"field":    "level",
-				"operator": "=",
-				"value":    level,
-			})
-		}
-		for _, method := range methods {
-			filters = append(filters, map[string]any{
-				"field":    "method",
-				"operator": "=",
-				"value":    method,
-			})
+	if !*allowInverted {
+		if err := checkTimeOrder(startTime, endTime); err != nil {
+			fatal(err)
 		}
-		if filterJSON, err := json.Marshal(filters); err == nil {
-			query.Set("filters", string(filterJSON))
+	}
+	// Anchor the query to specific entry IDs (more stable than timestamps when
+	// many entries share the same timestamp)
+	if v := strings.TrimSpace(*afterID); v != "" {
+		query.Set("after_id", v)
+	}
+	if v := strings.TrimSpace(*beforeID); v != "" {
+		query.Set("before_id", v)
+	}
+	// Expand --min-level into every level at or above it
+	if v := strings.TrimSpace(*minLevel); v != "" {
+		expanded, ok := levelsAtOrAbove(v)
+		if ok {
+			levels = append(levels, expanded...)
+		} else {
+			fmt.Fprintf(os.Stderr, "Warning: unknown level %q for --min-level, not filtering by level\n", v)
 		}
 	}
+	// Build filters for levels, methods, and --filter expressions
+	if filters := buildFiltersParam(levels, methods, fieldFilters, rawFilters); filters != "" {
+		query.Set("filters", filters)
+	}
 	// Backend uses cursor-based pagination with limit and direction
 	if *perPage > 0 {
 		query.Set("limit", strconv.Itoa(*perPage))
@@ -223,86 +743,377 @@ func main() {
 
 	endpoint := strings.TrimRight(finalBaseURL, "/") + "/api/streams/" + url.PathEscape(strings.TrimSpace(finalStreamID)) + "/logs"
 
-	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
-	defer cancel()
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"?"+query.Encode(), nil)
-	if err != nil {
-		fatal(err)
+	// Print a shareable command line instead of running the query
+	if *printQuery {
+		fmt.Println(buildQueryCommand(finalBaseURL, finalStreamID, query, searches))
+		return
 	}
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Authorization", "Bearer "+finalToken)
 
-	client := getHTTPClient(*timeout)
+	// Describe the effective query instead of running it
+	if *explain {
+		fmt.Print(explainQuery(finalStreamID, query, searches))
+		return
+	}
 
-	stopSpinner := func() {}
-	if !*quiet {
-		stopSpinner = startSpinner("Fetching logs")
-		defer stopSpinner()
+	// --overall-timeout bounds the whole fetch; interactive sessions are
+	// open-ended, so it only applies to direct output
+	ctx := context.Background()
+	if *overallTimeout > 0 && !useInteractive {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *overallTimeout)
+		defer cancel()
 	}
 
-	resp, err := client.Do(req)
+	// --timeout applies to each request individually
+	reqCtx, reqCancel := context.WithTimeout(ctx, *timeout)
+	defer reqCancel()
+
+	// Direct output never asks for more than --limit entries in one page,
+	// unless entries are filtered out client-side and pages may come up short
+	clientFiltered := len(searches) > 0 || len(fieldFilters) > 0 || sample != nil
+	firstQuery := query
+	if n := pageSizeFor(*perPage, *limit, 0, clientFiltered); !useInteractive && n != *perPage {
+		firstQuery = maps.Clone(query)
+		firstQuery.Set("limit", strconv.Itoa(n))
+	}
+	api := &tailstream.Client{BaseURL: finalBaseURL, Token: finalToken, HTTPClient: client, PostQueries: *postQuery}
+	req, err := api.NewLogsRequest(reqCtx, finalStreamID, firstQuery, "")
 	if err != nil {
 		fatal(err)
 	}
-	defer resp.Body.Close()
-	stopSpinner()
+	firstURL := api.LogsURL(finalStreamID, firstQuery, "") // Cache key, also for a POST
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		body, _ := io.ReadAll(resp.Body)
-		fatal(fmt.Errorf("request failed: %s\n%s", resp.Status, strings.TrimSpace(string(body))))
+	// Shared by the initial request and every paginated fetch
+	limiter := newRateLimiter(*rateLimit)
+
+	// Optional on-disk page cache (--cache or `cache: true` in config)
+	var cache *pageCache
+	if (*useCache || (config != nil && config.Cache)) && !*noCache {
+		dir, err := determineCacheDir(config)
+		if err == nil {
+			cache, err = newPageCache(dir, *cacheTTL)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: page cache disabled: %v\n", err)
+		}
+		cache.relativeBound("start_time", fromValue)
+		cache.relativeBound("end_time", *to)
 	}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		fatal(err)
+	// --share collects the output and uploads it once everything is written.
+	// There is deliberately no default paste service.
+	var out io.Writer = os.Stdout
+	if *share {
+		if config == nil || config.ShareURL == "" {
+			fatal(fmt.Errorf("--share needs share_url in %s (there is no default paste service)", configFileName))
+		}
+		if *follow {
+			fatal(fmt.Errorf("--share can't be combined with --follow"))
+		}
+		var shared bytes.Buffer
+		out = &shared
+		defer func() {
+			if shared.Len() == 0 {
+				fmt.Fprintln(os.Stderr, "Nothing to share.")
+				return
+			}
+			contentType := "text/plain; charset=utf-8"
+			if *rawJSON {
+				contentType = "application/json"
+			}
+			link, err := uploadShare(config.ShareURL, shared.Bytes(), contentType)
+			if err != nil {
+				fatal(err)
+			}
+			fmt.Println(link)
+		}()
 	}
 
-	if *rawJSON {
-		os.Stdout.Write(body)
-		if len(body) == 0 || body[len(body)-1] != '\n' {
-			fmt.Fprintln(os.Stdout)
+	// --format json-array wraps the entries in one array, closed once all
+	// output is written (before any --share upload). A json-array from the
+	// config gives way to the default format where an array can't be written.
+	var array *jsonArrayWriter
+	if *outputFormat == jsonArrayFormat && *outputTemplate == "" && !useInteractive {
+		if *follow || *searchStdin || *rawJSON {
+			if formatFlag != "" {
+				fatal(fmt.Errorf("--format json-array can't be combined with --follow, --search-stdin, or --json"))
+			}
+			*outputFormat = "default"
+			buildFormatter()
+		} else {
+			array = &jsonArrayWriter{w: out}
+			defer array.close()
 		}
-		return
 	}
 
-	var payload logResponse
-	if err := json.Unmarshal(body, &payload); err != nil {
-		fatal(fmt.Errorf("unable to parse response JSON: %w", err))
+	// --distinct prints the collected values instead of the entries, once
+	// everything is read
+	distinct := newDistinctValues(*distinctField)
+	if distinct != nil {
+		if *follow || *searchStdin {
+			fatal(fmt.Errorf("--distinct can't be combined with --follow or --search-stdin"))
+		}
+		defer distinct.write(out, *rawJSON)
 	}
 
-	entries := payload.Data
+	// Track request cost for the summary printed to stderr in direct output mode
+	started := time.Now()
+	pagesFetched, entriesOutput := 1, 0
+	pagesFailed := 0
+	defer func() {
+		if !useInteractive && !*quiet {
+			fmt.Fprintln(os.Stderr, formatFetchSummary(entriesOutput, pagesFetched, time.Since(started)))
+		}
+		if pagesFailed > 0 {
+			fmt.Fprintf(os.Stderr, "Warning: %s; output is incomplete (use --strict to exit with an error)\n", formatFailedPages(pagesFailed))
+		}
+	}()
+
+	// pageFailed handles a failed fetch of a page after the first: fatal with
+	// --strict, otherwise a warning counted for the summary
+	pageFailed := func(err error) {
+		if *strict {
+			fatal(err)
+		}
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		pagesFailed++
+	}
 
-	if len(entries) == 0 {
-		fmt.Println("No logs matched your filters.")
+	// Batch mode: one server-side search per stdin line, sharing one fetcher
+	if *searchStdin {
+		fetcher := createFetcher(finalBaseURL, finalToken, finalStreamID, query, normalizeQueries(searches), fetchOptions{
+			Limiter: limiter,
+			Cache:   cache,
+			Context: ctx,
+			Timeout: *timeout,
+			Filters: fieldFilters,
+			Match:   match,
+			Post:    *postQuery,
+		})
+		entriesOutput, pagesFetched = runSearchBatch(os.Stdin, out, fetcher, *limit, func(entry map[string]any) string {
+			return format(redact.apply(entry))
+		}, pageFailed)
 		return
 	}
 
-	// Filter entries based on search terms
+	// Polls for entries newer than the last one printed, without the page
+	// cache since each poll must see fresh data
 	terms := normalizeQueries(searches)
-	filtered := make([]map[string]any, 0, len(entries))
-	for _, entry := range entries {
-		if len(terms) > 0 && !entryMatches(entry, terms) {
-			continue
+	followNew := func(f *follower) {
+		if !*quiet {
+			fmt.Fprintln(os.Stderr, "Following new entries (live, Ctrl+C to stop)...")
 		}
-		filtered = append(filtered, entry)
-		if *limit > 0 && len(filtered) >= *limit {
-			break
+		var watch *fileWatch
+		if *watchQuery {
+			watch = newFileWatch(*queryFilePath)
+		}
+		// reloadQueryFile re-merges an edited query file with the flags,
+		// keeping the last good query when the file doesn't parse
+		reloadQueryFile := func() {
+			qf, err := loadQueryFile(*queryFilePath)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v; keeping the previous query\n", err)
+				return
+			}
+			var reloadMinLevel string
+			levels, reloadMinLevel, fieldFilters, _ = applyStreamDefaults(append(slices.Clone(flagLevels), qf.Levels...), flagMinLevel, append(slices.Clone(flagFilters), qf.fieldFilters...), defaults)
+			if expanded, ok := levelsAtOrAbove(reloadMinLevel); ok {
+				levels = append(levels, expanded...)
+			}
+			methods = append(slices.Clone(flagMethods), qf.Methods...)
+			terms = normalizeQueries(append(slices.Clone(flagSearches), qf.Search...))
+			query.Del("filters")
+			if filters := buildFiltersParam(levels, methods, fieldFilters, rawFilters); filters != "" {
+				query.Set("filters", filters)
+			}
+			fmt.Fprintf(os.Stderr, "Reloaded query from %s\n", *queryFilePath)
+		}
+		newFetcher := func(q url.Values) pageFetcher {
+			// q was derived from query before any reload, so carry the
+			// reloaded filters over
+			if watch != nil && watch.changed() {
+				reloadQueryFile()
+				if filters := query.Get("filters"); filters != "" {
+					q.Set("filters", filters)
+				} else {
+					q.Del("filters")
+				}
+			}
+			return createFetcher(finalBaseURL, finalToken, finalStreamID, q, terms, fetchOptions{
+				Limiter: limiter,
+				Context: ctx,
+				Timeout: *timeout,
+				Filters: fieldFilters,
+				Match:   match,
+				Post:    *postQuery,
+			})
+		}
+		emitNew := func(entry map[string]any) {
+			if !sample.keep() {
+				return
+			}
+			writeOutput(out, []byte(format(redact.apply(entry))+"\n"))
+			entriesOutput++
+		}
+		if *sse {
+			err := streamSSE(finalBaseURL, finalToken, finalStreamID, f, query, terms, fetchOptions{
+				Context: ctx,
+				Filters: fieldFilters,
+				Match:   match,
+			}, emitNew)
+			if !errors.Is(err, errNoSSE) {
+				if err != nil {
+					fatal(err)
+				}
+				return
+			}
+			fmt.Fprintf(os.Stderr, "Warning: %v; polling every %s instead\n", err, *followInterval)
+		}
+		pagesFetched += followEntries(ctx, newFetcher, query, f, *followInterval, emitNew)
+	}
+
+	// --follow-only-new skips the backfill and starts streaming from now
+	if *followOnlyNew {
+		pagesFetched = 0
+		followNew(&follower{since: now()})
+		return
+	}
+
+	body, cached := cache.get(finalToken, firstURL)
+	if !cached {
+		if limiter != nil {
+			if err := limiter.Wait(ctx); err != nil {
+				fatal(err)
+			}
+		}
+
+		stopSpinner := func(bool) {}
+		if !*quiet {
+			stopSpinner = startSpinner("Fetching logs")
+			defer stopSpinner(false)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			// fatal exits without running deferred calls
+			stopSpinner(false)
+			fatal(diagnoseRequestError(finalBaseURL, err))
+		}
+		defer resp.Body.Close()
+		stopSpinner(resp.StatusCode >= 200 && resp.StatusCode < 300)
+		warnAPIVersion(resp)
+
+		// Warn when the local clock has drifted enough to skew relative time ranges
+		if !*serverTime {
+			if offset, err := clockOffsetFromResponse(resp, time.Now()); err == nil && offset.Abs() > maxClockSkew {
+				fmt.Fprintf(os.Stderr, "Warning: local clock differs from server by %s; relative times may be off (use --server-time)\n", offset.Round(time.Second))
+			}
+		}
+
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			fatal(fmt.Errorf("request failed: %s\n%s%s", resp.Status, errorBody(resp), statusHint(resp.StatusCode)))
+		}
+
+		bodyReader, err := tailstream.DecodeBody(resp)
+		if err != nil {
+			fatal(fmt.Errorf("unable to decode response body: %w", err))
+		}
+		body, err = io.ReadAll(bodyReader)
+		if err != nil {
+			fatal(err)
+		}
+		cache.put(finalToken, firstURL, body)
+	}
+
+	if *rawJSON && distinct == nil {
+		var counted tailstream.LogResponse
+		if json.Unmarshal(body, &counted) == nil {
+			entriesOutput = len(counted.Data)
+		}
+		if body, err = redact.redactResponseBody(body); err != nil {
+			fatal(fmt.Errorf("unable to redact response JSON: %w", err))
+		}
+		if len(body) == 0 || body[len(body)-1] != '\n' {
+			body = append(body, '\n')
+		}
+		writeOutput(out, body)
+		return
+	}
+
+	payload, err := tailstream.DecodeLogResponse(body)
+	if err != nil {
+		fatal(fmt.Errorf("unable to parse response JSON: %w", err))
+	}
+
+	entries := plainEntries(payload.Data)
+
+	// An empty json-array (or --distinct --json) result is still a valid
+	// (empty) array
+	if len(entries) == 0 && (!*follow || useInteractive) {
+		if array == nil && !(distinct != nil && *rawJSON) {
+			fmt.Println("No logs matched your filters.")
 		}
+		return
 	}
 
-	if len(filtered) == 0 {
-		fmt.Println("No logs matched your filters.")
+	// Filter entries based on search terms, then redact what's kept, the same
+	// order as later pages (see redactPages)
+	filtered := redact.applyAll(filterPage(entries, terms, match, fieldFilters, sample, *limit))
+
+	if len(filtered) == 0 && (!*follow || useInteractive) {
+		if array == nil && !(distinct != nil && *rawJSON) {
+			fmt.Println("No logs matched your filters.")
+		}
 		return
 	}
 
-	// Create a fetcher function for pagination
-	fetcher := createFetcher(finalBaseURL, finalToken, finalStreamID, query, terms)
+	// Create a fetcher function for pagination. In direct output each page
+	// asks only for what --limit still needs.
+	var pageSize func() int
+	if !useInteractive {
+		pageSize = func() int {
+			if sortField != "" {
+				return pageSizeFor(*perPage, *limit, len(filtered), clientFiltered)
+			}
+			return pageSizeFor(*perPage, *limit, entriesOutput, clientFiltered)
+		}
+	}
+	fetcher := createFetcher(finalBaseURL, finalToken, finalStreamID, query, terms, fetchOptions{
+		Limiter:  limiter,
+		Cache:    cache,
+		Context:  ctx,
+		Timeout:  *timeout,
+		Filters:  fieldFilters,
+		Match:    match,
+		PageSize: pageSize,
+		Post:     *postQuery,
+	})
+	fetcher = redactPages(fetcher, redact, sample)
 
 	// Get initial cursor for pagination
-	initialCursor := ""
-	if payload.Meta.NextCursor != nil {
-		initialCursor = *payload.Meta.NextCursor
+	initialCursor := payload.NextPageToken(req.URL)
+
+	// Client-side sort needs every fetched entry (bounded by --limit) up front,
+	// so collect the remaining pages before displaying anything
+	if sortField != "" {
+		if *limit <= 0 || len(filtered) < *limit {
+			pages, err := walkPages(fetcher, payload.Meta.HasMore, initialCursor, *maxPages, func(page []map[string]any) bool {
+				filtered = append(filtered, page...)
+				return *limit <= 0 || len(filtered) < *limit
+			})
+			pagesFetched += pages
+			if err != nil {
+				pageFailed(err)
+			}
+		}
+		if *limit > 0 && len(filtered) > *limit {
+			filtered = filtered[:*limit]
+		}
+		sortEntries(filtered, sortField, sortDesc)
+
+		// Everything wanted is loaded; further pages would break the ordering
+		payload.Meta.HasMore = false
+		initialCursor = ""
 	}
 
 	// Display logs
@@ -310,52 +1121,787 @@ func main() {
 		// Pass context needed for dynamic filtering
 		interactiveCtx := &InteractiveContext{
 			BaseURL:   finalBaseURL,
-			Token:     finalToken,
+			API:       api,
 			StreamID:  finalStreamID,
 			PerPage:   *perPage,
 			SortDir:   *sortDir,
-			Client:    client,
 			Endpoint:  endpoint,
 			BaseQuery: query, // Original query params (without filters)
+			Redactor:  redact,
+		}
+		interactiveCtx.ContextWindow = *contextWindow
+		interactiveCtx.CompactUI = *compactUI
+		interactiveCtx.Keyboard = keyboard
+		interactiveCtx.Prefetch = *prefetch
+		interactiveCtx.PrefetchThreshold = determinePrefetchThreshold(*prefetchAt, config)
+		interactiveCtx.Fields = defaults.Fields
+		if *follow {
+			interactiveCtx.Live = *followInterval
+		}
+		if config != nil {
+			interactiveCtx.EntryURL = config.EntryURL
 		}
 		runInteractiveMode(filtered, !*noColor, payload.Meta.HasMore, payload.Meta.Total, initialCursor, fetcher, interactiveCtx)
 	} else {
-		// Direct output mode - print current page and continue if there are more
-		for _, entry := range filtered {
-			fmt.Println(formatEntry(entry, !*noColor))
+		// Direct output mode - print the first page, then keep fetching until
+		// --limit entries are printed (unlimited when <= 0) or --max-pages is hit
+		// --follow continues from the newest entry printed
+		followFrom := &follower{}
+		emit := limitEmit(*limit, &entriesOutput, func(entry map[string]any) {
+			switch {
+			case distinct != nil:
+				distinct.add(entry)
+			case array != nil:
+				array.write(format(entry))
+			default:
+				writeOutput(out, []byte(format(entry)+"\n"))
+			}
+			followFrom.accept(entry)
+		})
+		if emit(filtered) {
+			pages, err := walkPages(fetcher, payload.Meta.HasMore, initialCursor, *maxPages, emit)
+			pagesFetched += pages
+			if err != nil {
+				pageFailed(err)
+			}
+		}
+		if *follow {
+			followNew(followFrom)
+		}
+	}
+}
+
+// filterPage returns the entries of the first page that match the search
+// terms and field filters and survive sampling, up to limit of them (all when
+// limit <= 0). Later pages are matched by the fetcher instead.
+func filterPage(entries []map[string]any, terms []string, match matchMode, filters []fieldFilter, sample *sampler, limit int) []map[string]any {
+	filtered := make([]map[string]any, 0, len(entries))
+	for _, entry := range entries {
+		if len(terms) > 0 && !match.matches(entry, terms) || !filtersMatch(entry, filters) {
+			continue
+		}
+		if !sample.keep() {
+			continue
+		}
+		filtered = append(filtered, entry)
+		if limit > 0 && len(filtered) >= limit {
+			break
 		}
+	}
+	return filtered
+}
 
-		// If there are more pages and we're not limiting output, fetch and display them
-		cursor := initialCursor
-		if payload.Meta.HasMore && (*limit <= 0 || len(filtered) < *limit) {
-			remainingLimit := *limit - len(filtered)
+// redactPages samples and redacts every page from fetcher before it reaches
+// the display. The fetcher has already matched the raw entries, so searches
+// and filters see the real values, never the masks.
+func redactPages(fetcher pageFetcher, redact *redactor, sample *sampler) pageFetcher {
+	if redact == nil && sample == nil {
+		return fetcher
+	}
+	return func(cursor string, searchQuery string) ([]map[string]any, bool, *int, string, error) {
+		entries, hasMore, total, next, err := fetcher(cursor, searchQuery)
+		return redact.applyAll(sample.apply(entries)), hasMore, total, next, err
+	}
+}
 
-			for cursor != "" {
-				moreEntries, hasMore, _, nextCursor, err := fetcher(cursor, "") // No search in direct mode
-				if err != nil {
-					fmt.Fprintf(os.Stderr, "Warning: failed to fetch next page: %v\n", err)
-					break
-				}
+// walkPages fetches the pages following an already-fetched first page and
+// passes each page's entries to emit, until emit returns false, maxPages pages
+// (counting the first) have been fetched, or the results run out. maxPages <= 0
+// means unlimited. Empty pages don't end the walk, since client-side filtering
+// can empty a page that has more results after it. Returns the number of
+// additional pages fetched, and the error that ended the walk early if a
+// page failed to fetch.
+func walkPages(fetcher pageFetcher, hasMore bool, cursor string, maxPages int, emit func([]map[string]any) bool) (int, error) {
+	pages := 0
+	var loop cursorLoop
+	for hasMore && cursor != "" && (maxPages <= 0 || pages+1 < maxPages) {
+		entries, more, _, next, err := fetcher(cursor, "") // No search in direct mode
+		if err != nil {
+			return pages, fmt.Errorf("failed to fetch page %d: %w", pages+2, err)
+		}
+		pages++
+		if !emit(entries) {
+			break
+		}
+		if more && loop.advance(cursor, next) {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", errCursorLoop)
+			break
+		}
+		hasMore, cursor = more, next
+	}
+	return pages, nil
+}
 
-				if len(moreEntries) == 0 {
-					break
+// limitEmit returns the emit function for walkPages in direct output: it
+// passes entries to write until limit of them (--limit or --head; unlimited
+// when <= 0) are written, counting them in written, and reports whether
+// another page is still needed
+func limitEmit(limit int, written *int, write func(entry map[string]any)) func([]map[string]any) bool {
+	return func(page []map[string]any) bool {
+		for _, entry := range page {
+			if limit > 0 && *written >= limit {
+				return false
+			}
+			write(entry)
+			*written++
+		}
+		return limit <= 0 || *written < limit
+	}
+}
+
+// pageSizeFor returns the page size to request when have of limit entries
+// are already collected: perPage, shrunk to the remaining count so the last
+// page doesn't over-fetch. At least one is always requested. Returns perPage
+// when either is unset, or when clientFiltered entries (--search, --filter,
+// --sample-rate) may be dropped and a shrunken page would come up short.
+func pageSizeFor(perPage, limit, have int, clientFiltered bool) int {
+	if perPage <= 0 || limit <= 0 || clientFiltered {
+		return perPage
+	}
+	return max(min(perPage, limit-have), 1)
+}
+
+// buildFiltersParam returns the JSON "filters" query param for level, method,
+// and field filters followed by raw --filters-json ones, or "" when there are
+// none
+func buildFiltersParam(levels, methods []string, fieldFilters []fieldFilter, raw []map[string]any) string {
+	if len(levels) == 0 && len(methods) == 0 && len(fieldFilters) == 0 && len(raw) == 0 {
+		return ""
+	}
+	filters := make([]map[string]any, 0, len(levels)+len(methods)+len(fieldFilters)+len(raw))
+	for _, level := range levels {
+		filters = append(filters, map[string]any{
+			"field":    "level",
+			"operator": "=",
+			"value":    level,
+		})
+	}
+	for _, method := range methods {
+		filters = append(filters, map[string]any{
+			"field":    "method",
+			"operator": "=",
+			"value":    method,
+		})
+	}
+	for _, f := range fieldFilters {
+		filters = append(filters, f.serverFilter())
+	}
+	filters = append(filters, raw...)
+	filterJSON, err := json.Marshal(filters)
+	if err != nil {
+		return ""
+	}
+	return string(filterJSON)
+}
+
+// distinctValues collects the unique values of one field for --distinct. A
+// nil collector is a no-op.
+type distinctValues struct {
+	field string
+	seen  map[string]bool
+}
+
+// newDistinctValues returns a collector for field, or nil when field is empty
+func newDistinctValues(field string) *distinctValues {
+	field = strings.TrimSpace(field)
+	if field == "" {
+		return nil
+	}
+	return &distinctValues{field: field, seen: make(map[string]bool)}
+}
+
+// add records the entry's value for the field; entries without one are skipped
+func (d *distinctValues) add(entry map[string]any) {
+	if v, ok := tailstream.LogEntry(entry).Field(d.field); ok {
+		if s := tailstream.Stringify(v); s != "" {
+			d.seen[s] = true
+		}
+	}
+}
+
+// write prints the sorted values one per line, or as a JSON array
+func (d *distinctValues) write(w io.Writer, asJSON bool) {
+	if d == nil {
+		return
+	}
+	values := slices.Sorted(maps.Keys(d.seen))
+	if asJSON {
+		if values == nil {
+			values = []string{}
+		}
+		b, _ := json.Marshal(values)
+		writeOutput(w, append(b, '\n'))
+		return
+	}
+	for _, v := range values {
+		writeOutput(w, []byte(v+"\n"))
+	}
+}
+
+// sampler thins out entries for --sample-rate by keeping every k-th one, so
+// the sample is spread evenly across the pages fetched. A nil sampler keeps
+// everything.
+type sampler struct {
+	every int // Keep one entry out of this many
+	seen  int
+}
+
+// newSampler returns a sampler for the given rate, or nil when rate is 0
+func newSampler(rate float64) (*sampler, error) {
+	if rate == 0 {
+		return nil, nil
+	}
+	if rate < 0 || rate > 1 {
+		return nil, fmt.Errorf("invalid --sample-rate %v: must be between 0 and 1", rate)
+	}
+	return &sampler{every: max(1, int(math.Round(1/rate)))}, nil
+}
+
+// keep reports whether the next entry belongs to the sample
+func (s *sampler) keep() bool {
+	if s == nil {
+		return true
+	}
+	s.seen++
+	return (s.seen-1)%s.every == 0
+}
+
+// apply returns the sampled entries of a page
+func (s *sampler) apply(entries []map[string]any) []map[string]any {
+	if s == nil {
+		return entries
+	}
+	kept := make([]map[string]any, 0, len(entries)/s.every+1)
+	for _, entry := range entries {
+		if s.keep() {
+			kept = append(kept, entry)
+		}
+	}
+	return kept
+}
+
+// runSearchBatch runs one search per non-empty input line, printing up to
+// limit matching entries per line prefixed with "[line] ". Fetch errors are
+// passed to failed and the batch moves on to the next line. It returns the
+// number of entries printed and pages fetched.
+func runSearchBatch(in io.Reader, out io.Writer, fetcher pageFetcher, limit int, format entryFormatter, failed func(error)) (entries, pages int) {
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		term := strings.TrimSpace(scanner.Text())
+		if term == "" {
+			continue
+		}
+
+		printed := 0
+		cursor := ""
+		var loop cursorLoop
+	pages:
+		for {
+			page, hasMore, _, next, err := fetcher(cursor, term)
+			if err != nil {
+				failed(fmt.Errorf("search %q failed: %w", term, err))
+				break
+			}
+			pages++
+			for _, entry := range page {
+				writeOutput(out, []byte("["+term+"] "+format(entry)+"\n"))
+				entries++
+				printed++
+				if limit > 0 && printed >= limit {
+					break pages
 				}
+			}
+			if !hasMore || next == "" {
+				break
+			}
+			if loop.advance(cursor, next) {
+				fmt.Fprintf(os.Stderr, "Warning: search %q: %v\n", term, errCursorLoop)
+				break
+			}
+			cursor = next
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to read stdin: %v\n", err)
+	}
+	return entries, pages
+}
+
+// follower tracks the newest entry printed while following a stream, so each
+// poll only emits entries that haven't been printed yet
+type follower struct {
+	since time.Time       // Timestamp of the newest entry seen (zero = none yet)
+	seen  map[string]bool // Keys of the entries seen at exactly since
+}
+
+// accept records an entry and reports whether it is new. Entries without a
+// timestamp are deduplicated by key alone.
+func (f *follower) accept(entry map[string]any) bool {
+	key := followKey(entry)
+	t, ok := tailstream.LogEntry(entry).Timestamp()
+	switch {
+	case !ok || t.Equal(f.since):
+		if f.seen[key] {
+			return false
+		}
+	case t.Before(f.since):
+		return false
+	default:
+		f.since = t
+		f.seen = nil
+	}
+	if f.seen == nil {
+		f.seen = make(map[string]bool)
+	}
+	f.seen[key] = true
+	return true
+}
+
+// query returns the params for the next poll: oldest first, starting at the
+// newest entry seen (inclusive, so entries sharing its timestamp aren't lost)
+func (f *follower) query(base url.Values) url.Values {
+	q := url.Values{}
+	for k, v := range base {
+		q[k] = v
+	}
+	q.Del("end_time")
+	q.Del("after_id")
+	q.Del("before_id")
+	q.Set("start_time", strconv.FormatInt(f.since.UnixMilli(), 10))
+	q.Set("direction", "asc")
+	return q
+}
+
+// followKey identifies an entry for deduplication, by id when it has one
+func followKey(entry map[string]any) string {
+	if id := tailstream.LogEntry(entry).String("id"); id != "" {
+		return id
+	}
+	data, _ := json.Marshal(entry)
+	return string(data)
+}
 
-				// Print entries from this page
-				for _, entry := range moreEntries {
-					fmt.Println(formatEntry(entry, !*noColor))
-					remainingLimit--
-					if *limit > 0 && remainingLimit <= 0 {
-						return
-					}
+// followEntries polls every interval for entries newer than f.since and
+// passes them to emit in chronological order, until ctx is done. Fetch errors
+// are reported on stderr and retried on the next poll. It returns the number
+// of pages fetched.
+func followEntries(ctx context.Context, newFetcher func(url.Values) pageFetcher, base url.Values, f *follower, interval time.Duration, emit func(map[string]any)) int {
+	if f.since.IsZero() {
+		f.since = now()
+	}
+	pages := 0
+	for {
+		fetcher := newFetcher(f.query(base))
+		cursor := ""
+		var loop cursorLoop
+		for {
+			entries, hasMore, _, next, err := fetcher(cursor, "")
+			if err != nil {
+				if ctx.Err() != nil {
+					return pages
+				}
+				fmt.Fprintf(os.Stderr, "Warning: failed to fetch new entries: %v\n", err)
+				break
+			}
+			pages++
+			for _, entry := range entries {
+				if f.accept(entry) {
+					emit(entry)
 				}
+			}
+			if !hasMore || next == "" {
+				break
+			}
+			if loop.advance(cursor, next) {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", errCursorLoop)
+				break
+			}
+			cursor = next
+		}
+
+		select {
+		case <-ctx.Done():
+			return pages
+		case <-time.After(interval):
+		}
+	}
+}
+
+// buildQueryCommand reconstructs a canonical tailstream-client command line from
+// the effective query. Time bounds are rendered as absolute RFC3339 timestamps so
+// the command returns the same window when run later or by someone else.
+func buildQueryCommand(baseURL, streamID string, query url.Values, searches []string) string {
+	args := []string{"tailstream-client"}
+	if baseURL != "" && baseURL != defaultBaseURL {
+		args = append(args, "--base-url", shellQuote(baseURL))
+	}
+	args = append(args, "--stream-id", shellQuote(streamID))
+
+	for _, param := range []struct{ key, flag string }{
+		{"start_time", "--from"},
+		{"end_time", "--to"},
+	} {
+		if v := query.Get(param.key); v != "" {
+			if ms, err := strconv.ParseInt(v, 10, 64); err == nil {
+				args = append(args, param.flag, shellQuote(time.UnixMilli(ms).UTC().Format(time.RFC3339Nano)))
+			}
+		}
+	}
+	if v := query.Get("after_id"); v != "" {
+		args = append(args, "--after-id", shellQuote(v))
+	}
+	if v := query.Get("before_id"); v != "" {
+		args = append(args, "--before-id", shellQuote(v))
+	}
 
-				if !hasMore {
-					break
+	if raw := query.Get("filters"); raw != "" {
+		var filters []map[string]any
+		decoder := json.NewDecoder(strings.NewReader(raw))
+		decoder.UseNumber() // Keep numbers exactly as sent
+		if err := decoder.Decode(&filters); err == nil {
+			// Filters --filter can't express (ranges, lists, nested groups)
+			// are passed back as they are
+			var rawFilters []map[string]any
+			for _, f := range filters {
+				field, operator := tailstream.Stringify(f["field"]), tailstream.Stringify(f["operator"])
+				value := tailstream.Stringify(f["value"])
+				switch {
+				case !scalarComparison(f) && field != "q":
+					rawFilters = append(rawFilters, f)
+				case field == "level" && operator == "=":
+					args = append(args, "--level", shellQuote(value))
+				case field == "method" && operator == "=":
+					args = append(args, "--method", shellQuote(value))
+				case field == "q":
+					args = append(args, "--search", shellQuote(value))
+				default:
+					args = append(args, "--filter", shellQuote(field+operator+value))
+				}
+			}
+			if len(rawFilters) > 0 {
+				if rawJSON, err := json.Marshal(rawFilters); err == nil {
+					args = append(args, "--filters-json", shellQuote(string(rawJSON)))
 				}
+			}
+		}
+	}
+	for _, search := range searches {
+		if strings.TrimSpace(search) != "" {
+			args = append(args, "--search", shellQuote(search))
+		}
+	}
+
+	if v := query.Get("direction"); v != "" && v != "desc" {
+		args = append(args, "--sort", v)
+	}
+	if v := query.Get("limit"); v != "" && v != "200" {
+		args = append(args, "--per-page", v)
+	}
+	return strings.Join(args, " ")
+}
+
+// scalarComparison reports whether a server filter is a plain comparison of
+// one field against one value, with an operator --filter accepts
+func scalarComparison(f map[string]any) bool {
+	if _, ok := f["field"].(string); !ok || len(f) != 3 || !slices.Contains(filterOperators, tailstream.Stringify(f["operator"])) {
+		return false
+	}
+	switch f["value"].(type) {
+	case string, json.Number, bool:
+		return true
+	}
+	return false
+}
+
+// explainQuery describes the effective query in plain language: the resolved
+// time range, each server-side filter, client-side search terms, sort
+// direction, and page size.
+func explainQuery(streamID string, query url.Values, searches []string) string {
+	var b strings.Builder
+	line := func(label, value string) {
+		if label != "" {
+			label += ":"
+		}
+		fmt.Fprintf(&b, "%-12s %s\n", label, value)
+	}
+
+	line("Stream", streamID)
+
+	bound := func(key, open string) string {
+		if ms, err := strconv.ParseInt(query.Get(key), 10, 64); err == nil {
+			return time.UnixMilli(ms).UTC().Format(time.RFC3339Nano)
+		}
+		return open
+	}
+	line("Time range", bound("start_time", "beginning of stream")+" → "+bound("end_time", "now"))
+	if v := query.Get("after_id"); v != "" {
+		line("After ID", v)
+	}
+	if v := query.Get("before_id"); v != "" {
+		line("Before ID", v)
+	}
+
+	var filters []string
+	if raw := query.Get("filters"); raw != "" {
+		var parsed []map[string]any
+		if err := json.Unmarshal([]byte(raw), &parsed); err != nil {
+			filters = append(filters, fmt.Sprintf("unreadable (%v)", err))
+		}
+		for _, f := range parsed {
+			operator := tailstream.Stringify(f["operator"])
+			if operator == "" {
+				operator = "="
+			}
+			filters = append(filters, fmt.Sprintf("%s %s %q", tailstream.Stringify(f["field"]), operator, tailstream.Stringify(f["value"])))
+		}
+	}
+	if len(filters) == 0 {
+		filters = []string{"none"}
+	}
+	for i, f := range filters {
+		label := ""
+		if i == 0 {
+			label = "Filters"
+		}
+		line(label, f)
+	}
+
+	var terms []string
+	for _, search := range searches {
+		if search = strings.TrimSpace(search); search != "" {
+			terms = append(terms, fmt.Sprintf("%q", search))
+		}
+	}
+	if len(terms) > 0 {
+		line("Search", strings.Join(terms, " and ")+" (case-insensitive, matched client-side)")
+	}
+
+	direction := query.Get("direction")
+	if direction == "" {
+		direction = "desc"
+	}
+	if direction == "asc" {
+		line("Order", "oldest first (asc)")
+	} else {
+		line("Order", "newest first (desc)")
+	}
+	if v := query.Get("limit"); v != "" {
+		line("Page size", v+" entries per request")
+	}
+	return b.String()
+}
+
+// shellQuote quotes a value for safe use in a POSIX shell command line
+func shellQuote(value string) string {
+	if value != "" && strings.IndexFunc(value, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./:=,@+", r))
+	}) == -1 {
+		return value
+	}
+	return "'" + strings.ReplaceAll(value, "'", `'"'"'`) + "'"
+}
 
-				cursor = nextCursor
+// guidedTimeRanges are the time range presets offered by --select
+var guidedTimeRanges = []struct{ label, from string }{
+	{"Last 15 minutes", "-15m"},
+	{"Last hour", "-1h"},
+	{"Last 24 hours", "-24h"},
+	{"Last 7 days", "-168h"},
+}
+
+// guidedLevels are the minimum level presets offered by --select ("" = all levels)
+var guidedLevels = []struct{ label, level string }{
+	{"All levels", ""},
+	{"DEBUG and above", "DEBUG"},
+	{"INFO and above", "INFO"},
+	{"WARN and above", "WARN"},
+	{"ERROR and above", "ERROR"},
+}
+
+// runGuidedSelect prompts for a time range and minimum level from preset menus.
+// Pressing Enter picks the default (last hour, all levels).
+func runGuidedSelect(in io.Reader, out io.Writer) (from string, minLevel string, err error) {
+	scanner := bufio.NewScanner(in)
+
+	prompt := func(title string, labels []string, defaultIdx int) (int, error) {
+		fmt.Fprintln(out, title)
+		for i, label := range labels {
+			marker := ""
+			if i == defaultIdx {
+				marker = " (default)"
 			}
+			fmt.Fprintf(out, "[%d] %s%s\n", i+1, label, marker)
+		}
+		fmt.Fprintf(out, "Select (enter number, or press Enter for default [%d]): ", defaultIdx+1)
+		scanner.Scan()
+		selection := strings.TrimSpace(scanner.Text())
+		fmt.Fprintln(out)
+		if selection == "" {
+			return defaultIdx, nil
+		}
+		idx, err := strconv.Atoi(selection)
+		if err != nil || idx < 1 || idx > len(labels) {
+			return 0, fmt.Errorf("invalid selection %q", selection)
+		}
+		return idx - 1, nil
+	}
+
+	rangeLabels := make([]string, len(guidedTimeRanges))
+	for i, r := range guidedTimeRanges {
+		rangeLabels[i] = r.label
+	}
+	rangeIdx, err := prompt("Time range:", rangeLabels, 1)
+	if err != nil {
+		return "", "", err
+	}
+
+	levelLabels := make([]string, len(guidedLevels))
+	for i, l := range guidedLevels {
+		levelLabels[i] = l.label
+	}
+	levelIdx, err := prompt("Log level:", levelLabels, 0)
+	if err != nil {
+		return "", "", err
+	}
+
+	return guidedTimeRanges[rangeIdx].from, guidedLevels[levelIdx].level, nil
+}
+
+// releaseInfo is the subset of a GitHub-style latest-release response used by
+// `version --check`
+type releaseInfo struct {
+	TagName string `json:"tag_name"`
+	HTMLURL string `json:"html_url"`
+}
+
+// runUpdateCheck reports whether a newer release than Version is available
+func runUpdateCheck(out io.Writer) error {
+	endpoint := updateCheckURL
+	if v := os.Getenv("TAILSTREAM_UPDATE_URL"); v != "" {
+		endpoint = v
+	}
+	release, err := fetchLatestRelease(endpoint)
+	if err != nil {
+		return fmt.Errorf("update check failed: %w", err)
+	}
+	switch c, ok := compareVersions(release.TagName, Version); {
+	case !ok:
+		fmt.Fprintf(out, "\nLatest release is %s (this build, %s, can't be compared)\n", release.TagName, Version)
+	case c > 0:
+		fmt.Fprintf(out, "\nA newer version is available: %s\n", release.TagName)
+	default:
+		fmt.Fprintf(out, "\nYou're up to date.\n")
+		return nil
+	}
+	if release.HTMLURL != "" {
+		fmt.Fprintf(out, "Download: %s\n", release.HTMLURL)
+	}
+	return nil
+}
+
+// fetchLatestRelease queries the latest-release endpoint, failing fast
+func fetchLatestRelease(endpoint string) (*releaseInfo, error) {
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "tailstream-client/"+Version)
+	resp, err := getHTTPClient(updateCheckTimeout).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", endpoint, resp.Status)
+	}
+	var release releaseInfo
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return nil, fmt.Errorf("unable to parse release info: %w", err)
+	}
+	if release.TagName == "" {
+		return nil, fmt.Errorf("release info from %s has no tag_name", endpoint)
+	}
+	return &release, nil
+}
+
+// compareVersions compares two semantic versions such as "v1.2.3" or
+// "1.3.0-rc.1", returning -1, 0, or 1. A pre-release sorts before its
+// release. It is false when either isn't a version (e.g. a "dev" build).
+func compareVersions(a, b string) (int, bool) {
+	va, ok := parseSemver(a)
+	if !ok {
+		return 0, false
+	}
+	vb, ok := parseSemver(b)
+	if !ok {
+		return 0, false
+	}
+	for i := range 3 {
+		if c := cmp.Compare(va.core[i], vb.core[i]); c != 0 {
+			return c, true
+		}
+	}
+	switch {
+	case va.pre == vb.pre:
+		return 0, true
+	case va.pre == "":
+		return 1, true
+	case vb.pre == "":
+		return -1, true
+	}
+	return comparePrerelease(va.pre, vb.pre), true
+}
+
+// comparePrerelease orders pre-release tags by SemVer's rules: identifiers
+// compare in turn, numeric ones as numbers and below alphanumeric ones, and
+// a tag that runs out first sorts first (rc.2 < rc.10 < rc.10.1)
+func comparePrerelease(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := range min(len(as), len(bs)) {
+		an, aErr := strconv.ParseUint(as[i], 10, 64)
+		bn, bErr := strconv.ParseUint(bs[i], 10, 64)
+		var c int
+		switch {
+		case aErr == nil && bErr == nil:
+			c = cmp.Compare(an, bn)
+		case aErr == nil:
+			c = -1
+		case bErr == nil:
+			c = 1
+		default:
+			c = strings.Compare(as[i], bs[i])
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(len(as), len(bs))
+}
+
+// semver is a parsed major.minor.patch version with an optional pre-release
+type semver struct {
+	core [3]int
+	pre  string
+}
+
+// parseSemver parses "v1.2.3", "1.2", or "1.2.3-rc.1"; build metadata after
+// "+" is ignored and missing minor/patch numbers are zero
+func parseSemver(value string) (semver, bool) {
+	var v semver
+	value = strings.TrimPrefix(strings.TrimSpace(value), "v")
+	value, _, _ = strings.Cut(value, "+")
+	value, v.pre, _ = strings.Cut(value, "-")
+	parts := strings.Split(value, ".")
+	if len(parts) > 3 {
+		return v, false
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return v, false
 		}
+		v.core[i] = n
 	}
+	return v, true
 }
@@ -1,7 +1,22 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
+
+	"tailstream/client/pkg/tailstream"
 )
 
 // Basic smoke test to ensure main compiles and flags work
@@ -9,3 +24,589 @@ func TestMainFlags(t *testing.T) {
 	// Just ensure the package compiles correctly
 	// Actual CLI testing is done via test-client.sh
 }
+
+func TestBuildQueryCommand(t *testing.T) {
+	query := url.Values{}
+	query.Set("start_time", "1704067200000") // 2024-01-01T00:00:00Z
+	query.Set("end_time", "1704070800000")   // 2024-01-01T01:00:00Z
+	query.Set("filters", `[{"field":"level","operator":"=","value":"ERROR"},{"field":"method","operator":"=","value":"POST"}]`)
+	query.Set("direction", "asc")
+	query.Set("limit", "200")
+
+	got := buildQueryCommand(defaultBaseURL, "my-stream", query, []string{"db timeout"})
+	expected := "tailstream-client --stream-id my-stream --from 2024-01-01T00:00:00Z --to 2024-01-01T01:00:00Z --level ERROR --method POST --search 'db timeout' --sort asc"
+	if got != expected {
+		t.Errorf("unexpected command:\n got: %s\nwant: %s", got, expected)
+	}
+
+	// Filters on other fields round-trip as --filter expressions
+	query = url.Values{}
+	query.Set("filters", `[{"field":"fields.http.status","operator":">=","value":500}]`)
+	got = buildQueryCommand(defaultBaseURL, "s", query, nil)
+	expected = "tailstream-client --stream-id s --filter 'fields.http.status>=500'"
+	if got != expected {
+		t.Errorf("unexpected command:\n got: %s\nwant: %s", got, expected)
+	}
+
+	// Only equality maps to --level and --method
+	query = url.Values{}
+	query.Set("filters", `[{"field":"level","operator":"!=","value":"DEBUG"},{"field":"method","operator":"=","value":"GET"}]`)
+	got = buildQueryCommand(defaultBaseURL, "s", query, nil)
+	expected = "tailstream-client --stream-id s --filter 'level!=DEBUG' --method GET"
+	if got != expected {
+		t.Errorf("unexpected command:\n got: %s\nwant: %s", got, expected)
+	}
+
+	// Filters --filter can't express come back as --filters-json, numbers intact
+	query = url.Values{}
+	query.Set("filters", `[{"field":"status","operator":"=","value":500},{"field":"duration_ms","operator":"between","value":[100,250.5]}]`)
+	got = buildQueryCommand(defaultBaseURL, "s", query, nil)
+	expected = `tailstream-client --stream-id s --filter status=500 --filters-json '[{"field":"duration_ms","operator":"between","value":[100,250.5]}]'`
+	if got != expected {
+		t.Errorf("unexpected command:\n got: %s\nwant: %s", got, expected)
+	}
+
+	// Non-default base URL is included
+	got = buildQueryCommand("https://logs.example.com", "s", url.Values{}, nil)
+	expected = "tailstream-client --base-url https://logs.example.com --stream-id s"
+	if got != expected {
+		t.Errorf("unexpected command:\n got: %s\nwant: %s", got, expected)
+	}
+}
+
+func TestExplainQuery(t *testing.T) {
+	query := url.Values{}
+	query.Set("start_time", "1704067200000") // 2024-01-01T00:00:00Z
+	query.Set("filters", `[{"field":"level","operator":"=","value":"ERROR"},{"field":"method","operator":"=","value":"POST"}]`)
+	query.Set("direction", "asc")
+	query.Set("limit", "50")
+
+	got := explainQuery("my-stream", query, []string{"db timeout"})
+	for _, want := range []string{
+		"Stream:      my-stream\n",
+		"Time range:  2024-01-01T00:00:00Z → now\n",
+		"Filters:     level = \"ERROR\"\n",
+		"             method = \"POST\"\n",
+		"Search:      \"db timeout\"",
+		"Order:       oldest first (asc)\n",
+		"Page size:   50 entries per request\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected explanation to contain %q, got:\n%s", want, got)
+		}
+	}
+
+	// An empty query has an open range and no filters
+	got = explainQuery("s", url.Values{}, nil)
+	if !strings.Contains(got, "beginning of stream → now") || !strings.Contains(got, "Filters:     none") {
+		t.Errorf("unexpected explanation for empty query:\n%s", got)
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"simple", "simple"},
+		{"-1h", "-1h"},
+		{"with space", "'with space'"},
+		{"it's", `'it'"'"'s'`},
+		{"", "''"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := shellQuote(tt.input); got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRunGuidedSelect(t *testing.T) {
+	var out bytes.Buffer
+
+	// Defaults on empty input
+	from, level, err := runGuidedSelect(strings.NewReader("\n\n"), &out)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if from != "-1h" || level != "" {
+		t.Errorf("expected defaults (-1h, all levels), got (%s, %s)", from, level)
+	}
+
+	// Explicit choices
+	from, level, err = runGuidedSelect(strings.NewReader("3\n4\n"), &out)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if from != "-24h" || level != "WARN" {
+		t.Errorf("expected (-24h, WARN), got (%s, %s)", from, level)
+	}
+
+	// Out of range selection
+	if _, _, err := runGuidedSelect(strings.NewReader("9\n"), &out); err == nil {
+		t.Error("expected error for invalid selection")
+	}
+}
+
+func TestRunSearchBatch(t *testing.T) {
+	// Each search returns two pages of one entry each
+	var searched []string
+	fetcher := func(cursor string, searchQuery string) ([]map[string]any, bool, *int, string, error) {
+		searched = append(searched, searchQuery+"@"+cursor)
+		entry := map[string]any{"message": searchQuery + " hit"}
+		if cursor == "" {
+			return []map[string]any{entry}, true, nil, "next", nil
+		}
+		return []map[string]any{entry}, false, nil, "", nil
+	}
+	format := func(entry map[string]any) string { return tailstream.Stringify(entry["message"]) }
+
+	var out bytes.Buffer
+	failed := func(err error) { t.Errorf("unexpected fetch error: %v", err) }
+	entries, pages := runSearchBatch(strings.NewReader("timeout\n\n  refused \n"), &out, fetcher, 0, format, failed)
+
+	expected := "[timeout] timeout hit\n[timeout] timeout hit\n[refused] refused hit\n[refused] refused hit\n"
+	if out.String() != expected {
+		t.Errorf("unexpected output:\n got: %q\nwant: %q", out.String(), expected)
+	}
+	if entries != 4 || pages != 4 {
+		t.Errorf("expected 4 entries over 4 pages, got %d over %d", entries, pages)
+	}
+	if strings.Join(searched, ",") != "timeout@,timeout@next,refused@,refused@next" {
+		t.Errorf("unexpected fetch sequence: %v", searched)
+	}
+
+	// The limit applies per input line
+	out.Reset()
+	entries, _ = runSearchBatch(strings.NewReader("a\nb\n"), &out, fetcher, 1, format, failed)
+	if entries != 2 || out.String() != "[a] a hit\n[b] b hit\n" {
+		t.Errorf("unexpected limited output (%d entries): %q", entries, out.String())
+	}
+
+	// A failed search is reported and the batch moves on
+	out.Reset()
+	var errs []error
+	flaky := func(cursor string, searchQuery string) ([]map[string]any, bool, *int, string, error) {
+		if searchQuery == "bad" {
+			return nil, false, nil, "", errors.New("boom")
+		}
+		return fetcher(cursor, searchQuery)
+	}
+	entries, _ = runSearchBatch(strings.NewReader("bad\nok\n"), &out, flaky, 1, format, func(err error) { errs = append(errs, err) })
+	if entries != 1 || len(errs) != 1 || !strings.Contains(errs[0].Error(), `search "bad" failed: boom`) {
+		t.Errorf("unexpected result after a failed search: %d entries, errors %v", entries, errs)
+	}
+}
+
+// pagedFetcher serves the given pages in order, following cursors "1", "2", ...
+func pagedFetcher(pages [][]map[string]any, fetched *int) pageFetcher {
+	return func(cursor string, searchQuery string) ([]map[string]any, bool, *int, string, error) {
+		idx, _ := strconv.Atoi(cursor)
+		*fetched++
+		next := strconv.Itoa(idx + 1)
+		hasMore := idx+1 < len(pages)
+		if !hasMore {
+			next = ""
+		}
+		return pages[idx], hasMore, nil, next, nil
+	}
+}
+
+func TestWalkPages(t *testing.T) {
+	entry := map[string]any{"message": "x"}
+	pages := [][]map[string]any{
+		{entry, entry}, // First page, already fetched by the caller
+		{entry},
+		{}, // Emptied by client-side filtering; must not end the walk
+		{entry, entry},
+	}
+
+	collect := func(limit, maxPages int) (entries, fetched int) {
+		extra, err := walkPages(pagedFetcher(pages, &fetched), true, "1", maxPages, func(page []map[string]any) bool {
+			entries += len(page)
+			return limit <= 0 || entries < limit
+		})
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if extra != fetched {
+			t.Errorf("walkPages reported %d pages, fetched %d", extra, fetched)
+		}
+		return entries, fetched
+	}
+
+	// Unlimited walks every remaining page, including past an empty one
+	if entries, fetched := collect(0, 0); entries != 3 || fetched != 3 {
+		t.Errorf("unlimited: got %d entries over %d pages, want 3 over 3", entries, fetched)
+	}
+	if entries, fetched := collect(-1, 0); entries != 3 || fetched != 3 {
+		t.Errorf("limit -1: got %d entries over %d pages, want 3 over 3", entries, fetched)
+	}
+
+	// A limit stops once emit has enough
+	if entries, fetched := collect(1, 0); entries != 1 || fetched != 1 {
+		t.Errorf("limit 1: got %d entries over %d pages, want 1 over 1", entries, fetched)
+	}
+
+	// --max-pages counts the first page
+	if _, fetched := collect(0, 1); fetched != 0 {
+		t.Errorf("max-pages 1: fetched %d extra pages, want 0", fetched)
+	}
+	if entries, fetched := collect(0, 3); entries != 1 || fetched != 2 {
+		t.Errorf("max-pages 3: got %d entries over %d pages, want 1 over 2", entries, fetched)
+	}
+
+	// Nothing is fetched when the first page was the last
+	var fetched int
+	if got, _ := walkPages(pagedFetcher(pages, &fetched), false, "", 0, func([]map[string]any) bool { return true }); got != 0 || fetched != 0 {
+		t.Error("expected no fetches without more pages")
+	}
+
+	// A server that keeps returning the same cursor stops the walk
+	stuck := 0
+	repeating := func(cursor string, searchQuery string) ([]map[string]any, bool, *int, string, error) {
+		stuck++
+		if stuck > 10 {
+			t.Fatal("walkPages did not detect the cursor loop")
+		}
+		return []map[string]any{entry}, true, nil, "same", nil
+	}
+	if got, _ := walkPages(repeating, true, "same", 0, func([]map[string]any) bool { return true }); got != 1 {
+		t.Errorf("repeating cursor: fetched %d pages, want 1", got)
+	}
+
+	// A failed fetch ends the walk and is returned with the page number
+	failing := func(cursor string, searchQuery string) ([]map[string]any, bool, *int, string, error) {
+		if cursor == "2" {
+			return nil, false, nil, "", errors.New("502 Bad Gateway")
+		}
+		return pagedFetcher(pages, &fetched)(cursor, searchQuery)
+	}
+	got, err := walkPages(failing, true, "1", 0, func([]map[string]any) bool { return true })
+	if got != 1 || err == nil || err.Error() != "failed to fetch page 3: 502 Bad Gateway" {
+		t.Errorf("failing page: fetched %d pages, error %v", got, err)
+	}
+}
+
+func TestHeadStopsFetching(t *testing.T) {
+	// --head 3 over pages of two: the first page is already printed, the
+	// second completes the three, and nothing further is requested
+	const head = 3
+	entry := map[string]any{"message": "x"}
+	pages := [][]map[string]any{{entry, entry}, {entry, entry}, {entry, entry}, {entry, entry}}
+	printed, written, fetched := 0, 0, 0
+	emit := limitEmit(head, &printed, func(map[string]any) { written++ })
+	if !emit(pages[0]) {
+		t.Fatal("expected the first page to leave room for more")
+	}
+	extra, err := walkPages(pagedFetcher(pages, &fetched), true, "1", 0, emit)
+	if err != nil || printed != head || written != head || extra != 1 || fetched != 1 {
+		t.Errorf("printed %d entries over %d extra pages (%d fetched, err %v), want %d over 1", written, extra, fetched, err, head)
+	}
+
+	// The request for the remainder asks for just one entry
+	if n := pageSizeFor(200, head, 2, false); n != 1 {
+		t.Errorf("expected a page size of 1 for the last entry, got %d", n)
+	}
+}
+
+func TestRedactAfterMatching(t *testing.T) {
+	// A search for a value the redactor masks finds it on the first page
+	// and on later pages alike, and both print it masked
+	page := func() []map[string]any {
+		return []map[string]any{{"message": "login user=alice"}, {"message": "login user=bob"}}
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"data": page(), "meta": map[string]any{"has_more": false}})
+	}))
+	defer server.Close()
+
+	redact, err := newRedactor(nil, []string{`user=\w+`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	terms := normalizeQueries([]string{"alice"})
+
+	first := redact.applyAll(filterPage(page(), terms, "", nil, nil, 0))
+	fetcher := redactPages(createFetcher(server.URL, "token", "s", url.Values{}, terms, fetchOptions{}), redact, nil)
+	second, _, _, _, err := fetcher("2", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i, entries := range [][]map[string]any{first, second} {
+		if len(entries) != 1 || entries[0]["message"] != "login ***" {
+			t.Errorf("page %d: got %v, want the one match, redacted", i+1, entries)
+		}
+	}
+}
+
+func TestFollowerAccept(t *testing.T) {
+	f := &follower{since: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	entry := func(id, ts string) map[string]any {
+		return map[string]any{"id": id, "timestamp": ts}
+	}
+
+	if f.accept(entry("old", "2023-12-31T23:59:59Z")) {
+		t.Error("entry older than since was accepted")
+	}
+	if !f.accept(entry("a", "2024-01-01T00:00:00Z")) {
+		t.Error("new entry at since was rejected")
+	}
+	if f.accept(entry("a", "2024-01-01T00:00:00Z")) {
+		t.Error("repeated entry was accepted")
+	}
+	if !f.accept(entry("b", "2024-01-01T00:00:00Z")) {
+		t.Error("second entry sharing the timestamp was rejected")
+	}
+	if !f.accept(entry("c", "2024-01-01T00:00:05Z")) {
+		t.Error("newer entry was rejected")
+	}
+	if got := f.since.Format(time.RFC3339); got != "2024-01-01T00:00:05Z" {
+		t.Errorf("since = %s, want 2024-01-01T00:00:05Z", got)
+	}
+
+	q := f.query(url.Values{"end_time": {"1"}, "before_id": {"x"}, "limit": {"50"}})
+	if q.Get("start_time") != "1704067205000" || q.Get("direction") != "asc" || q.Get("limit") != "50" {
+		t.Errorf("unexpected follow query %v", q)
+	}
+	if q.Has("end_time") || q.Has("before_id") {
+		t.Errorf("follow query kept upper bounds: %v", q)
+	}
+}
+
+func TestFollowEntries(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Each poll returns everything since the requested start_time
+	all := []map[string]any{
+		{"id": "1", "timestamp": "2024-01-01T00:00:01Z"},
+		{"id": "2", "timestamp": "2024-01-01T00:00:02Z"},
+		{"id": "3", "timestamp": "2024-01-01T00:00:02Z"},
+	}
+	polls := 0
+	newFetcher := func(q url.Values) pageFetcher {
+		polls++
+		if polls == 3 {
+			cancel()
+		}
+		start, _ := strconv.ParseInt(q.Get("start_time"), 10, 64)
+		visible := 1 + polls // A new entry arrives before each poll
+		return func(cursor string, searchQuery string) ([]map[string]any, bool, *int, string, error) {
+			var page []map[string]any
+			for _, e := range all[:min(visible, len(all))] {
+				if ts, _ := tailstream.LogEntry(e).Timestamp(); ts.UnixMilli() >= start {
+					page = append(page, e)
+				}
+			}
+			return page, false, nil, "", nil
+		}
+	}
+
+	var got []string
+	f := &follower{since: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	pages := followEntries(ctx, newFetcher, url.Values{}, f, time.Millisecond, func(entry map[string]any) {
+		got = append(got, entry["id"].(string))
+	})
+	if strings.Join(got, ",") != "1,2,3" {
+		t.Errorf("followed entries = %v, want [1 2 3]", got)
+	}
+	if pages != 3 {
+		t.Errorf("fetched %d pages, want 3", pages)
+	}
+}
+
+func TestSampler(t *testing.T) {
+	if s, err := newSampler(0); s != nil || err != nil {
+		t.Errorf("newSampler(0) = %v, %v; want nil sampler", s, err)
+	}
+	for _, rate := range []float64{-0.5, 1.5} {
+		if _, err := newSampler(rate); err == nil {
+			t.Errorf("newSampler(%v) succeeded, want error", rate)
+		}
+	}
+
+	// The nil sampler keeps everything
+	var none *sampler
+	page := []map[string]any{{"n": 0}, {"n": 1}, {"n": 2}}
+	if got := none.apply(page); len(got) != 3 {
+		t.Errorf("nil sampler kept %d of 3 entries", len(got))
+	}
+
+	// Every k-th entry is kept, counting across pages
+	s, err := newSampler(0.25)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kept []int
+	for p := 0; p < 3; p++ {
+		var page []map[string]any
+		for i := 0; i < 5; i++ {
+			page = append(page, map[string]any{"n": p*5 + i})
+		}
+		for _, entry := range s.apply(page) {
+			kept = append(kept, entry["n"].(int))
+		}
+	}
+	if want := []int{0, 4, 8, 12}; !reflect.DeepEqual(kept, want) {
+		t.Errorf("sampled %v, want %v", kept, want)
+	}
+}
+
+func TestListFlag(t *testing.T) {
+	var levels listFlag
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Var(&levels, "level", "")
+	if err := fs.Parse([]string{"--level", "ERROR, WARN,", "--level", "FATAL", "--level", ","}); err != nil {
+		t.Fatal(err)
+	}
+	if want := (listFlag{"ERROR", "WARN", "FATAL"}); !reflect.DeepEqual(levels, want) {
+		t.Errorf("levels = %v, want %v", levels, want)
+	}
+}
+
+func TestBuildFiltersParam(t *testing.T) {
+	if got := buildFiltersParam(nil, nil, nil, nil); got != "" {
+		t.Errorf("no filters: got %q, want empty", got)
+	}
+	got := buildFiltersParam([]string{"ERROR"}, []string{"GET"}, []fieldFilter{{Field: "status", Operator: ">=", Value: "500"}}, nil)
+	want := `[{"field":"level","operator":"=","value":"ERROR"},{"field":"method","operator":"=","value":"GET"},{"field":"status","operator":"\u003e=","value":500}]`
+	if got != want {
+		t.Errorf("buildFiltersParam = %s\nwant %s", got, want)
+	}
+
+	// --filters-json filters come last, passed through as given
+	raw, err := parseFiltersJSON(`[{"field":"duration_ms","operator":"between","value":[100,250.5]}]`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got = buildFiltersParam([]string{"ERROR"}, nil, nil, raw)
+	want = `[{"field":"level","operator":"=","value":"ERROR"},{"field":"duration_ms","operator":"between","value":[100,250.5]}]`
+	if got != want {
+		t.Errorf("buildFiltersParam with raw filters = %s\nwant %s", got, want)
+	}
+}
+
+func TestPageSizeFor(t *testing.T) {
+	tests := []struct {
+		perPage, limit, have int
+		filtered             bool
+		want                 int
+	}{
+		{200, 200, 0, false, 200},
+		{200, 50, 0, false, 50},    // First page shrinks to --limit
+		{200, 450, 400, false, 50}, // Last page only fetches the rest
+		{200, 50, 50, false, 1},    // Never asks for nothing
+		{200, 0, 400, false, 200},  // Unlimited
+		{0, 50, 0, false, 0},       // No --per-page, server default
+		{200, 50, 0, true, 200},    // Client-side filters may drop entries
+		{200, 450, 400, true, 200},
+	}
+	for _, tt := range tests {
+		if got := pageSizeFor(tt.perPage, tt.limit, tt.have, tt.filtered); got != tt.want {
+			t.Errorf("pageSizeFor(%d, %d, %d, %t) = %d, want %d", tt.perPage, tt.limit, tt.have, tt.filtered, got, tt.want)
+		}
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+		ok   bool
+	}{
+		{"v1.2.3", "1.2.3", 0, true},
+		{"v1.10.0", "v1.9.9", 1, true},
+		{"v1.2", "v1.2.1", -1, true},
+		{"v2.0.0-rc.1", "v2.0.0", -1, true},
+		{"v2.0.0", "v2.0.0-rc.1", 1, true},
+		{"v2.0.0-rc.2", "v2.0.0-rc.10", -1, true},
+		{"v2.0.0-rc.10", "v2.0.0-rc.2", 1, true},
+		{"v2.0.0-rc.1", "v2.0.0-rc.1.1", -1, true},
+		{"v2.0.0-1", "v2.0.0-alpha", -1, true},
+		{"v2.0.0-alpha", "v2.0.0-beta", -1, true},
+		{"v1.0.0+build.5", "v1.0.0", 0, true},
+		{"v1.0.0", "dev", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := compareVersions(tt.a, tt.b)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("compareVersions(%q, %q) = %d, %v; want %d, %v", tt.a, tt.b, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestRunUpdateCheck(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"tag_name": "v1.4.0",
+			"html_url": "https://example.com/releases/v1.4.0",
+		})
+	}))
+	defer server.Close()
+	t.Setenv("TAILSTREAM_UPDATE_URL", server.URL)
+
+	check := func(version string) string {
+		t.Helper()
+		saved := Version
+		Version = version
+		defer func() { Version = saved }()
+		var out bytes.Buffer
+		if err := runUpdateCheck(&out); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return out.String()
+	}
+	if got := check("v1.3.2"); !strings.Contains(got, "newer version is available: v1.4.0") || !strings.Contains(got, "https://example.com/releases/v1.4.0") {
+		t.Errorf("expected an update notice with the link, got %q", got)
+	}
+	if got := check("v1.4.0"); !strings.Contains(got, "up to date") {
+		t.Errorf("expected up to date, got %q", got)
+	}
+	if got := check("dev"); !strings.Contains(got, "can't be compared") {
+		t.Errorf("expected a dev build notice, got %q", got)
+	}
+
+	t.Setenv("TAILSTREAM_UPDATE_URL", server.URL+"/missing\x7f")
+	if err := runUpdateCheck(io.Discard); err == nil {
+		t.Error("expected an error for a bad endpoint")
+	}
+}
+
+func TestDistinctValues(t *testing.T) {
+	if newDistinctValues(" ") != nil {
+		t.Fatal("expected no collector without a field")
+	}
+	var none *distinctValues
+	none.write(io.Discard, false) // No-op
+
+	d := newDistinctValues("status")
+	for _, entry := range []map[string]any{
+		{"status": 500.0},
+		{"fields": map[string]any{"status": "200"}},
+		{"status": 404.0},
+		{"status": 500.0},
+		{"message": "no status"},
+	} {
+		d.add(entry)
+	}
+	var out bytes.Buffer
+	d.write(&out, false)
+	if out.String() != "200\n404\n500\n" {
+		t.Errorf("unexpected lines: %q", out.String())
+	}
+	out.Reset()
+	d.write(&out, true)
+	if out.String() != "[\"200\",\"404\",\"500\"]\n" {
+		t.Errorf("unexpected JSON: %q", out.String())
+	}
+
+	out.Reset()
+	newDistinctValues("status").write(&out, true)
+	if out.String() != "[]\n" {
+		t.Errorf("expected an empty array, got %q", out.String())
+	}
+}
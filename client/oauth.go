@@ -12,46 +12,59 @@
 // - Interactive stream selection from user's available streams
 // - Logout functionality (clearing stored credentials)
 // - Opening the verification URL in the user's browser
+// - Optionally keeping tokens in the OS keychain instead of the config file
+// - Scriptable two-step login (--login --json, then --login --poll CODE)
 
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"net/http"
+	"math/rand/v2"
 	"net/url"
 	"os"
+	"os/exec"
+	"runtime"
 	"strings"
 	"time"
+
+	"tailstream/client/pkg/tailstream"
 )
 
 const (
-	clientID = "tailstream-client"
+	defaultClientID     = "tailstream-client"
+	defaultScope        = "stream:read"
+	defaultPollInterval = 5 // Seconds between token polls when the server gave no interval
+
+	// Bounds on the server-requested poll interval, so a buggy or hostile
+	// server can't make the client hammer the token endpoint (or stall it)
+	minPollInterval = 1 * time.Second
+	maxPollInterval = 60 * time.Second
+	slowDownStep    = 5 * time.Second // Added on "slow_down", per RFC 8628
 )
 
-// DeviceCodeResponse represents the response from the device code request
-type DeviceCodeResponse struct {
-	DeviceCode              string `json:"device_code"`
-	UserCode                string `json:"user_code"`
-	VerificationURI         string `json:"verification_uri"`
-	VerificationURIComplete string `json:"verification_uri_complete"`
-	ExpiresIn               int    `json:"expires_in"`
-	Interval                int    `json:"interval"`
+// pollSleep waits between token polls; tests replace it to avoid real delays
+var pollSleep = time.Sleep
+
+// pollDelay returns how long to wait between token polls: the interval (in
+// seconds) clamped to [minPollInterval, maxPollInterval], plus up to 10%
+// jitter (jitter is in [0, 1)) so clients don't poll in lockstep
+func pollDelay(interval time.Duration, jitter float64) time.Duration {
+	interval = min(max(interval, minPollInterval), maxPollInterval)
+	return interval + time.Duration(jitter*float64(interval)/10)
 }
 
+// DeviceCodeResponse represents the response from the device code request
+type DeviceCodeResponse = tailstream.DeviceCodeResponse
+
 // TokenResponse represents the response from the token exchange
-type TokenResponse struct {
-	AccessToken  string `json:"access_token"`
-	TokenType    string `json:"token_type"`
-	ExpiresIn    int    `json:"expires_in"`
-	RefreshToken string `json:"refresh_token"`
-	Scope        string `json:"scope"`
-	Error        string `json:"error"`
-}
+type TokenResponse = tailstream.TokenResponse
 
-// runLogin executes the OAuth device flow
-func runLogin(baseURL string) error {
+// runLogin executes the OAuth device flow using the given OAuth client ID and scope
+func runLogin(baseURL, clientID, scope string, useKeychain bool) error {
 	if baseURL == "" {
 		baseURL = defaultBaseURL
 	}
@@ -60,19 +73,27 @@ func runLogin(baseURL string) error {
 	fmt.Println()
 
 	// Step 1: Request device code
-	deviceResp, err := requestDeviceCode(baseURL)
+	deviceResp, err := requestDeviceCode(baseURL, clientID, scope)
 	if err != nil {
 		return fmt.Errorf("failed to request device code: %v", err)
 	}
 
 	// Step 2: Show user instructions
-	fmt.Printf("Visit: %s\n", deviceResp.VerificationURI)
-	fmt.Printf("Enter code: %s\n", deviceResp.UserCode)
+	// Prefer the complete URI when the server provides it, since it embeds the code
+	if deviceResp.VerificationURIComplete != "" {
+		fmt.Println("Open this link to authorize (the code is already included):")
+		fmt.Printf("  %s\n", deviceResp.VerificationURIComplete)
+		fmt.Println()
+		fmt.Printf("Or visit %s and enter code: %s\n", deviceResp.VerificationURI, deviceResp.UserCode)
+	} else {
+		fmt.Printf("Visit: %s\n", deviceResp.VerificationURI)
+		fmt.Printf("Enter code: %s\n", deviceResp.UserCode)
+	}
 	fmt.Println()
 	fmt.Print("Waiting for authorization... ⏳")
 
 	// Step 3: Poll for token
-	token, err := pollForToken(baseURL, deviceResp.DeviceCode, deviceResp.Interval)
+	token, err := pollForToken(baseURL, clientID, deviceResp.DeviceCode, deviceResp.Interval)
 	if err != nil {
 		return fmt.Errorf("authorization failed: %v", err)
 	}
@@ -80,15 +101,8 @@ func runLogin(baseURL string) error {
 	fmt.Println("\n✅ Logged in successfully!")
 
 	// Step 4: Save config
-	config := &ClientConfig{
-		BaseURL:      baseURL,
-		AccessToken:  token.AccessToken,
-		RefreshToken: token.RefreshToken,
-		UpdatedAt:    time.Now().Format(time.RFC3339),
-	}
-
-	if err := saveConfig(config); err != nil {
-		return fmt.Errorf("failed to save config: %v", err)
+	if err := saveLoginConfig(baseURL, clientID, scope, token, useKeychain); err != nil {
+		return err
 	}
 
 	configPath, _ := getConfigPath()
@@ -99,6 +113,88 @@ func runLogin(baseURL string) error {
 	return nil
 }
 
+// runLoginJSON starts the device flow and writes the device code response as
+// JSON, without waiting. A script can show the code to a human and finish
+// with runLoginPoll.
+func runLoginJSON(baseURL, clientID, scope string, out io.Writer) error {
+	if baseURL == "" {
+		baseURL = defaultBaseURL
+	}
+	deviceResp, err := requestDeviceCode(baseURL, clientID, scope)
+	if err != nil {
+		return fmt.Errorf("failed to request device code: %v", err)
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(deviceResp)
+}
+
+// runLoginPoll waits for the device code to be authorized, saves the tokens
+// to the config file, and writes the token response as JSON
+func runLoginPoll(baseURL, clientID, scope, deviceCode string, useKeychain bool, out io.Writer) error {
+	if baseURL == "" {
+		baseURL = defaultBaseURL
+	}
+	token, err := pollForToken(baseURL, clientID, deviceCode, defaultPollInterval)
+	if err != nil {
+		return fmt.Errorf("authorization failed: %v", err)
+	}
+	if err := saveLoginConfig(baseURL, clientID, scope, token, useKeychain); err != nil {
+		return err
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(token)
+}
+
+// saveLoginConfig stores freshly issued tokens in the config, keeping the
+// user's other settings. With useKeychain the tokens go to the OS keychain
+// when one is available.
+func saveLoginConfig(baseURL, clientID, scope string, token *TokenResponse, useKeychain bool) error {
+	existing, err := loadConfig()
+	if err != nil && !os.IsNotExist(err) {
+		fmt.Fprintf(os.Stderr, "Warning: existing config is unreadable (%v); replacing it\n", err)
+	}
+	config := applyLogin(existing, baseURL, clientID, scope, token)
+	if existing != nil && existing.Keychain != "" && (!useKeychain || existing.Keychain != baseURL) {
+		deleteKeychainTokens(existing.Keychain) // The old tokens are no longer referenced
+	}
+	if useKeychain {
+		if err := storeTokensInKeychain(config, baseURL); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v; storing tokens in the config file\n", err)
+		}
+	}
+
+	if err := saveConfig(config); err != nil {
+		return fmt.Errorf("failed to save config: %v", err)
+	}
+	return nil
+}
+
+// applyLogin returns a copy of existing (which may be nil) with the server,
+// tokens, and OAuth registration of a new login. Everything else the user
+// configured is kept.
+func applyLogin(existing *ClientConfig, baseURL, clientID, scope string, token *TokenResponse) *ClientConfig {
+	config := &ClientConfig{}
+	if existing != nil {
+		*config = *existing
+	}
+	config.BaseURL = baseURL
+	config.AccessToken = token.AccessToken
+	config.RefreshToken = token.RefreshToken
+	config.Keychain = "" // Set again if the tokens go to the keychain
+	config.UpdatedAt = time.Now().Format(time.RFC3339)
+	// Remember non-default OAuth registrations for future logins
+	config.ClientID, config.Scope = "", ""
+	if clientID != defaultClientID {
+		config.ClientID = clientID
+	}
+	if scope != defaultScope {
+		config.Scope = scope
+	}
+	return config
+}
+
 // runLogout removes stored credentials
 func runLogout() error {
 	path, err := getConfigPath()
@@ -106,6 +202,10 @@ func runLogout() error {
 		return err
 	}
 
+	if config, err := loadConfig(); err == nil && config.Keychain != "" {
+		deleteKeychainTokens(config.Keychain)
+	}
+
 	if err := os.Remove(path); err != nil {
 		if os.IsNotExist(err) {
 			fmt.Println("No stored credentials found.")
@@ -118,78 +218,69 @@ func runLogout() error {
 	return nil
 }
 
+// openBrowser opens the URL in the user's default browser
+func openBrowser(target string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", target)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", target)
+	default:
+		cmd = exec.Command("xdg-open", target)
+	}
+	return cmd.Start()
+}
+
 // requestDeviceCode initiates the OAuth Device Code Flow
-func requestDeviceCode(baseURL string) (*DeviceCodeResponse, error) {
+func requestDeviceCode(baseURL, clientID, scope string) (*DeviceCodeResponse, error) {
 	// Ensure the base URL doesn't have trailing slash for consistent URL construction
 	baseURL = strings.TrimRight(baseURL, "/")
-
-	data := url.Values{
-		"client_id": {clientID},
-		"scope":     {"stream:read"},
-	}
-
-	client := getHTTPClient(10 * time.Second)
 	endpoint := baseURL + "/api/oauth/device/code"
 
-	resp, err := client.PostForm(endpoint, data)
-	if err != nil {
+	deviceResp, err := newAPIClient(baseURL, "", 10*time.Second).RequestDeviceCode(context.Background(), clientID, scope)
+	var statusErr *tailstream.StatusError
+	var urlErr *url.Error
+	switch {
+	case errors.As(err, &statusErr):
+		return nil, fmt.Errorf("device code request failed: %s\nEndpoint: %s\nResponse: %s", statusErr.Status, endpoint, statusErr.Body)
+	case errors.As(err, &urlErr):
 		return nil, fmt.Errorf("failed to connect to %s: %v", endpoint, err)
+	case err != nil:
+		return nil, err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("device code request failed: %s\nEndpoint: %s\nResponse: %s", resp.Status, endpoint, string(body))
-	}
-
-	var deviceResp DeviceCodeResponse
-	if err := json.NewDecoder(resp.Body).Decode(&deviceResp); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %v", err)
-	}
-
-	return &deviceResp, nil
+	return deviceResp, nil
 }
 
 // pollForToken polls the token endpoint until authorization is complete
-func pollForToken(baseURL, deviceCode string, interval int) (*TokenResponse, error) {
+func pollForToken(baseURL, clientID, deviceCode string, interval int) (*TokenResponse, error) {
 	// Ensure the base URL doesn't have trailing slash for consistent URL construction
 	baseURL = strings.TrimRight(baseURL, "/")
 
-	data := url.Values{
-		"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
-		"device_code": {deviceCode},
-		"client_id":   {clientID},
-	}
-
 	timeout := time.Now().Add(10 * time.Minute)
-	client := getHTTPClient(10 * time.Second)
-	endpoint := baseURL + "/api/oauth/device/token"
+	client := newAPIClient(baseURL, "", 10*time.Second)
 
-	for time.Now().Before(timeout) {
-		resp, err := client.PostForm(endpoint, data)
-		if err != nil {
-			time.Sleep(time.Duration(interval) * time.Second)
-			continue
-		}
-
-		var tokenResp TokenResponse
-		if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
-			resp.Body.Close()
-			time.Sleep(time.Duration(interval) * time.Second)
-			continue
-		}
-		resp.Body.Close()
+	wait := time.Duration(interval) * time.Second
+	sleep := func() { pollSleep(pollDelay(wait, rand.Float64())) }
 
-		if tokenResp.Error == "authorization_pending" {
-			time.Sleep(time.Duration(interval) * time.Second)
-			continue
-		}
-
-		if tokenResp.Error != "" {
-			return nil, fmt.Errorf("oauth error: %s", tokenResp.Error)
+	for time.Now().Before(timeout) {
+		token, err := client.PollToken(context.Background(), clientID, deviceCode)
+		var oauthErr *tailstream.OAuthError
+		switch {
+		case err == nil:
+			return token, nil
+		case !errors.As(err, &oauthErr):
+			// Network or decoding trouble; try again
+			sleep()
+		case oauthErr.Code == tailstream.ErrCodeAuthorizationPending:
+			sleep()
+		case oauthErr.Code == tailstream.ErrCodeSlowDown:
+			// The server asks for a longer interval from now on
+			wait = max(wait, minPollInterval) + slowDownStep
+			sleep()
+		default:
+			return nil, err
 		}
-
-		return &tokenResp, nil
 	}
 
 	return nil, fmt.Errorf("authorization timeout")
@@ -6,6 +6,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestRequestDeviceCode(t *testing.T) {
@@ -22,7 +23,7 @@ func TestRequestDeviceCode(t *testing.T) {
 		if err := r.ParseForm(); err != nil {
 			t.Fatal(err)
 		}
-		if r.FormValue("client_id") != clientID {
+		if r.FormValue("client_id") != defaultClientID {
 			t.Errorf("unexpected client_id: %s", r.FormValue("client_id"))
 		}
 
@@ -39,7 +40,7 @@ func TestRequestDeviceCode(t *testing.T) {
 	defer server.Close()
 
 	// Test the function
-	result, err := requestDeviceCode(server.URL)
+	result, err := requestDeviceCode(server.URL, defaultClientID, defaultScope)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -61,7 +62,7 @@ func TestRequestDeviceCodeError(t *testing.T) {
 	defer server.Close()
 
 	// Test the function
-	_, err := requestDeviceCode(server.URL)
+	_, err := requestDeviceCode(server.URL, defaultClientID, defaultScope)
 	if err == nil {
 		t.Fatal("expected error for unauthorized response")
 	}
@@ -95,7 +96,8 @@ func TestPollForTokenSuccess(t *testing.T) {
 	defer server.Close()
 
 	// Test the function with short interval
-	result, err := pollForToken(server.URL, "test-device-code", 0)
+	skipPollSleep(t)
+	result, err := pollForToken(server.URL, defaultClientID, "test-device-code", 0)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -119,7 +121,8 @@ func TestPollForTokenOAuthError(t *testing.T) {
 	defer server.Close()
 
 	// Test the function
-	_, err := pollForToken(server.URL, "test-device-code", 0)
+	skipPollSleep(t)
+	_, err := pollForToken(server.URL, defaultClientID, "test-device-code", 0)
 	if err == nil {
 		t.Fatal("expected error for access_denied")
 	}
@@ -128,3 +131,134 @@ func TestPollForTokenOAuthError(t *testing.T) {
 	}
 }
 
+
+func TestRequestDeviceCodeCustomClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Fatal(err)
+		}
+		if r.FormValue("client_id") != "self-hosted" {
+			t.Errorf("unexpected client_id: %s", r.FormValue("client_id"))
+		}
+		if r.FormValue("scope") != "stream:read stream:write" {
+			t.Errorf("unexpected scope: %s", r.FormValue("scope"))
+		}
+		json.NewEncoder(w).Encode(DeviceCodeResponse{DeviceCode: "code"})
+	}))
+	defer server.Close()
+
+	if _, err := requestDeviceCode(server.URL, "self-hosted", "stream:read stream:write"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestRunLoginJSON(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(DeviceCodeResponse{
+			DeviceCode:              "dev-123",
+			UserCode:                "ABCD-EFGH",
+			VerificationURI:         "https://example.com/activate",
+			VerificationURIComplete: "https://example.com/activate?code=ABCD-EFGH",
+			ExpiresIn:               600,
+			Interval:                5,
+		})
+	}))
+	defer server.Close()
+
+	var out strings.Builder
+	if err := runLoginJSON(server.URL, defaultClientID, defaultScope, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got DeviceCodeResponse
+	if err := json.Unmarshal([]byte(out.String()), &got); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, out.String())
+	}
+	if got.DeviceCode != "dev-123" || got.UserCode != "ABCD-EFGH" || got.ExpiresIn != 600 {
+		t.Errorf("unexpected device code output: %+v", got)
+	}
+	if !strings.Contains(out.String(), `"verification_uri_complete"`) {
+		t.Errorf("expected snake_case keys in output, got:\n%s", out.String())
+	}
+}
+
+// skipPollSleep makes token polling return immediately for the rest of the
+// test, and returns the delays that would have been slept
+func skipPollSleep(t *testing.T) *[]time.Duration {
+	var slept []time.Duration
+	orig := pollSleep
+	pollSleep = func(d time.Duration) { slept = append(slept, d) }
+	t.Cleanup(func() { pollSleep = orig })
+	return &slept
+}
+
+func TestPollDelay(t *testing.T) {
+	tests := []struct {
+		interval time.Duration
+		jitter   float64
+		want     time.Duration
+	}{
+		{0, 0, minPollInterval},               // Floor
+		{5 * time.Second, 0, 5 * time.Second}, // Server interval honored
+		{5 * time.Second, 0.5, 5250 * time.Millisecond},
+		{time.Hour, 0, maxPollInterval}, // Ceiling
+	}
+	for _, tt := range tests {
+		if got := pollDelay(tt.interval, tt.jitter); got != tt.want {
+			t.Errorf("pollDelay(%v, %v) = %v, want %v", tt.interval, tt.jitter, got, tt.want)
+		}
+	}
+}
+
+func TestPollForTokenSlowDown(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			json.NewEncoder(w).Encode(TokenResponse{Error: "slow_down"})
+			return
+		}
+		json.NewEncoder(w).Encode(TokenResponse{AccessToken: "token"})
+	}))
+	defer server.Close()
+
+	slept := skipPollSleep(t)
+	if _, err := pollForToken(server.URL, defaultClientID, "code", 2); err != nil {
+		t.Fatal(err)
+	}
+	if len(*slept) != 1 || (*slept)[0] < 7*time.Second {
+		t.Errorf("slow_down should raise the interval to at least 7s, slept %v", *slept)
+	}
+}
+
+func TestApplyLogin(t *testing.T) {
+	existing := &ClientConfig{
+		BaseURL:        "https://old.example.com",
+		AccessToken:    "old",
+		Keychain:       "https://old.example.com",
+		ClientID:       "custom",
+		DefaultRange:   "-1h",
+		StreamNames:    map[string]string{"api": "s1"},
+		OutputFormat:   "ndjson",
+		StreamDefaults: map[string]StreamSettings{"s1": {Format: "short"}},
+	}
+	token := &TokenResponse{AccessToken: "new", RefreshToken: "refresh"}
+
+	config := applyLogin(existing, "https://app.example.com", defaultClientID, defaultScope, token)
+	if config.BaseURL != "https://app.example.com" || config.AccessToken != "new" || config.RefreshToken != "refresh" || config.UpdatedAt == "" {
+		t.Errorf("expected the login to be applied, got %+v", config)
+	}
+	if config.Keychain != "" || config.ClientID != "" {
+		t.Errorf("expected the keychain ref and old client ID to be reset, got %+v", config)
+	}
+	if config.DefaultRange != "-1h" || config.StreamNames["api"] != "s1" || config.OutputFormat != "ndjson" || config.StreamDefaults["s1"].Format != "short" {
+		t.Errorf("expected user settings to be kept, got %+v", config)
+	}
+	if existing.AccessToken != "old" {
+		t.Error("expected the existing config not to be modified")
+	}
+
+	if config := applyLogin(nil, "https://app.example.com", "other", defaultScope, token); config.ClientID != "other" || config.AccessToken != "new" {
+		t.Errorf("expected a fresh config without an existing one, got %+v", config)
+	}
+}
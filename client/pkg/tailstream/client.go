@@ -0,0 +1,151 @@
+// Package tailstream is a Go client for the Tailstream API.
+//
+// It covers what the tailstream-client CLI needs and is usable on its own:
+//   - Listing the user's streams (Client.Streams)
+//   - Querying logs one page at a time (Client.Logs) or across every page
+//     (Client.LogPages), or building the page request to send yourself
+//     (Client.NewLogsRequest)
+//   - The OAuth device flow used to obtain an access token
+//     (Client.RequestDeviceCode, Client.PollToken)
+//
+// A Client is safe for concurrent use. Its HTTPClient can be replaced to
+// configure TLS, timeouts, or a test transport.
+package tailstream
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Client talks to a Tailstream deployment on behalf of one access token
+type Client struct {
+	BaseURL    string       // e.g. "https://app.tailstream.io"
+	Token      string       // OAuth access token; empty for the OAuth endpoints
+	HTTPClient *http.Client // nil uses http.DefaultClient
+
+	// PostQueries sends log queries as POST form bodies rather than GET
+	// query strings. Queries too long for a URL are POSTed regardless.
+	PostQueries bool
+}
+
+// NewClient returns a client for baseURL authenticating with token
+func NewClient(baseURL, token string) *Client {
+	return &Client{BaseURL: baseURL, Token: token}
+}
+
+// StatusError is returned when the API answers with a non-2xx status
+type StatusError struct {
+	StatusCode int
+	Status     string // e.g. "401 Unauthorized"
+	Body       string // Response body, for the server's explanation
+}
+
+func (e *StatusError) Error() string {
+	if e.Body == "" {
+		return "request failed: " + e.Status
+	}
+	return fmt.Sprintf("request failed: %s: %s", e.Status, e.Body)
+}
+
+// Stream represents a user's stream from the API
+type Stream struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	StreamID    string `json:"stream_id"`
+	Description string `json:"description"`
+}
+
+// Streams lists the streams the token can read
+func (c *Client) Streams(ctx context.Context) ([]Stream, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.endpoint("/api/user/streams"), nil)
+	if err != nil {
+		return nil, err
+	}
+	var streamsResp struct {
+		Streams []Stream `json:"streams"`
+	}
+	if err := c.doJSON(req, &streamsResp); err != nil {
+		return nil, err
+	}
+	return streamsResp.Streams, nil
+}
+
+// endpoint returns the absolute URL for an API path
+func (c *Client) endpoint(path string) string {
+	return strings.TrimRight(c.BaseURL, "/") + path
+}
+
+// Do sends an authenticated request, turning non-2xx responses into a
+// *StatusError. The caller closes the body of a successful response.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	if req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", "application/json")
+	}
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		var body []byte
+		if decoded, err := DecodeBody(resp); err == nil {
+			body, _ = io.ReadAll(decoded)
+		}
+		return nil, &StatusError{StatusCode: resp.StatusCode, Status: resp.Status, Body: strings.TrimSpace(string(body))}
+	}
+	return resp, nil
+}
+
+// doJSON sends req and decodes the (possibly compressed) JSON response into v.
+// Numbers in untyped values decode as json.Number (see LogEntry).
+func (c *Client) doJSON(req *http.Request, v any) error {
+	resp, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := DecodeBody(resp)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(body)
+	decoder.UseNumber()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// DecodeBody returns a reader for the response body that transparently
+// decompresses gzip or deflate content encodings. Go's transport only does
+// this itself when it added Accept-Encoding, which proxies don't always respect.
+func DecodeBody(resp *http.Response) (io.Reader, error) {
+	switch strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))) {
+	case "gzip", "x-gzip":
+		return gzip.NewReader(resp.Body)
+	case "deflate":
+		// HTTP "deflate" should be zlib-wrapped, but some servers send raw DEFLATE
+		buffered := bufio.NewReader(resp.Body)
+		if header, err := buffered.Peek(2); err == nil && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+			return zlib.NewReader(buffered)
+		}
+		return flate.NewReader(buffered), nil
+	default:
+		return resp.Body, nil
+	}
+}
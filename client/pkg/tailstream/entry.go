@@ -0,0 +1,179 @@
+package tailstream
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LogEntry is a single log entry: the JSON object returned by the API,
+// typically with raw_message, timestamp, and a parsed fields object.
+// Numbers are json.Number rather than float64, so 64-bit IDs survive intact.
+//
+// Its shape varies by source: the level may be top-level or inside 'fields',
+// the message may be raw_message, message, or msg, and timestamps may be
+// RFC3339 strings or epoch numbers. The accessors resolve and coerce these
+// in one place.
+type LogEntry map[string]any
+
+// messageFields are the keys holding an entry's log line, most specific first
+var messageFields = []string{"raw_message", "message", "msg", "body", "description"}
+
+// timestampFields are the keys checked for an entry's timestamp, in order
+var timestampFields = []string{"timestamp", "time", "created_at", "datetime", "logged_at"}
+
+// String returns the first non-empty top-level value among keys (each also
+// tried in lower case), as a string
+func (e LogEntry) String(keys ...string) string {
+	for _, k := range keys {
+		if v, ok := e[k]; ok {
+			if s := Stringify(v); s != "" {
+				return s
+			}
+		}
+		if v, ok := e[strings.ToLower(k)]; ok {
+			if s := Stringify(v); s != "" {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+// Field resolves a field from the entry's parsed 'fields' object, falling
+// back to the top level. Dotted paths like "http.status" or
+// "fields.http.status" walk into nested objects.
+func (e LogEntry) Field(path string) (any, bool) {
+	if fields, ok := e["fields"].(map[string]any); ok {
+		if val, exists := lookupPath(fields, path); exists {
+			return val, true
+		}
+	}
+	return lookupPath(e, path)
+}
+
+// FieldString returns Field as a string, or "" when it's missing
+func (e LogEntry) FieldString(path string) string {
+	v, _ := e.Field(path)
+	return Stringify(v)
+}
+
+// Level returns the entry's level in upper case, or "" when it has none
+func (e LogEntry) Level() string {
+	return strings.ToUpper(e.FieldString("level"))
+}
+
+// Message returns the entry's log line
+func (e LogEntry) Message() string {
+	return e.String(messageFields...)
+}
+
+// TimeText returns the entry's timestamp as sent, or ""
+func (e LogEntry) TimeText() string {
+	return e.String(timestampFields...)
+}
+
+// Timestamp returns the entry's timestamp from an RFC3339 string, a Unix
+// epoch number (seconds or milliseconds), or a numeric timestamp_ms field.
+// It is the shared way to order entries by time (sort-by, follow, merges).
+func (e LogEntry) Timestamp() (time.Time, bool) {
+	for _, key := range timestampFields {
+		if v, ok := e[key].(string); ok {
+			if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+				return t, true
+			}
+			continue
+		}
+		if v, ok := number(e[key]); ok {
+			// Anything past 1e12 is only plausible as milliseconds
+			if v > 1e12 {
+				return time.UnixMilli(int64(v)), true
+			}
+			return time.Unix(int64(v), 0), true
+		}
+	}
+	switch v := e["timestamp_ms"].(type) {
+	case float64:
+		return time.UnixMilli(int64(v)), true
+	case string:
+		if ms, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return time.UnixMilli(ms), true
+		}
+	case json.Number:
+		if ms, err := v.Int64(); err == nil {
+			return time.UnixMilli(ms), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// IsTimestampField reports whether name is one of the keys Timestamp reads
+func IsTimestampField(name string) bool {
+	return name == "timestamp_ms" || slices.Contains(timestampFields, name)
+}
+
+// Stringify renders an entry value as text: strings as-is, whole numbers
+// without a decimal point, and objects and arrays as compact JSON
+func Stringify(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case float64:
+		if v == float64(int64(v)) {
+			return fmt.Sprintf("%d", int64(v))
+		}
+		return fmt.Sprintf("%f", v)
+	case fmt.Stringer:
+		// After the cases above, which are Stringers with their own format
+		return v.String()
+	case bool:
+		if v {
+			return "true"
+		}
+		return "false"
+	default:
+		if b, err := json.Marshal(v); err == nil {
+			return string(b)
+		}
+	}
+	return ""
+}
+
+// number returns a decoded JSON number as a float64. Entries from the API
+// hold json.Number; float64 covers entries decoded without UseNumber.
+func number(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// lookupPath resolves a key, or a dotted path through nested objects.
+// An exact key match wins over path traversal.
+func lookupPath(m map[string]any, path string) (any, bool) {
+	if val, exists := m[path]; exists {
+		return val, true
+	}
+	head, rest, found := strings.Cut(path, ".")
+	if !found {
+		return nil, false
+	}
+	child, ok := m[head].(map[string]any)
+	if !ok {
+		return nil, false
+	}
+	return lookupPath(child, rest)
+}
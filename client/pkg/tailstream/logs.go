@@ -0,0 +1,250 @@
+package tailstream
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"iter"
+	"maps"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Filter is a server-side condition on an entry field, e.g.
+// {Field: "status", Operator: ">=", Value: 500}
+type Filter struct {
+	Field    string `json:"field"`
+	Operator string `json:"operator,omitempty"`
+	Value    any    `json:"value"`
+}
+
+// LogQuery selects the logs of one stream. Zero values are left to the
+// server's defaults.
+type LogQuery struct {
+	StreamID  string
+	Start     time.Time // Inclusive lower bound; zero = unbounded (tail mode)
+	End       time.Time // Upper bound; zero = unbounded
+	Limit     int       // Entries per page
+	Direction string    // "asc" (oldest first) or "desc" (newest first)
+	Filters   []Filter
+	Search    string // Full-text search, sent as a "q" filter
+	Cursor    string // NextCursor of a previous page, to continue from it
+}
+
+// Values returns the query parameters for q, excluding the stream ID (which
+// is part of the path)
+func (q LogQuery) Values() url.Values {
+	v := url.Values{}
+	if !q.Start.IsZero() {
+		v.Set("start_time", strconv.FormatInt(q.Start.UnixMilli(), 10))
+	}
+	if !q.End.IsZero() {
+		v.Set("end_time", strconv.FormatInt(q.End.UnixMilli(), 10))
+	}
+	if q.Limit > 0 {
+		v.Set("limit", strconv.Itoa(q.Limit))
+	}
+	if q.Direction != "" {
+		v.Set("direction", q.Direction)
+	}
+	filters := q.Filters
+	if q.Search != "" {
+		filters = append(filters[:len(filters):len(filters)], Filter{Field: "q", Value: q.Search})
+	}
+	if len(filters) > 0 {
+		b, _ := json.Marshal(filters)
+		v.Set("filters", string(b))
+	}
+	if q.Cursor != "" && !IsNextLink(q.Cursor) {
+		v.Set("cursor", q.Cursor)
+	}
+	return v
+}
+
+// LogResponse is the API's response to a logs request. Decode it with
+// UseNumber (as DecodeLogResponse does) to keep entry numbers exact.
+type LogResponse struct {
+	Data []LogEntry `json:"data"`
+	Meta struct {
+		HasMore    bool    `json:"has_more"`
+		NextCursor *string `json:"next_cursor"`
+		Total      *int    `json:"total"` // null in tail mode (no time range)
+	} `json:"meta"`
+	Links struct {
+		Next *string `json:"next"`
+	} `json:"links"`
+}
+
+// DecodeLogResponse parses a logs response body, decoding entry numbers as
+// json.Number
+func DecodeLogResponse(data []byte) (*LogResponse, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var resp LogResponse
+	if err := decoder.Decode(&resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// NextPageToken returns the token for requesting the next page: the opaque
+// cursor when present, otherwise the Links.Next URL (for servers using
+// HAL-style links), resolved to an absolute URL against the request URL
+func (r *LogResponse) NextPageToken(requestURL *url.URL) string {
+	if r.Meta.NextCursor != nil {
+		return *r.Meta.NextCursor
+	}
+	if r.Links.Next == nil || *r.Links.Next == "" {
+		return ""
+	}
+	next, err := url.Parse(*r.Links.Next)
+	if err != nil {
+		return ""
+	}
+	if requestURL != nil {
+		next = requestURL.ResolveReference(next)
+	}
+	return next.String()
+}
+
+// IsNextLink reports whether a page token is an absolute next-page URL
+// rather than an opaque cursor
+func IsNextLink(token string) bool {
+	u, err := url.Parse(token)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// ErrForeignNextLink is returned instead of following a next-page link on a
+// different scheme or host than the API, which would receive the token
+var ErrForeignNextLink = errors.New("next-page link points away from the API host; not following it")
+
+// SameOrigin reports whether link has the same scheme and host as baseURL
+func SameOrigin(link, baseURL string) bool {
+	l, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+	b, err := url.Parse(baseURL)
+	return err == nil && strings.EqualFold(l.Scheme, b.Scheme) && strings.EqualFold(l.Host, b.Host)
+}
+
+// LogPage is one page of query results
+type LogPage struct {
+	Entries    []LogEntry
+	HasMore    bool
+	Total      *int   // Matching entries overall; nil when the server doesn't count (tail mode)
+	NextCursor string // Set as LogQuery.Cursor to fetch the following page
+}
+
+// ErrCursorLoop reports a server that handed out the same cursor twice
+var ErrCursorLoop = errors.New("server repeated a pagination cursor")
+
+// MaxGetURLLength is the longest logs URL sent as a GET; longer queries
+// (usually huge filter sets) are POSTed instead so servers don't answer
+// 414 URI Too Long
+const MaxGetURLLength = 8000
+
+// LogsURL returns the GET URL of a page of logs, which identifies the page
+// (e.g. as a cache key) whether it is sent as a GET or a POST. params are
+// query parameters as from LogQuery.Values; cursor is a page token, and a
+// next-page link is its own URL.
+func (c *Client) LogsURL(streamID string, params url.Values, cursor string) string {
+	if IsNextLink(cursor) {
+		return cursor
+	}
+	if cursor != "" {
+		params = maps.Clone(params)
+		params.Set("cursor", cursor)
+	}
+	endpoint := c.endpoint("/api/streams/" + url.PathEscape(strings.TrimSpace(streamID)) + "/logs")
+	if len(params) == 0 {
+		return endpoint
+	}
+	return endpoint + "?" + params.Encode()
+}
+
+// NewLogsRequest builds the authenticated request for a page of logs: a GET
+// of LogsURL, or a POST of the parameters as a form body when PostQueries is
+// set or the URL would exceed MaxGetURLLength. A next-page link is followed
+// with a GET, and only if it stays on the API host (ErrForeignNextLink).
+func (c *Client) NewLogsRequest(ctx context.Context, streamID string, params url.Values, cursor string) (*http.Request, error) {
+	if IsNextLink(cursor) && !SameOrigin(cursor, c.BaseURL) {
+		return nil, ErrForeignNextLink
+	}
+	fullURL := c.LogsURL(streamID, params, cursor)
+	var req *http.Request
+	var err error
+	if !IsNextLink(cursor) && (c.PostQueries || len(fullURL) > MaxGetURLLength) {
+		endpoint, query, _ := strings.Cut(fullURL, "?")
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(query))
+		if err == nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+	} else {
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, fullURL, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	return req, nil
+}
+
+// Page returns the page described by a response to the request for
+// requestURL, which resolves relative next-page links
+func (r *LogResponse) Page(requestURL *url.URL) *LogPage {
+	return &LogPage{
+		Entries:    r.Data,
+		HasMore:    r.Meta.HasMore,
+		Total:      r.Meta.Total,
+		NextCursor: r.NextPageToken(requestURL),
+	}
+}
+
+// Logs fetches a single page of logs
+func (c *Client) Logs(ctx context.Context, q LogQuery) (*LogPage, error) {
+	params := q.Values()
+	params.Del("cursor") // Passed as the cursor instead
+	req, err := c.NewLogsRequest(ctx, q.StreamID, params, q.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	var payload LogResponse
+	if err := c.doJSON(req, &payload); err != nil {
+		return nil, err
+	}
+	return payload.Page(req.URL), nil
+}
+
+// LogPages iterates over every page of q, starting at q.Cursor, until the
+// results run out or the loop is stopped. An error ends the iteration after
+// being yielded.
+func (c *Client) LogPages(ctx context.Context, q LogQuery) iter.Seq2[*LogPage, error] {
+	return func(yield func(*LogPage, error) bool) {
+		seen := make(map[string]bool)
+		for {
+			page, err := c.Logs(ctx, q)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(page, nil) || !page.HasMore || page.NextCursor == "" {
+				return
+			}
+			if seen[page.NextCursor] {
+				yield(nil, ErrCursorLoop)
+				return
+			}
+			seen[page.NextCursor] = true
+			q.Cursor = page.NextCursor
+		}
+	}
+}
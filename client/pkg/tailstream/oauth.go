@@ -0,0 +1,106 @@
+package tailstream
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DeviceCodeResponse represents the response from the device code request
+type DeviceCodeResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+// TokenResponse represents the response from the token exchange
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	Scope        string `json:"scope"`
+	Error        string `json:"error,omitempty"`
+}
+
+// OAuthError is an error code from the token endpoint, e.g.
+// "authorization_pending" while the user hasn't approved the device yet
+type OAuthError struct {
+	Code string
+}
+
+func (e *OAuthError) Error() string {
+	return "oauth error: " + e.Code
+}
+
+// Device flow error codes (RFC 8628) that mean "keep polling"
+const (
+	ErrCodeAuthorizationPending = "authorization_pending"
+	ErrCodeSlowDown             = "slow_down" // Also poll less often from now on
+)
+
+// RequestDeviceCode starts the OAuth device authorization flow
+func (c *Client) RequestDeviceCode(ctx context.Context, clientID, scope string) (*DeviceCodeResponse, error) {
+	data := url.Values{
+		"client_id": {clientID},
+		"scope":     {scope},
+	}
+	req, err := newFormRequest(ctx, c.endpoint("/api/oauth/device/code"), data)
+	if err != nil {
+		return nil, err
+	}
+	var deviceResp DeviceCodeResponse
+	if err := c.doJSON(req, &deviceResp); err != nil {
+		return nil, err
+	}
+	return &deviceResp, nil
+}
+
+// PollToken asks once whether the device code has been authorized. Until it
+// has, the error is an *OAuthError with ErrCodeAuthorizationPending (or
+// ErrCodeSlowDown); callers wait the device code's interval between polls.
+func (c *Client) PollToken(ctx context.Context, clientID, deviceCode string) (*TokenResponse, error) {
+	data := url.Values{
+		"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+		"device_code": {deviceCode},
+		"client_id":   {clientID},
+	}
+	req, err := newFormRequest(ctx, c.endpoint("/api/oauth/device/token"), data)
+	if err != nil {
+		return nil, err
+	}
+	// OAuth errors come with a 400 status, so the body is read regardless
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var tokenResp TokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if tokenResp.Error != "" {
+		return nil, &OAuthError{Code: tokenResp.Error}
+	}
+	return &tokenResp, nil
+}
+
+// newFormRequest builds a form-encoded POST request
+func newFormRequest(ctx context.Context, endpoint string, data url.Values) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(data.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req, nil
+}
@@ -0,0 +1,476 @@
+package tailstream
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStreams(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/user/streams" || r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("unexpected request: %s %v", r.URL, r.Header)
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"streams": []Stream{{ID: 1, Name: "web", StreamID: "s-1"}},
+		})
+	}))
+	defer server.Close()
+
+	streams, err := NewClient(server.URL+"/", "token").Streams(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(streams) != 1 || streams[0].StreamID != "s-1" {
+		t.Errorf("unexpected streams: %+v", streams)
+	}
+}
+
+func TestStatusError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "token expired", http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	_, err := NewClient(server.URL, "old").Streams(context.Background())
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusUnauthorized || statusErr.Body != "token expired" {
+		t.Fatalf("expected a 401 StatusError, got %v", err)
+	}
+}
+
+func TestLogQueryValues(t *testing.T) {
+	q := LogQuery{
+		StreamID:  "s",
+		Start:     time.UnixMilli(1704067200000),
+		Limit:     50,
+		Direction: "asc",
+		Filters:   []Filter{{Field: "level", Operator: "=", Value: "ERROR"}},
+		Search:    "timeout",
+		Cursor:    "abc",
+	}
+	want := url.Values{
+		"start_time": {"1704067200000"},
+		"limit":      {"50"},
+		"direction":  {"asc"},
+		"filters":    {`[{"field":"level","operator":"=","value":"ERROR"},{"field":"q","value":"timeout"}]`},
+		"cursor":     {"abc"},
+	}
+	if got := q.Values(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Values() = %v, want %v", got, want)
+	}
+	if len(q.Filters) != 1 {
+		t.Error("Values() must not modify the query's filters")
+	}
+	if got := (LogQuery{}).Values(); len(got) != 0 {
+		t.Errorf("expected no params for an empty query, got %v", got)
+	}
+}
+
+func TestLogPages(t *testing.T) {
+	// Three pages chained by cursor
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("cursor"))
+		next := strconv.Itoa(page + 1)
+		json.NewEncoder(w).Encode(map[string]any{
+			"data": []LogEntry{{"page": page}},
+			"meta": map[string]any{"has_more": page < 2, "next_cursor": next},
+		})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "token")
+	var pages []json.Number
+	for page, err := range client.LogPages(context.Background(), LogQuery{StreamID: "s"}) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		pages = append(pages, page.Entries[0]["page"].(json.Number))
+	}
+	if !reflect.DeepEqual(pages, []json.Number{"0", "1", "2"}) {
+		t.Errorf("expected pages 0-2, got %v", pages)
+	}
+
+	// Breaking out early stops fetching
+	seen := 0
+	for range client.LogPages(context.Background(), LogQuery{StreamID: "s"}) {
+		seen++
+		break
+	}
+	if seen != 1 {
+		t.Errorf("expected 1 page before break, got %d", seen)
+	}
+}
+
+func TestLogPagesCursorLoop(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"data": []LogEntry{{}},
+			"meta": map[string]any{"has_more": true, "next_cursor": "same"},
+		})
+	}))
+	defer server.Close()
+
+	var last error
+	pages := 0
+	for _, err := range NewClient(server.URL, "token").LogPages(context.Background(), LogQuery{StreamID: "s"}) {
+		if err != nil {
+			last = err
+			continue
+		}
+		if pages++; pages > 5 {
+			t.Fatal("LogPages did not detect the cursor loop")
+		}
+	}
+	if !errors.Is(last, ErrCursorLoop) {
+		t.Errorf("expected ErrCursorLoop, got %v", last)
+	}
+}
+
+func TestNewLogsRequest(t *testing.T) {
+	var method, path, filters, auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		method, path, filters, auth = r.Method, r.URL.Path, r.FormValue("filters"), r.Header.Get("Authorization")
+	}))
+	defer server.Close()
+
+	// The CLI sends its first page, pagination, and interactive reloads this way
+	client := NewClient(server.URL, "token")
+	send := func(params url.Values, cursor string) {
+		t.Helper()
+		req, err := client.NewLogsRequest(context.Background(), "s", params, cursor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	short := url.Values{"filters": {`[{"field":"level","value":"error"}]`}}
+	send(short, "")
+	if method != http.MethodGet || path != "/api/streams/s/logs" || filters != short.Get("filters") || auth != "Bearer token" {
+		t.Errorf("expected an authorized GET with the filters, got %s %s %q (%q)", method, path, filters, auth)
+	}
+
+	long := url.Values{"filters": {strings.Repeat("x", MaxGetURLLength)}}
+	send(long, "")
+	if method != http.MethodPost || filters != long.Get("filters") || auth != "Bearer token" {
+		t.Errorf("expected an authorized POST for a long query, got %s with %d filter bytes (%q)", method, len(filters), auth)
+	}
+
+	client.PostQueries = true
+	send(short, "")
+	if method != http.MethodPost || filters != short.Get("filters") {
+		t.Errorf("expected PostQueries to force a POST, got %s %q", method, filters)
+	}
+
+	// Next-page links are followed with a GET, and only on the API host
+	send(short, server.URL+"/api/streams/s/logs?page=2")
+	if method != http.MethodGet || filters != "" {
+		t.Errorf("expected a plain GET of the next link, got %s %q", method, filters)
+	}
+	if _, err := client.NewLogsRequest(context.Background(), "s", short, "https://evil.example/logs"); !errors.Is(err, ErrForeignNextLink) {
+		t.Errorf("expected ErrForeignNextLink, got %v", err)
+	}
+
+	if got := client.LogsURL("s", long, "c1"); got != server.URL+"/api/streams/s/logs?cursor=c1&"+long.Encode() {
+		t.Errorf("expected the GET URL as the page's key, got %.60q", got)
+	}
+}
+
+func TestPollToken(t *testing.T) {
+	approved := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("device_code") != "code" {
+			t.Errorf("unexpected device code: %q", r.FormValue("device_code"))
+		}
+		if !approved {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(TokenResponse{Error: ErrCodeAuthorizationPending})
+			return
+		}
+		json.NewEncoder(w).Encode(TokenResponse{AccessToken: "access"})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "")
+	_, err := client.PollToken(context.Background(), "cli", "code")
+	var oauthErr *OAuthError
+	if !errors.As(err, &oauthErr) || oauthErr.Code != ErrCodeAuthorizationPending {
+		t.Fatalf("expected authorization_pending, got %v", err)
+	}
+
+	approved = true
+	token, err := client.PollToken(context.Background(), "cli", "code")
+	if err != nil || token.AccessToken != "access" {
+		t.Fatalf("expected a token, got %v, %v", token, err)
+	}
+}
+
+func TestDecodeBody(t *testing.T) {
+	payload := `{"data":[{"raw_message":"hello"}]}`
+
+	compress := func(encoding string) []byte {
+		var buf bytes.Buffer
+		var w io.WriteCloser
+		switch encoding {
+		case "gzip":
+			w = gzip.NewWriter(&buf)
+		case "deflate":
+			w = zlib.NewWriter(&buf)
+		case "raw-deflate":
+			w, _ = flate.NewWriter(&buf, flate.DefaultCompression)
+		}
+		w.Write([]byte(payload))
+		w.Close()
+		return buf.Bytes()
+	}
+
+	tests := []struct {
+		name     string
+		encoding string
+		body     []byte
+	}{
+		{"identity", "", []byte(payload)},
+		{"gzip", "gzip", compress("gzip")},
+		{"zlib deflate", "deflate", compress("deflate")},
+		{"raw deflate", "deflate", compress("raw-deflate")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{
+				Header: http.Header{},
+				Body:   io.NopCloser(bytes.NewReader(tt.body)),
+			}
+			if tt.encoding != "" {
+				resp.Header.Set("Content-Encoding", tt.encoding)
+			}
+			reader, err := DecodeBody(resp)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got, err := io.ReadAll(reader)
+			if err != nil {
+				t.Fatalf("unexpected read error: %v", err)
+			}
+			if string(got) != payload {
+				t.Errorf("expected %s, got %s", payload, got)
+			}
+		})
+	}
+}
+
+func TestNextPageToken(t *testing.T) {
+	requestURL, _ := url.Parse("https://app.example.com/api/streams/s/logs?limit=10")
+	cursor := "abc123"
+	absolute := "https://app.example.com/api/streams/s/logs?page=2"
+	relative := "/api/streams/s/logs?page=3"
+
+	var payload LogResponse
+	if got := payload.NextPageToken(requestURL); got != "" {
+		t.Errorf("expected empty token, got %s", got)
+	}
+
+	// Cursor takes precedence over links
+	payload.Meta.NextCursor = &cursor
+	payload.Links.Next = &absolute
+	if got := payload.NextPageToken(requestURL); got != cursor {
+		t.Errorf("expected cursor, got %s", got)
+	}
+
+	payload.Meta.NextCursor = nil
+	if got := payload.NextPageToken(requestURL); got != absolute {
+		t.Errorf("expected absolute link, got %s", got)
+	}
+
+	// Relative links resolve against the request URL
+	payload.Links.Next = &relative
+	if got := payload.NextPageToken(requestURL); got != "https://app.example.com/api/streams/s/logs?page=3" {
+		t.Errorf("unexpected resolved link: %s", got)
+	}
+}
+
+func TestSameOrigin(t *testing.T) {
+	base := "https://app.example.com"
+	tests := []struct {
+		link     string
+		expected bool
+	}{
+		{"https://app.example.com/api/streams/s/logs?page=2", true},
+		{"https://APP.example.com/api/streams/s/logs", true},
+		{"http://app.example.com/api/streams/s/logs", false},
+		{"https://app.example.com:8443/api/streams/s/logs", false},
+		{"https://evil.example.net/collect", false},
+	}
+	for _, tt := range tests {
+		if got := SameOrigin(tt.link, base); got != tt.expected {
+			t.Errorf("SameOrigin(%q) = %v, expected %v", tt.link, got, tt.expected)
+		}
+	}
+
+	c := NewClient(base, "token")
+	if _, err := c.Logs(context.Background(), LogQuery{StreamID: "s", Cursor: "https://evil.example.net/collect"}); !errors.Is(err, ErrForeignNextLink) {
+		t.Errorf("expected ErrForeignNextLink, got %v", err)
+	}
+}
+
+func TestLogEntryString(t *testing.T) {
+	entry := LogEntry{
+		"message": "test message",
+		"level":   "ERROR",
+		"empty":   "",
+	}
+
+	// Should find message
+	result := entry.String("message")
+	if result != "test message" {
+		t.Errorf("expected 'test message', got '%s'", result)
+	}
+
+	// Should find level
+	result = entry.String("level")
+	if result != "ERROR" {
+		t.Errorf("expected 'ERROR', got '%s'", result)
+	}
+
+	// Should skip empty and find next
+	result = entry.String("empty", "message")
+	if result != "test message" {
+		t.Errorf("expected 'test message', got '%s'", result)
+	}
+
+	// Should return empty for non-existent keys
+	result = entry.String("nonexistent")
+	if result != "" {
+		t.Errorf("expected empty string, got '%s'", result)
+	}
+}
+
+func TestLogEntryField(t *testing.T) {
+	entry := LogEntry{
+		"request.id": "exact",
+		"fields": map[string]any{
+			"http": map[string]any{"status": float64(404)},
+		},
+		"user": map[string]any{"name": "ada"},
+	}
+
+	tests := []struct {
+		path string
+		want any
+		ok   bool
+	}{
+		{"http.status", float64(404), true},
+		{"fields.http.status", float64(404), true},
+		{"user.name", "ada", true},
+		{"request.id", "exact", true},
+		{"user.missing", nil, false},
+		{"http.status.code", nil, false},
+	}
+	for _, tt := range tests {
+		got, ok := entry.Field(tt.path)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("Field(%q) = %v, %v; want %v, %v", tt.path, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestLogEntryTimestamp(t *testing.T) {
+	want := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []map[string]any{
+		{"timestamp": "2024-01-01T12:00:00Z"},
+		{"time": "2024-01-01T14:00:00+02:00"},
+		{"timestamp": float64(want.Unix())},
+		{"created_at": float64(want.UnixMilli())},
+		{"timestamp_ms": float64(want.UnixMilli())},
+		{"timestamp_ms": strconv.FormatInt(want.UnixMilli(), 10)},
+	}
+	for _, entry := range tests {
+		got, ok := LogEntry(entry).Timestamp()
+		if !ok || !got.Equal(want) {
+			t.Errorf("Timestamp(%v) = %v, %v; want %v", entry, got, ok, want)
+		}
+	}
+
+	if got, ok := LogEntry(map[string]any{"timestamp": "2024-01-01T12:00:00.250Z"}).Timestamp(); !ok || got.Nanosecond() != 250e6 {
+		t.Errorf("fractional seconds lost: %v, %v", got, ok)
+	}
+	if _, ok := LogEntry(map[string]any{"timestamp": "yesterday"}).Timestamp(); ok {
+		t.Error("expected unparseable timestamp to fail")
+	}
+	if _, ok := LogEntry(map[string]any{"message": "no time"}).Timestamp(); ok {
+		t.Error("expected entry without timestamp to fail")
+	}
+}
+
+func TestLogEntryAccessors(t *testing.T) {
+	entry := LogEntry{
+		"raw_message": "GET /health 200",
+		"message":     "ignored",
+		"timestamp":   "2024-01-01T12:00:00Z",
+		"fields":      map[string]any{"level": "warn", "status": float64(200)},
+	}
+	if got := entry.Message(); got != "GET /health 200" {
+		t.Errorf("Message() = %q", got)
+	}
+	if got := entry.Level(); got != "WARN" {
+		t.Errorf("Level() = %q, want WARN", got)
+	}
+	if got := entry.FieldString("status"); got != "200" {
+		t.Errorf("FieldString(status) = %q, want 200", got)
+	}
+	if got := entry.FieldString("missing"); got != "" {
+		t.Errorf("FieldString(missing) = %q, want empty", got)
+	}
+	if got := entry.TimeText(); got != "2024-01-01T12:00:00Z" {
+		t.Errorf("TimeText() = %q", got)
+	}
+	if got := (LogEntry{"message": "x"}).Level(); got != "" {
+		t.Errorf("Level() without level = %q, want empty", got)
+	}
+}
+
+func TestStringify(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    any
+		expected string
+	}{
+		{"nil", nil, ""},
+		{"string", "test", "test"},
+		{"int-like float", 42.0, "42"},
+		{"float", 42.5, "42.500000"},
+		{"bool true", true, "true"},
+		{"bool false", false, "false"},
+		{"empty string", "", ""},
+		{"json number", json.Number("9007199254740993"), "9007199254740993"},
+		{"time", time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC), "2024-01-01T12:00:00Z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Stringify(tt.input)
+			if result != tt.expected {
+				t.Errorf("expected '%s', got '%s'", tt.expected, result)
+			}
+		})
+	}
+}
@@ -4,53 +4,191 @@
 //
 // This file provides functions to parse flexible time specifications including:
 // - Relative times (e.g., "-1h", "-30m", "-7d")
-// - Absolute dates (e.g., "2024-01-02", "2024-01-02 15:04")
-// - RFC3339 timestamps
+// - Absolute dates (e.g., "2024-01-02", "2024-01-02 15:04"); a date-only end
+//   bound covers the whole day
+// - Calendar months ("2024-01") and ISO weeks ("2024-W05"); an end bound
+//   covers the whole period
+// - RFC3339 timestamps, with optional fractional seconds
+// - Unix epoch timestamps in seconds or milliseconds
 // - Special keywords ("now")
 //
-// All times are normalized to RFC3339 format in UTC for API consumption.
+// All times are normalized to RFC3339 format in UTC for API consumption,
+// keeping sub-second precision so millisecond bounds survive.
+// Relative times are resolved against now(), which can be corrected for
+// local clock skew using an offset measured against the server.
 
 package main
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 )
 
-// parseTimeArg parses a time string in various formats and returns RFC3339 format.
+// clockOffset is added to the local clock when resolving relative times.
+// It is set from the server's Date header when --server-time is used.
+var clockOffset time.Duration
+
+// now returns the current time corrected by clockOffset
+func now() time.Time {
+	return time.Now().Add(clockOffset)
+}
+
+// parseTimeArg parses a time string in various formats and returns RFC3339
+// format with fractional seconds when present.
 // Supports:
 // - "now" -> current time
-// - Relative durations: "-1h", "-30m", "-2h30m"
+// - Relative durations: "-1h", "-30m", "-2h30m", "-1500ms"
 // - Dates: "2024-01-01"
-// - Date and time: "2024-01-01 15:04"
-// - RFC3339: "2024-01-01T15:04:05Z"
+// - Months and ISO weeks: "2024-01", "2024-W05" (their first instant)
+// - Date and time: "2024-01-01 15:04", "2024-01-01 15:04:05.123"
+// - RFC3339: "2024-01-01T15:04:05Z", "2024-01-01T15:04:05.123Z"
+// - Epoch: "1704067200" (seconds) or "1704067200123" (milliseconds)
 func parseTimeArg(value string) (string, error) {
 	value = strings.TrimSpace(value)
 	if value == "" {
 		return "", nil
 	}
 	if strings.EqualFold(value, "now") {
-		return time.Now().UTC().Format(time.RFC3339), nil
+		return now().UTC().Format(time.RFC3339Nano), nil
 	}
 	if strings.HasPrefix(value, "-") {
 		dur, err := time.ParseDuration(value)
 		if err != nil {
 			return "", fmt.Errorf("invalid relative duration %q: %w", value, err)
 		}
-		return time.Now().Add(dur).UTC().Format(time.RFC3339), nil
+		return now().Add(dur).UTC().Format(time.RFC3339Nano), nil
+	}
+
+	if isDigits(value) {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid epoch timestamp %q: %w", value, err)
+		}
+		// 12+ digits can only be milliseconds for any plausible date
+		if len(value) >= 12 {
+			return time.UnixMilli(n).UTC().Format(time.RFC3339Nano), nil
+		}
+		return time.Unix(n, 0).UTC().Format(time.RFC3339Nano), nil
+	}
+
+	if start, _, ok, err := parsePeriod(value); ok {
+		if err != nil {
+			return "", err
+		}
+		return start.UTC().Format(time.RFC3339Nano), nil
 	}
 
+	// Fractional seconds are accepted after the seconds field by every layout
 	layouts := []string{
 		time.RFC3339,
 		"2006-01-02 15:04:05",
 		"2006-01-02 15:04",
-		"2006-01-02",
+		dateOnlyLayout,
 	}
 	for _, layout := range layouts {
 		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
-			return t.UTC().Format(time.RFC3339), nil
+			return t.UTC().Format(time.RFC3339Nano), nil
 		}
 	}
 	return "", fmt.Errorf("could not parse time value %q", value)
 }
+
+// isRelativeTime reports whether a time argument depends on when it is
+// parsed ("now" or a relative duration like "-1h")
+func isRelativeTime(value string) bool {
+	value = strings.TrimSpace(value)
+	return strings.EqualFold(value, "now") || strings.HasPrefix(value, "-")
+}
+
+// dateOnlyLayout is the layout of a bare calendar date such as "2024-01-02"
+const dateOnlyLayout = "2006-01-02"
+
+// isDateOnly reports whether value is a bare calendar date without a time
+func isDateOnly(value string) bool {
+	_, err := time.Parse(dateOnlyLayout, strings.TrimSpace(value))
+	return err == nil
+}
+
+// parseEndTimeArg parses an end bound like parseTimeArg, except that a
+// date-only value means the end of that day (23:59:59.999 local time) rather
+// than its start, so "--from 2024-01-02 --to 2024-01-02" covers the whole day.
+// Months and ISO weeks likewise mean the last millisecond of the period.
+func parseEndTimeArg(value string) (string, error) {
+	if _, next, ok, err := parsePeriod(strings.TrimSpace(value)); ok {
+		if err != nil {
+			return "", err
+		}
+		return next.Add(-time.Millisecond).UTC().Format(time.RFC3339Nano), nil
+	}
+	if !isDateOnly(value) {
+		return parseTimeArg(value)
+	}
+	t, err := time.ParseInLocation(dateOnlyLayout, strings.TrimSpace(value), time.Local)
+	if err != nil {
+		return "", fmt.Errorf("could not parse time value %q", value)
+	}
+	return t.AddDate(0, 0, 1).Add(-time.Millisecond).UTC().Format(time.RFC3339Nano), nil
+}
+
+// parsePeriod parses a calendar month ("2024-01") or an ISO 8601 week
+// ("2024-W05", Monday to Sunday) and returns its first instant and the first
+// instant after it, in local time. ok is false when value has neither form;
+// err is set when it has the form but names no real month or week.
+func parsePeriod(value string) (start, next time.Time, ok bool, err error) {
+	year, rest, found := strings.Cut(value, "-")
+	if !found || len(year) != 4 || !isDigits(year) {
+		return time.Time{}, time.Time{}, false, nil
+	}
+	y, _ := strconv.Atoi(year)
+
+	if len(rest) == 2 && isDigits(rest) {
+		m, _ := strconv.Atoi(rest)
+		if m < 1 || m > 12 {
+			return time.Time{}, time.Time{}, true, fmt.Errorf("invalid month %q: month must be 01-12", value)
+		}
+		start = time.Date(y, time.Month(m), 1, 0, 0, 0, 0, time.Local)
+		return start, start.AddDate(0, 1, 0), true, nil
+	}
+
+	if len(rest) == 3 && (rest[0] == 'W' || rest[0] == 'w') && isDigits(rest[1:]) {
+		w, _ := strconv.Atoi(rest[1:])
+		// The last ISO week of a year always contains December 28th
+		_, weeks := time.Date(y, time.December, 28, 0, 0, 0, 0, time.Local).ISOWeek()
+		if w < 1 || w > weeks {
+			return time.Time{}, time.Time{}, true, fmt.Errorf("invalid ISO week %q: %d has weeks 01-%02d", value, y, weeks)
+		}
+		// Week 1 is the week containing January 4th
+		jan4 := time.Date(y, time.January, 4, 0, 0, 0, 0, time.Local)
+		monday := jan4.AddDate(0, 0, -((int(jan4.Weekday()) + 6) % 7))
+		start = monday.AddDate(0, 0, 7*(w-1))
+		return start, start.AddDate(0, 0, 7), true, nil
+	}
+
+	return time.Time{}, time.Time{}, false, nil
+}
+
+// checkTimeOrder returns an error when both bounds are set and start is after
+// end, which would otherwise silently query an empty range. Zero times are
+// treated as unset.
+func checkTimeOrder(start, end time.Time) error {
+	if start.IsZero() || end.IsZero() || !start.After(end) {
+		return nil
+	}
+	return fmt.Errorf("--from (%s) is after --to (%s); swap them or pass --allow-inverted",
+		start.UTC().Format(time.RFC3339Nano), end.UTC().Format(time.RFC3339Nano))
+}
+
+// isDigits reports whether value is a non-empty string of ASCII digits
+func isDigits(value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -76,3 +76,171 @@ func TestParseTimeArgEmpty(t *testing.T) {
 		t.Fatalf("expected empty string, got: %s", got)
 	}
 }
+
+func TestParseTimeArgClockOffset(t *testing.T) {
+	clockOffset = -2 * time.Hour
+	defer func() { clockOffset = 0 }()
+
+	got, err := parseTimeArg("now")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	parsed, err := time.Parse(time.RFC3339, got)
+	if err != nil {
+		t.Fatalf("result is not valid RFC3339: %s", got)
+	}
+	// Should be shifted by the offset
+	diff := time.Since(parsed)
+	if diff < 119*time.Minute || diff > 121*time.Minute {
+		t.Fatalf("expected time around 2 hours ago, got diff: %v", diff)
+	}
+}
+
+func TestCheckTimeOrder(t *testing.T) {
+	early := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	late := early.Add(time.Hour)
+
+	if err := checkTimeOrder(early, late); err != nil {
+		t.Errorf("expected ordered range to pass, got %v", err)
+	}
+	if err := checkTimeOrder(early, early); err != nil {
+		t.Errorf("expected equal bounds to pass, got %v", err)
+	}
+	if err := checkTimeOrder(late, time.Time{}); err != nil {
+		t.Errorf("expected open-ended range to pass, got %v", err)
+	}
+	if err := checkTimeOrder(late, early); err == nil {
+		t.Error("expected inverted range to fail")
+	}
+}
+
+func TestParseTimeArgMillisecondPrecision(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"2024-01-01T12:00:00.123Z", "2024-01-01T12:00:00.123Z"},
+		{"2024-01-01T12:00:00.5+02:00", "2024-01-01T10:00:00.5Z"},
+		{"1704067200", "2024-01-01T00:00:00Z"},
+		{"1704067200123", "2024-01-01T00:00:00.123Z"},
+	}
+	for _, tt := range tests {
+		got, err := parseTimeArg(tt.input)
+		if err != nil {
+			t.Fatalf("parseTimeArg(%q) failed: %v", tt.input, err)
+		}
+		if got != tt.expected {
+			t.Errorf("parseTimeArg(%q) = %q, want %q", tt.input, got, tt.expected)
+		}
+	}
+
+	// Millisecond timestamps survive the round trip used to build the query
+	got, _ := parseTimeArg("1704067200123")
+	parsed, err := time.Parse(time.RFC3339, got)
+	if err != nil || parsed.UnixMilli() != 1704067200123 {
+		t.Errorf("expected millisecond round trip, got %d (%v)", parsed.UnixMilli(), err)
+	}
+
+	// Sub-second relative durations keep their precision
+	before := time.Now()
+	got, _ = parseTimeArg("-1500ms")
+	parsed, _ = time.Parse(time.RFC3339, got)
+	if diff := before.Sub(parsed); diff < 1400*time.Millisecond || diff > 1600*time.Millisecond {
+		t.Errorf("expected -1500ms to be ~1.5s ago, got %v", diff)
+	}
+}
+
+func TestParseEndTimeArgDateOnly(t *testing.T) {
+	got, err := parseEndTimeArg("2024-01-02")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := time.Date(2024, 1, 2, 23, 59, 59, int(999*time.Millisecond), time.Local).UTC().Format(time.RFC3339Nano)
+	if got != expected {
+		t.Fatalf("expected end of day %s, got %s", expected, got)
+	}
+
+	// The same date as --from and --to spans the whole day
+	start, _ := parseTimeArg("2024-01-02")
+	from, _ := time.Parse(time.RFC3339, start)
+	to, _ := time.Parse(time.RFC3339, got)
+	if span := to.Sub(from); span != 24*time.Hour-time.Millisecond {
+		t.Fatalf("expected a full day range, got %v", span)
+	}
+
+	// Values with a time of day are not adjusted
+	got, err = parseEndTimeArg("2024-01-02 15:04")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected = time.Date(2024, 1, 2, 15, 4, 0, 0, time.Local).UTC().Format(time.RFC3339)
+	if got != expected {
+		t.Fatalf("expected %s got %s", expected, got)
+	}
+}
+
+func TestParsePeriod(t *testing.T) {
+	tests := []struct {
+		input       string
+		start, next time.Time
+	}{
+		{"2024-01", time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local), time.Date(2024, 2, 1, 0, 0, 0, 0, time.Local)},
+		{"2024-12", time.Date(2024, 12, 1, 0, 0, 0, 0, time.Local), time.Date(2025, 1, 1, 0, 0, 0, 0, time.Local)},
+		// 2024 starts on a Monday, so week 1 starts on January 1st
+		{"2024-W01", time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local), time.Date(2024, 1, 8, 0, 0, 0, 0, time.Local)},
+		{"2024-W05", time.Date(2024, 1, 29, 0, 0, 0, 0, time.Local), time.Date(2024, 2, 5, 0, 0, 0, 0, time.Local)},
+		// 2021 starts on a Friday, so its first days belong to 2020-W53
+		{"2021-W01", time.Date(2021, 1, 4, 0, 0, 0, 0, time.Local), time.Date(2021, 1, 11, 0, 0, 0, 0, time.Local)},
+		{"2020-w53", time.Date(2020, 12, 28, 0, 0, 0, 0, time.Local), time.Date(2021, 1, 4, 0, 0, 0, 0, time.Local)},
+	}
+	for _, tt := range tests {
+		start, next, ok, err := parsePeriod(tt.input)
+		if !ok || err != nil {
+			t.Fatalf("parsePeriod(%q): ok=%v err=%v", tt.input, ok, err)
+		}
+		if !start.Equal(tt.start) || !next.Equal(tt.next) {
+			t.Errorf("parsePeriod(%q) = %v - %v, want %v - %v", tt.input, start, next, tt.start, tt.next)
+		}
+	}
+
+	for _, input := range []string{"2024-00", "2024-13", "2024-W00", "2021-W53"} {
+		if _, _, ok, err := parsePeriod(input); !ok || err == nil {
+			t.Errorf("parsePeriod(%q) should be rejected, got ok=%v err=%v", input, ok, err)
+		}
+	}
+	for _, input := range []string{"2024-01-02", "24-01", "2024-1", "2024-W5", "-1h"} {
+		if _, _, ok, _ := parsePeriod(input); ok {
+			t.Errorf("parsePeriod(%q) should not be a period", input)
+		}
+	}
+}
+
+func TestPeriodBounds(t *testing.T) {
+	// --from 2024-02 --to 2024-02 covers all of February, leap day included
+	from, err := parseTimeArg("2024-02")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := time.Date(2024, 2, 1, 0, 0, 0, 0, time.Local).UTC().Format(time.RFC3339Nano); from != want {
+		t.Errorf("expected start %s, got %s", want, from)
+	}
+	to, err := parseEndTimeArg("2024-02")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := time.Date(2024, 2, 29, 23, 59, 59, int(999*time.Millisecond), time.Local).UTC().Format(time.RFC3339Nano); to != want {
+		t.Errorf("expected end %s, got %s", want, to)
+	}
+
+	to, err = parseEndTimeArg("2024-W05")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := time.Date(2024, 2, 4, 23, 59, 59, int(999*time.Millisecond), time.Local).UTC().Format(time.RFC3339Nano); to != want {
+		t.Errorf("expected end of week %s, got %s", want, to)
+	}
+
+	if _, err := parseEndTimeArg("2024-13"); err == nil {
+		t.Error("expected error for month 13")
+	}
+}